@@ -0,0 +1,90 @@
+// Package apierror defines the body of every error response and the codes
+// clients match on. Handlers and middleware both respond through it so that
+// every error has the same shape.
+package apierror
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Error codes returned in the "code" field of every error response. Clients
+// should match on these rather than on the human readable message.
+const (
+	CodeValidationFailed         = "VALIDATION_FAILED"
+	CodeInvalidRequestBody       = "INVALID_REQUEST_BODY"
+	CodeInvalidID                = "INVALID_ID"
+	CodeUnauthorized             = "UNAUTHORIZED"
+	CodeForbidden                = "FORBIDDEN"
+	CodeInvalidCredentials       = "INVALID_CREDENTIALS"
+	CodeInvalidToken             = "INVALID_TOKEN"
+	CodeLoginDisabled            = "LOGIN_DISABLED"
+	CodeBrandNotFound            = "BRAND_NOT_FOUND"
+	CodeVoucherNotFound          = "VOUCHER_NOT_FOUND"
+	CodeCustomerNotFound         = "CUSTOMER_NOT_FOUND"
+	CodeTransactionNotFound      = "TRANSACTION_NOT_FOUND"
+	CodeTransactionItemNotFound  = "TRANSACTION_ITEM_NOT_FOUND"
+	CodeVoucherCodeNotFound      = "VOUCHER_CODE_NOT_FOUND"
+	CodeAPIKeyNotFound           = "API_KEY_NOT_FOUND"
+	CodeEmailAlreadyExists       = "EMAIL_ALREADY_EXISTS"
+	CodeInsufficientPoints       = "INSUFFICIENT_POINTS"
+	CodeOutOfStock               = "OUT_OF_STOCK"
+	CodeCustomerLimitExceeded    = "CUSTOMER_LIMIT_EXCEEDED"
+	CodePeriodLimitExceeded      = "PERIOD_LIMIT_EXCEEDED"
+	CodeNotEligible              = "NOT_ELIGIBLE"
+	CodeInvalidStateTransition   = "INVALID_STATE_TRANSITION"
+	CodeVoucherCodesUsed         = "VOUCHER_CODES_USED"
+	CodeVoucherCodeUsed          = "VOUCHER_CODE_USED"
+	CodeVoucherCodeVoid          = "VOUCHER_CODE_VOID"
+	CodeVoucherCodeExpired       = "VOUCHER_CODE_EXPIRED"
+	CodeVoucherBrandChanged      = "VOUCHER_BRAND_CHANGED"
+	CodeIdempotencyKeyReused     = "IDEMPOTENCY_KEY_REUSED"
+	CodeIdempotencyKeyInProgress = "IDEMPOTENCY_KEY_IN_PROGRESS"
+	CodeRateLimited              = "RATE_LIMITED"
+	CodeInternal                 = "INTERNAL_ERROR"
+)
+
+// Error is the body of every error response: a stable code, a human
+// readable message and optional details such as per-field validation errors
+type Error struct {
+	Status  int         `json:"-"`
+	Code    string      `json:"code"`
+	Message string      `json:"error"`
+	Details interface{} `json:"details,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// FieldError describes why a single request field was rejected
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// New creates an Error without details
+func New(status int, code, message string) *Error {
+	return &Error{Status: status, Code: code, Message: message}
+}
+
+// Field creates a validation error for a single field
+func Field(field, message string) *Error {
+	return &Error{
+		Status:  http.StatusBadRequest,
+		Code:    CodeValidationFailed,
+		Message: message,
+		Details: []FieldError{{Field: field, Message: message}},
+	}
+}
+
+// Respond writes err as the response
+func Respond(c *gin.Context, err *Error) {
+	c.JSON(err.Status, err)
+}
+
+// Abort writes an error response without details and stops the handler chain
+func Abort(c *gin.Context, status int, code, message string) {
+	c.AbortWithStatusJSON(status, New(status, code, message))
+}
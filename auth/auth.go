@@ -0,0 +1,139 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+
+	"my-backend-app/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// KeyPrefix starts every generated API key so leaked keys are easy to spot
+const KeyPrefix = "vk_"
+
+// PrincipalContextKey is the gin context key holding the authenticated Principal
+const PrincipalContextKey = "principal"
+
+var (
+	// ErrInvalidRole is returned for a role other than admin, brand_operator or customer
+	ErrInvalidRole = errors.New("role must be one of admin, brand_operator or customer")
+	// ErrInvalidScope is returned when the brand or customer scope does not fit the role
+	ErrInvalidScope = errors.New("brand operators need a brand ID, customers need a customer ID and admins need neither")
+)
+
+// Principal is the authenticated caller of a request, identified either by an
+// API key or by a customer access token
+type Principal struct {
+	KeyID      uuid.UUID
+	Name       string
+	Role       string
+	BrandID    *uuid.UUID
+	CustomerID *uuid.UUID
+}
+
+// NewPrincipal describes the caller authenticated by key
+func NewPrincipal(key models.APIKey) *Principal {
+	return &Principal{
+		KeyID:      key.ID,
+		Name:       key.Name,
+		Role:       key.Role,
+		BrandID:    key.BrandID,
+		CustomerID: key.CustomerID,
+	}
+}
+
+// IsAdmin reports whether the principal may act on any resource
+func (p *Principal) IsAdmin() bool {
+	return p.Role == models.RoleAdmin
+}
+
+// CanAccessBrand reports whether the principal may manage the brand and its vouchers
+func (p *Principal) CanAccessBrand(brandID uuid.UUID) bool {
+	return p.IsAdmin() || (p.Role == models.RoleBrandOperator && p.BrandID != nil && *p.BrandID == brandID)
+}
+
+// CanAccessCustomer reports whether the principal may act for the customer
+func (p *Principal) CanAccessCustomer(customerID uuid.UUID) bool {
+	return p.IsAdmin() || (p.Role == models.RoleCustomer && p.CustomerID != nil && *p.CustomerID == customerID)
+}
+
+// Identity uniquely identifies the caller: the API key, or the customer for
+// access tokens, which are not tied to a key
+func (p *Principal) Identity() string {
+	if p.KeyID != uuid.Nil {
+		return "key:" + p.KeyID.String()
+	}
+	if p.CustomerID != nil {
+		return "customer:" + p.CustomerID.String()
+	}
+	return p.Role + ":" + p.Name
+}
+
+// Actor identifies the principal on ledger entries
+func (p *Principal) Actor() string {
+	return p.Role + ":" + p.Name
+}
+
+// FromContext returns the principal set by the authentication middleware, or nil
+func FromContext(c *gin.Context) *Principal {
+	if value, ok := c.Get(PrincipalContextKey); ok {
+		if principal, ok := value.(*Principal); ok {
+			return principal
+		}
+	}
+	return nil
+}
+
+// SetPrincipal stores the authenticated principal on the request context
+func SetPrincipal(c *gin.Context, principal *Principal) {
+	c.Set(PrincipalContextKey, principal)
+}
+
+// HashKey returns the value stored for a plaintext API key
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// NewKey validates the role and scope of key and generates a new plaintext
+// API key for it. Only the prefix and hash are set on key; the plaintext key
+// is returned once and cannot be recovered later.
+func NewKey(key *models.APIKey) (string, error) {
+	if err := validateScope(key); err != nil {
+		return "", err
+	}
+
+	secret := make([]byte, 24)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	plaintext := KeyPrefix + hex.EncodeToString(secret)
+
+	key.Prefix = plaintext[:len(KeyPrefix)+8]
+	key.KeyHash = HashKey(plaintext)
+	return plaintext, nil
+}
+
+func validateScope(key *models.APIKey) error {
+	switch key.Role {
+	case models.RoleAdmin:
+		if key.BrandID != nil || key.CustomerID != nil {
+			return ErrInvalidScope
+		}
+	case models.RoleBrandOperator:
+		if key.BrandID == nil || key.CustomerID != nil {
+			return ErrInvalidScope
+		}
+	case models.RoleCustomer:
+		if key.CustomerID == nil || key.BrandID != nil {
+			return ErrInvalidScope
+		}
+	default:
+		return ErrInvalidRole
+	}
+	return nil
+}
@@ -0,0 +1,213 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"my-backend-app/config"
+	"my-backend-app/models"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Default token lifetimes, overridden by ACCESS_TOKEN_TTL and REFRESH_TOKEN_TTL
+const (
+	DefaultAccessTokenTTL  = 15 * time.Minute
+	DefaultRefreshTokenTTL = 30 * 24 * time.Hour
+)
+
+// Tokens signs and verifies customer tokens with the configured secret and
+// lifetimes. Tokens are disabled on a nil Tokens or one without a secret.
+type Tokens struct {
+	cfg config.Auth
+}
+
+// NewTokens returns Tokens using the signing secret and lifetimes of cfg
+func NewTokens(cfg config.Auth) *Tokens {
+	return &Tokens{cfg: cfg}
+}
+
+// One-time login code settings
+const (
+	OTPTTL         = 10 * time.Minute
+	MaxOTPAttempts = 5
+	otpDigits      = 6
+)
+
+var (
+	// ErrTokensDisabled is returned when JWT_SECRET is not configured
+	ErrTokensDisabled = errors.New("customer tokens are disabled: JWT_SECRET is not set")
+	// ErrOTPDisabled is returned when no OTPSender is configured
+	ErrOTPDisabled = errors.New("one-time code login is disabled: no sender is configured")
+	// ErrInvalidCredentials is returned for a wrong password or one-time code
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// OTPSender delivers one-time login codes to customers, for example by SMS or
+// email. Codes are credentials: implementations must never log them.
+type OTPSender interface {
+	SendOTP(ctx context.Context, customer models.Customer, code string) error
+}
+
+// OTPSenderFunc adapts a function to an OTPSender
+type OTPSenderFunc func(ctx context.Context, customer models.Customer, code string) error
+
+// SendOTP calls f
+func (f OTPSenderFunc) SendOTP(ctx context.Context, customer models.Customer, code string) error {
+	return f(ctx, customer, code)
+}
+
+// TokenPair is returned to a customer on login and refresh
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+// settings returns the configuration of t, which is empty for a nil t
+func (t *Tokens) settings() config.Auth {
+	if t == nil {
+		return config.Auth{}
+	}
+	return t.cfg
+}
+
+func (t *Tokens) secret() ([]byte, error) {
+	secret := t.settings().JWTSecret
+	if secret == "" {
+		return nil, ErrTokensDisabled
+	}
+	return []byte(secret), nil
+}
+
+func tokenTTL(ttl, fallback time.Duration) time.Duration {
+	if ttl > 0 {
+		return ttl
+	}
+	return fallback
+}
+
+// RefreshTokenExpiry returns when a refresh token issued at now expires
+func (t *Tokens) RefreshTokenExpiry(now time.Time) time.Time {
+	return now.Add(tokenTTL(t.settings().RefreshTokenTTL, DefaultRefreshTokenTTL))
+}
+
+// SignTokens signs a new access token and refresh token for the customer. The
+// refresh token carries refresh.ID so it can be rotated and revoked.
+func (t *Tokens) SignTokens(refresh models.RefreshToken, now time.Time) (*TokenPair, error) {
+	secret, err := t.secret()
+	if err != nil {
+		return nil, err
+	}
+	accessTTL := tokenTTL(t.settings().AccessTokenTTL, DefaultAccessTokenTTL)
+
+	access, err := SignToken(Claims{
+		Subject:   refresh.CustomerID.String(),
+		Type:      TokenTypeAccess,
+		ID:        uuid.NewString(),
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(accessTTL).Unix(),
+	}, secret)
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, err := SignToken(Claims{
+		Subject:   refresh.CustomerID.String(),
+		Type:      TokenTypeRefresh,
+		ID:        refresh.ID.String(),
+		IssuedAt:  now.Unix(),
+		ExpiresAt: refresh.ExpiresAt.Unix(),
+	}, secret)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:  access,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(accessTTL / time.Second),
+	}, nil
+}
+
+// ParseAccessToken verifies a customer access token and returns its principal
+func (t *Tokens) ParseAccessToken(token string, now time.Time) (*Principal, error) {
+	claims, err := t.parseToken(token, TokenTypeAccess, now)
+	if err != nil {
+		return nil, err
+	}
+	customerID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	return &Principal{Name: customerID.String(), Role: models.RoleCustomer, CustomerID: &customerID}, nil
+}
+
+// ParseRefreshToken verifies a refresh token and returns the ID of its stored
+// record and the customer it was issued to
+func (t *Tokens) ParseRefreshToken(token string, now time.Time) (id, customerID uuid.UUID, err error) {
+	claims, err := t.parseToken(token, TokenTypeRefresh, now)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, err
+	}
+	id, err = uuid.Parse(claims.ID)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, ErrInvalidToken
+	}
+	customerID, err = uuid.Parse(claims.Subject)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, ErrInvalidToken
+	}
+	return id, customerID, nil
+}
+
+// parseToken verifies a token signed with the configured secret and checks its type
+func (t *Tokens) parseToken(token, tokenType string, now time.Time) (*Claims, error) {
+	secret, err := t.secret()
+	if err != nil {
+		return nil, err
+	}
+	claims, err := ParseToken(token, secret, now)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Type != tokenType {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
+
+// HashPassword returns the bcrypt hash stored for a customer password
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return string(hash), err
+}
+
+// CheckPassword reports whether password matches the customer's stored hash
+func CheckPassword(customer models.Customer, password string) bool {
+	if customer.PasswordHash == "" {
+		return false
+	}
+	return bcrypt.CompareHashAndPassword([]byte(customer.PasswordHash), []byte(password)) == nil
+}
+
+// NewOTP generates a one-time login code for the customer and the hash stored for it
+func NewOTP(customerID uuid.UUID) (code, hash string, err error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", "", err
+	}
+	code = fmt.Sprintf("%0*d", otpDigits, n.Int64())
+	return code, OTPHash(customerID, code), nil
+}
+
+// OTPHash binds the code to the customer so equal codes hash differently
+func OTPHash(customerID uuid.UUID, code string) string {
+	return HashKey(customerID.String() + ":" + code)
+}
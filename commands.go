@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"my-backend-app/database"
+	"my-backend-app/migrations"
+	"my-backend-app/models"
+	"my-backend-app/service"
+
+	"github.com/google/uuid"
+)
+
+// reconcilePoints verifies every customer's cached point balance against the
+// sum of their ledger entries and returns the process exit code
+func reconcilePoints(services *service.Services) int {
+	mismatches, err := services.Customers.ReconcilePoints(context.Background())
+	if err != nil {
+		log.Println("Failed to reconcile point balances:", err)
+		return 1
+	}
+
+	for _, m := range mismatches {
+		log.Printf("Customer %s: cached balance %d, ledger balance %d", m.CustomerID, m.CachedBalance, m.LedgerBalance)
+	}
+
+	if len(mismatches) > 0 {
+		log.Printf("%d customer balance(s) do not match the ledger", len(mismatches))
+		return 1
+	}
+
+	log.Println("All customer balances match the ledger")
+	return 0
+}
+
+// createAPIKey issues an API key from the command line, which is how the first
+// admin key is created, and returns the process exit code
+func createAPIKey(services *service.Services, args []string) int {
+	flags := flag.NewFlagSet("create-api-key", flag.ContinueOnError)
+	name := flags.String("name", "", "name identifying the key holder")
+	role := flags.String("role", models.RoleAdmin, "admin, brand_operator or customer")
+	brandID := flags.String("brand-id", "", "brand a brand_operator key is scoped to")
+	customerID := flags.String("customer-id", "", "customer a customer key is scoped to")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *name == "" {
+		log.Println("-name is required")
+		return 2
+	}
+
+	apiKey := models.APIKey{Name: *name, Role: *role}
+	if *brandID != "" {
+		id, err := uuid.Parse(*brandID)
+		if err != nil {
+			log.Println("Invalid brand ID:", err)
+			return 2
+		}
+		apiKey.BrandID = &id
+	}
+	if *customerID != "" {
+		id, err := uuid.Parse(*customerID)
+		if err != nil {
+			log.Println("Invalid customer ID:", err)
+			return 2
+		}
+		apiKey.CustomerID = &id
+	}
+
+	plaintext, err := services.APIKeys.Create(context.Background(), &apiKey)
+	if err != nil {
+		log.Println("Failed to create API key:", err)
+		return 1
+	}
+
+	log.Printf("Created %s API key %q (%s). Store it now, it will not be shown again:", apiKey.Role, apiKey.Name, apiKey.ID)
+	fmt.Println(plaintext)
+	return 0
+}
+
+// runMigrate applies, rolls back or lists schema migrations and returns the
+// process exit code. Usage: migrate <up|down|status|baseline> [flags]
+func runMigrate(args []string) int {
+	if len(args) == 0 {
+		log.Println("Usage: migrate <up|down|status|baseline> [-dry-run] [-to version] [-steps n] [-version n]")
+		return 2
+	}
+
+	flags := flag.NewFlagSet("migrate "+args[0], flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "print the SQL that would run without changing the database")
+	to := flags.Int("to", 0, "up: apply migrations up to this version (default all)")
+	steps := flags.Int("steps", 1, "down: number of migrations to roll back")
+	version := flags.Int("version", 0, "baseline: record migrations up to this version as applied")
+	if err := flags.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	migrator, err := database.NewMigrator(database.GetDB(), migrations.FS)
+	if err != nil {
+		log.Println("Failed to load migrations:", err)
+		return 1
+	}
+	migrator.DryRun = *dryRun
+	if *dryRun {
+		migrator.Out = os.Stdout
+	}
+
+	var done []database.Migration
+	switch args[0] {
+	case "up":
+		done, err = migrator.Up(*to)
+	case "down":
+		done, err = migrator.Down(*steps)
+	case "baseline":
+		if *version <= 0 {
+			log.Println("-version is required")
+			return 2
+		}
+		done, err = migrator.Baseline(*version)
+	case "status":
+		return migrationStatus(migrator)
+	default:
+		log.Printf("Unknown migrate command %q", args[0])
+		return 2
+	}
+
+	verb := map[string]string{"up": "Applied", "down": "Rolled back", "baseline": "Baselined"}[args[0]]
+	if *dryRun {
+		verb = "Dry run: " + strings.ToLower(verb)
+	}
+	for _, migration := range done {
+		log.Printf("%s %03d_%s", verb, migration.Version, migration.Name)
+	}
+	if err != nil {
+		log.Println("Migration failed:", err)
+		return 1
+	}
+	if len(done) == 0 {
+		log.Println("Nothing to do")
+	}
+	return 0
+}
+
+// migrationStatus prints every known migration and whether it is applied
+func migrationStatus(migrator *database.Migrator) int {
+	statuses, err := migrator.Status()
+	if err != nil {
+		log.Println("Failed to read migration status:", err)
+		return 1
+	}
+
+	for _, status := range statuses {
+		applied := "pending"
+		if status.AppliedAt != nil {
+			applied = "applied " + status.AppliedAt.Format(time.RFC3339)
+		}
+		fmt.Printf("%03d_%-40s %s\n", status.Version, status.Name, applied)
+	}
+	return 0
+}
@@ -0,0 +1,283 @@
+// Package config loads the application settings. Values come from, in
+// increasing order of precedence: built-in defaults, an optional YAML file,
+// the config.env file and the process environment.
+package config
+
+import (
+	"errors"
+	"os"
+	"time"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultYAMLFile is read when CONFIG_FILE is not set, if it exists
+const DefaultYAMLFile = "config.yaml"
+
+// Supported values of DB_DRIVER and TEST_DB_DRIVER
+const (
+	DriverMySQL    = "mysql"
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
+// Supported values of LOG_LEVEL
+const (
+	LogLevelDebug = "debug"
+	LogLevelInfo  = "info"
+	LogLevelWarn  = "warn"
+	LogLevelError = "error"
+)
+
+// Config holds every application setting
+type Config struct {
+	// TestMode switches the database to TEST_DB_DRIVER and TEST_DB_DSN and
+	// silences startup logging
+	TestMode  bool       `yaml:"-"`
+	Server    Server     `yaml:"server"`
+	Database  Database   `yaml:"database"`
+	Auth      Auth       `yaml:"auth"`
+	CORS      CORS       `yaml:"cors"`
+	Log       Log        `yaml:"log"`
+	Tracing   Tracing    `yaml:"tracing"`
+	RateLimit RateLimits `yaml:"rate_limit"`
+}
+
+// Server configures the HTTP server
+type Server struct {
+	Port         string        `yaml:"port"`
+	Mode         string        `yaml:"mode"`
+	ReadTimeout  time.Duration `yaml:"read_timeout"`
+	WriteTimeout time.Duration `yaml:"write_timeout"`
+	IdleTimeout  time.Duration `yaml:"idle_timeout"`
+	// DrainDelay keeps serving after readiness starts failing so load
+	// balancers stop routing before the listener closes
+	DrainDelay time.Duration `yaml:"drain_delay"`
+	// ShutdownTimeout bounds how long in-flight requests may take to finish
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+	// TrustedProxies lists the proxy IPs and CIDRs whose X-Forwarded-For
+	// header is believed. With none, the client IP is the peer address.
+	TrustedProxies []string `yaml:"trusted_proxies"`
+}
+
+// Database configures the database connection and its pool. DSN, when set,
+// is used as is instead of the individual connection fields.
+type Database struct {
+	Driver   string `yaml:"driver"`
+	Host     string `yaml:"host"`
+	Port     string `yaml:"port"`
+	User     string `yaml:"user"`
+	Password string `yaml:"password"`
+	Name     string `yaml:"name"`
+	SSLMode  string `yaml:"sslmode"`
+	DSN      string `yaml:"dsn"`
+
+	MaxOpenConns    int           `yaml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time"`
+
+	// SlowQueryThreshold is the duration above which a statement is logged
+	// as a slow query; zero disables the warning
+	SlowQueryThreshold time.Duration `yaml:"slow_query_threshold"`
+}
+
+// Auth configures customer tokens. Login is disabled without a JWTSecret.
+type Auth struct {
+	JWTSecret       string        `yaml:"jwt_secret"`
+	AccessTokenTTL  time.Duration `yaml:"access_token_ttl"`
+	RefreshTokenTTL time.Duration `yaml:"refresh_token_ttl"`
+}
+
+// CORS configures cross-origin requests. An origin of "*" allows any origin.
+type CORS struct {
+	AllowedOrigins []string `yaml:"allowed_origins"`
+}
+
+// Log configures logging
+type Log struct {
+	Level string `yaml:"level"`
+}
+
+// RateLimit allows Requests per Period from one caller, as a token bucket
+// holding up to Requests tokens. Zero Requests disables the limit.
+type RateLimit struct {
+	Requests int           `yaml:"requests"`
+	Period   time.Duration `yaml:"period"`
+}
+
+// Enabled reports whether the limit applies
+func (l RateLimit) Enabled() bool {
+	return l.Requests > 0
+}
+
+// RateLimits configures the rate limit of each route group
+type RateLimits struct {
+	// API limits every authenticated /api/v1 route per API key or customer
+	API RateLimit `yaml:"api"`
+	// Auth limits the public login routes per client IP
+	Auth RateLimit `yaml:"auth"`
+	// Redemption limits POST /transaction/redemption per customer
+	Redemption RateLimit `yaml:"redemption"`
+}
+
+// Supported values of TRACING_EXPORTER
+const (
+	TracingExporterNone   = "none"
+	TracingExporterStdout = "stdout"
+	TracingExporterOTLP   = "otlp"
+)
+
+// Tracing configures OpenTelemetry tracing. OTLPEndpoint is a host:port
+// receiving OTLP over HTTP; when empty the exporter falls back to the
+// standard OTEL_EXPORTER_OTLP_* variables and then localhost:4318.
+type Tracing struct {
+	Exporter     string  `yaml:"exporter"`
+	ServiceName  string  `yaml:"service_name"`
+	SampleRatio  float64 `yaml:"sample_ratio"`
+	OTLPEndpoint string  `yaml:"otlp_endpoint"`
+	OTLPInsecure bool    `yaml:"otlp_insecure"`
+}
+
+// Default returns the settings used when no source sets a value
+func Default() *Config {
+	return &Config{
+		Server: Server{
+			Port:         "8080",
+			ReadTimeout:  15 * time.Second,
+			WriteTimeout: 30 * time.Second,
+			IdleTimeout:  60 * time.Second,
+
+			ShutdownTimeout: 30 * time.Second,
+		},
+		Database: Database{
+			Driver:          DriverMySQL,
+			SSLMode:         "disable",
+			MaxOpenConns:    25,
+			MaxIdleConns:    10,
+			ConnMaxLifetime: 30 * time.Minute,
+			ConnMaxIdleTime: 5 * time.Minute,
+
+			SlowQueryThreshold: 200 * time.Millisecond,
+		},
+		Auth: Auth{
+			AccessTokenTTL:  15 * time.Minute,
+			RefreshTokenTTL: 30 * 24 * time.Hour,
+		},
+		CORS: CORS{AllowedOrigins: []string{"*"}},
+		Log:  Log{Level: LogLevelInfo},
+		Tracing: Tracing{
+			Exporter:    TracingExporterNone,
+			ServiceName: "voucher-system",
+			SampleRatio: 1,
+		},
+		RateLimit: RateLimits{
+			API:        RateLimit{Requests: 300, Period: time.Minute},
+			Auth:       RateLimit{Requests: 10, Period: time.Minute},
+			Redemption: RateLimit{Requests: 10, Period: time.Minute},
+		},
+	}
+}
+
+// Load reads the settings from every source and validates them. envFile is
+// the dotenv file to read, usually config.env; it and the YAML file may be
+// missing. The YAML file is named by CONFIG_FILE and defaults to config.yaml.
+// Every invalid setting is reported in one *ValidationError.
+func Load(envFile string) (*Config, error) {
+	dotenv, err := godotenv.Read(envFile)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	env := source{dotenv: dotenv}
+
+	cfg := Default()
+
+	yamlFile, required := env.lookup("CONFIG_FILE")
+	if !required {
+		yamlFile = DefaultYAMLFile
+	}
+	if err := cfg.readYAML(yamlFile, required); err != nil {
+		return nil, err
+	}
+
+	cfg.readEnv(&env)
+	if len(env.problems) > 0 {
+		return nil, &ValidationError{Problems: env.problems}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// readYAML overlays the YAML file on cfg. A missing file is only an error if
+// it was named explicitly.
+func (cfg *Config) readYAML(path string, required bool) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && !required {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return yaml.Unmarshal(data, cfg)
+}
+
+// readEnv overlays the environment and dotenv file on cfg
+func (cfg *Config) readEnv(env *source) {
+	cfg.TestMode = env.bool("TEST_MODE", false)
+
+	env.string("SERVER_PORT", &cfg.Server.Port)
+	env.string("GIN_MODE", &cfg.Server.Mode)
+	env.duration("SERVER_READ_TIMEOUT", &cfg.Server.ReadTimeout)
+	env.duration("SERVER_WRITE_TIMEOUT", &cfg.Server.WriteTimeout)
+	env.duration("SERVER_IDLE_TIMEOUT", &cfg.Server.IdleTimeout)
+	env.duration("SERVER_DRAIN_DELAY", &cfg.Server.DrainDelay)
+	env.duration("SERVER_SHUTDOWN_TIMEOUT", &cfg.Server.ShutdownTimeout)
+	env.list("TRUSTED_PROXIES", &cfg.Server.TrustedProxies)
+
+	env.string("DB_DRIVER", &cfg.Database.Driver)
+	env.string("DB_HOST", &cfg.Database.Host)
+	env.string("DB_PORT", &cfg.Database.Port)
+	env.string("DB_USER", &cfg.Database.User)
+	env.string("DB_PASSWORD", &cfg.Database.Password)
+	env.string("DB_NAME", &cfg.Database.Name)
+	env.string("DB_SSLMODE", &cfg.Database.SSLMode)
+	env.string("DB_DSN", &cfg.Database.DSN)
+	env.int("DB_MAX_OPEN_CONNS", &cfg.Database.MaxOpenConns)
+	env.int("DB_MAX_IDLE_CONNS", &cfg.Database.MaxIdleConns)
+	env.duration("DB_CONN_MAX_LIFETIME", &cfg.Database.ConnMaxLifetime)
+	env.duration("DB_CONN_MAX_IDLE_TIME", &cfg.Database.ConnMaxIdleTime)
+	env.duration("DB_SLOW_QUERY_THRESHOLD", &cfg.Database.SlowQueryThreshold)
+
+	// Tests run against an in-memory SQLite database unless TEST_DB_DRIVER
+	// and TEST_DB_DSN point them at a server
+	if cfg.TestMode {
+		cfg.Database.Driver = DriverSQLite
+		cfg.Database.DSN = ":memory:"
+		env.string("TEST_DB_DRIVER", &cfg.Database.Driver)
+		if cfg.Database.Driver != DriverSQLite {
+			cfg.Database.DSN, _ = env.lookup("TEST_DB_DSN")
+		}
+	}
+
+	env.string("JWT_SECRET", &cfg.Auth.JWTSecret)
+	env.duration("ACCESS_TOKEN_TTL", &cfg.Auth.AccessTokenTTL)
+	env.duration("REFRESH_TOKEN_TTL", &cfg.Auth.RefreshTokenTTL)
+
+	env.list("CORS_ALLOWED_ORIGINS", &cfg.CORS.AllowedOrigins)
+
+	env.string("LOG_LEVEL", &cfg.Log.Level)
+
+	env.string("TRACING_EXPORTER", &cfg.Tracing.Exporter)
+	env.string("OTEL_SERVICE_NAME", &cfg.Tracing.ServiceName)
+	env.float("TRACING_SAMPLE_RATIO", &cfg.Tracing.SampleRatio)
+	env.string("TRACING_OTLP_ENDPOINT", &cfg.Tracing.OTLPEndpoint)
+	cfg.Tracing.OTLPInsecure = env.bool("TRACING_OTLP_INSECURE", cfg.Tracing.OTLPInsecure)
+
+	env.rateLimit("RATE_LIMIT_API", &cfg.RateLimit.API)
+	env.rateLimit("RATE_LIMIT_AUTH", &cfg.RateLimit.Auth)
+	env.rateLimit("RATE_LIMIT_REDEMPTION", &cfg.RateLimit.Redemption)
+}
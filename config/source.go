@@ -0,0 +1,113 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// source reads settings from the process environment, falling back to the
+// dotenv file. Empty values count as unset. Values that fail to parse are
+// collected in problems so they can be reported together.
+type source struct {
+	dotenv   map[string]string
+	problems []string
+}
+
+// lookup returns the value of a variable and whether it is set
+func (s *source) lookup(name string) (string, bool) {
+	if value := os.Getenv(name); value != "" {
+		return value, true
+	}
+	value := s.dotenv[name]
+	return value, value != ""
+}
+
+func (s *source) string(name string, target *string) {
+	if value, ok := s.lookup(name); ok {
+		*target = value
+	}
+}
+
+func (s *source) int(name string, target *int) {
+	if value, ok := s.lookup(name); ok {
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			s.problems = append(s.problems, fmt.Sprintf("%s must be a whole number, got %q", name, value))
+			return
+		}
+		*target = n
+	}
+}
+
+func (s *source) float(name string, target *float64) {
+	if value, ok := s.lookup(name); ok {
+		f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			s.problems = append(s.problems, fmt.Sprintf("%s must be a number, got %q", name, value))
+			return
+		}
+		*target = f
+	}
+}
+
+func (s *source) bool(name string, fallback bool) bool {
+	value, ok := s.lookup(name)
+	if !ok {
+		return fallback
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		s.problems = append(s.problems, fmt.Sprintf("%s must be true or false, got %q", name, value))
+		return fallback
+	}
+	return b
+}
+
+func (s *source) duration(name string, target *time.Duration) {
+	if value, ok := s.lookup(name); ok {
+		d, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil {
+			s.problems = append(s.problems, fmt.Sprintf("%s must be a duration such as 30s or 5m, got %q", name, value))
+			return
+		}
+		*target = d
+	}
+}
+
+// list reads a comma separated list, dropping empty entries
+func (s *source) list(name string, target *[]string) {
+	if value, ok := s.lookup(name); ok {
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		*target = items
+	}
+}
+
+// rateLimit reads a limit written as requests/period, such as 10/1m, or off
+func (s *source) rateLimit(name string, target *RateLimit) {
+	value, ok := s.lookup(name)
+	if !ok {
+		return
+	}
+	value = strings.TrimSpace(value)
+	if value == "off" || value == "0" {
+		*target = RateLimit{}
+		return
+	}
+
+	requests, period, found := strings.Cut(value, "/")
+	n, err := strconv.Atoi(requests)
+	d, err2 := time.ParseDuration(period)
+	if !found || err != nil || err2 != nil {
+		s.problems = append(s.problems, fmt.Sprintf("%s must be requests/period such as 10/1m, or off, got %q", name, value))
+		return
+	}
+	*target = RateLimit{Requests: n, Period: d}
+}
@@ -0,0 +1,154 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ValidationError lists every invalid or missing setting
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// Validate checks that the required settings are present and every setting is
+// in range. It reports all problems at once.
+func (cfg *Config) Validate() error {
+	var problems []string
+	add := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+	positive := func(name string, d time.Duration) {
+		if d <= 0 {
+			add("%s must be greater than zero", name)
+		}
+	}
+
+	if port, err := strconv.Atoi(cfg.Server.Port); err != nil || port < 1 || port > 65535 {
+		add("SERVER_PORT must be a port number between 1 and 65535, got %q", cfg.Server.Port)
+	}
+	switch cfg.Server.Mode {
+	case "", "debug", "release", "test":
+	default:
+		add("GIN_MODE must be debug, release or test, got %q", cfg.Server.Mode)
+	}
+	positive("SERVER_READ_TIMEOUT", cfg.Server.ReadTimeout)
+	positive("SERVER_WRITE_TIMEOUT", cfg.Server.WriteTimeout)
+	positive("SERVER_IDLE_TIMEOUT", cfg.Server.IdleTimeout)
+	positive("SERVER_SHUTDOWN_TIMEOUT", cfg.Server.ShutdownTimeout)
+	if cfg.Server.DrainDelay < 0 {
+		add("SERVER_DRAIN_DELAY must not be negative")
+	}
+	for _, proxy := range cfg.Server.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			add("TRUSTED_PROXIES entries must be IP addresses or CIDR ranges, got %q", proxy)
+		}
+	}
+
+	db := cfg.Database
+	driver := "DB_DRIVER"
+	if cfg.TestMode {
+		driver = "TEST_DB_DRIVER"
+	}
+	switch db.Driver {
+	case DriverMySQL, DriverPostgres:
+		if cfg.TestMode && db.DSN == "" {
+			add("TEST_DB_DSN is required when TEST_DB_DRIVER is %s", db.Driver)
+		}
+		if db.DSN == "" {
+			required := map[string]string{"DB_HOST": db.Host, "DB_PORT": db.Port, "DB_USER": db.User, "DB_NAME": db.Name}
+			for _, name := range []string{"DB_HOST", "DB_PORT", "DB_USER", "DB_NAME"} {
+				if required[name] == "" {
+					add("%s is required when DB_DRIVER is %s", name, db.Driver)
+				}
+			}
+		}
+	case DriverSQLite:
+		if db.DSN == "" && db.Name == "" {
+			add("DB_NAME is required when DB_DRIVER is sqlite")
+		}
+	default:
+		add("%s must be mysql, postgres or sqlite, got %q", driver, db.Driver)
+	}
+	if db.MaxOpenConns < 0 {
+		add("DB_MAX_OPEN_CONNS must not be negative")
+	}
+	// Migrations hold their lock on one connection and run on another
+	if db.MaxOpenConns == 1 && db.Driver != DriverSQLite {
+		add("DB_MAX_OPEN_CONNS must be at least 2 when DB_DRIVER is %s", db.Driver)
+	}
+	if db.MaxIdleConns < 0 {
+		add("DB_MAX_IDLE_CONNS must not be negative")
+	}
+	if db.MaxOpenConns > 0 && db.MaxIdleConns > db.MaxOpenConns {
+		add("DB_MAX_IDLE_CONNS (%d) must not exceed DB_MAX_OPEN_CONNS (%d)", db.MaxIdleConns, db.MaxOpenConns)
+	}
+	if db.ConnMaxLifetime < 0 {
+		add("DB_CONN_MAX_LIFETIME must not be negative")
+	}
+	if db.ConnMaxIdleTime < 0 {
+		add("DB_CONN_MAX_IDLE_TIME must not be negative")
+	}
+	if db.SlowQueryThreshold < 0 {
+		add("DB_SLOW_QUERY_THRESHOLD must not be negative")
+	}
+
+	positive("ACCESS_TOKEN_TTL", cfg.Auth.AccessTokenTTL)
+	positive("REFRESH_TOKEN_TTL", cfg.Auth.RefreshTokenTTL)
+
+	for _, origin := range cfg.CORS.AllowedOrigins {
+		if origin == "*" {
+			continue
+		}
+		u, err := url.Parse(origin)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || (u.Path != "" && u.Path != "/") {
+			add("CORS_ALLOWED_ORIGINS entries must be * or an origin such as https://example.com, got %q", origin)
+		}
+	}
+
+	switch cfg.Log.Level {
+	case LogLevelDebug, LogLevelInfo, LogLevelWarn, LogLevelError:
+	default:
+		add("LOG_LEVEL must be debug, info, warn or error, got %q", cfg.Log.Level)
+	}
+
+	switch cfg.Tracing.Exporter {
+	case TracingExporterNone, TracingExporterStdout, TracingExporterOTLP:
+	default:
+		add("TRACING_EXPORTER must be none, stdout or otlp, got %q", cfg.Tracing.Exporter)
+	}
+	if cfg.Tracing.SampleRatio < 0 || cfg.Tracing.SampleRatio > 1 {
+		add("TRACING_SAMPLE_RATIO must be between 0 and 1, got %v", cfg.Tracing.SampleRatio)
+	}
+	if cfg.Tracing.ServiceName == "" {
+		add("OTEL_SERVICE_NAME must not be empty")
+	}
+
+	for _, limit := range []struct {
+		name string
+		RateLimit
+	}{
+		{"RATE_LIMIT_API", cfg.RateLimit.API},
+		{"RATE_LIMIT_AUTH", cfg.RateLimit.Auth},
+		{"RATE_LIMIT_REDEMPTION", cfg.RateLimit.Redemption},
+	} {
+		if limit.Requests < 0 {
+			add("%s must not allow a negative number of requests", limit.name)
+		}
+		if limit.Enabled() && limit.Period <= 0 {
+			add("%s must have a period greater than zero", limit.name)
+		}
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
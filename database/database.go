@@ -3,65 +3,189 @@ package database
 import (
 	"fmt"
 	"log"
-	"os"
+	"log/slog"
+	"net/url"
+	"strings"
 
-	"my-backend-app/models"
+	"my-backend-app/config"
+	"my-backend-app/logging"
+	"my-backend-app/migrations"
+	"my-backend-app/tracing"
 
 	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
 
+// Supported values of DB_DRIVER and TEST_DB_DRIVER
+const (
+	DriverMySQL    = config.DriverMySQL
+	DriverPostgres = config.DriverPostgres
+	DriverSQLite   = config.DriverSQLite
+)
+
 var DB *gorm.DB
 
-// InitDB initializes the database connection
-func InitDB() {
+// InitDB connects to the database and applies any pending migrations
+func InitDB(cfg *config.Config) {
+	Connect(cfg)
+
+	// Test suites share a MySQL or PostgreSQL test database, so each one
+	// starts from an empty schema
+	if cfg.TestMode && DB.Dialector.Name() != DriverSQLite {
+		if err := DropAllTables(DB); err != nil {
+			log.Fatal("Failed to reset test database:", err)
+		}
+	}
+
+	migrator, err := NewMigrator(DB, migrations.FS)
+	if err != nil {
+		log.Fatal("Failed to load migrations:", err)
+	}
+	applied, err := migrator.Up(0)
+	if err != nil {
+		log.Fatal("Failed to migrate database:", err)
+	}
+
+	if !cfg.TestMode {
+		slog.Info("Database connected", "migrations_applied", len(applied))
+	}
+}
+
+// Connect opens the database connection without touching the schema, sizes
+// its pool and traces its queries
+func Connect(cfg *config.Config) {
+	driver, dsn := ConnectionSettings(cfg.Database)
+
 	var err error
+	gormLogger := logging.NewGormLogger(slog.Default(), LogLevel(cfg), cfg.Database.SlowQueryThreshold)
+	DB, err = Open(driver, dsn, gormLogger)
+	if err != nil {
+		log.Fatal("Failed to connect to database:", err)
+	}
 
-	// Check if we're in test mode
-	if os.Getenv("TEST_MODE") == "true" {
-		// Use SQLite for testing
-		DB, err = gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
-			Logger: logger.Default.LogMode(logger.Silent),
-		})
-		if err != nil {
-			log.Fatal("Failed to connect to test database:", err)
+	if err := DB.Use(tracing.GormPlugin{}); err != nil {
+		log.Fatal("Failed to enable query tracing:", err)
+	}
+
+	sqlDB, err := DB.DB()
+	if err != nil {
+		log.Fatal("Failed to connect to database:", err)
+	}
+	// Open already limits in-memory SQLite to its single shared connection
+	if !(driver == DriverSQLite && dsn == ":memory:") {
+		sqlDB.SetMaxOpenConns(cfg.Database.MaxOpenConns)
+	}
+	sqlDB.SetMaxIdleConns(cfg.Database.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.Database.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(cfg.Database.ConnMaxIdleTime)
+}
+
+// LogLevel maps LOG_LEVEL to the GORM log level. Every SQL statement is only
+// logged at debug, slow queries from warn; test mode is silent.
+func LogLevel(cfg *config.Config) logger.LogLevel {
+	if cfg.TestMode {
+		return logger.Silent
+	}
+	switch cfg.Log.Level {
+	case config.LogLevelDebug:
+		return logger.Info
+	case config.LogLevelError:
+		return logger.Error
+	default:
+		return logger.Warn
+	}
+}
+
+// ConnectionSettings returns the driver and DSN for the database settings.
+// An explicit DSN is used as is; otherwise it is built from the host, port,
+// user, password and name (the file path for sqlite).
+func ConnectionSettings(db config.Database) (driver, dsn string) {
+	if db.DSN != "" {
+		return db.Driver, db.DSN
+	}
+
+	switch db.Driver {
+	case DriverPostgres:
+		dsn := url.URL{
+			Scheme:   "postgres",
+			User:     url.UserPassword(db.User, db.Password),
+			Host:     db.Host + ":" + db.Port,
+			Path:     db.Name,
+			RawQuery: "sslmode=" + url.QueryEscape(db.SSLMode),
 		}
-	} else {
-		// Use MySQL for production
-		dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
-			os.Getenv("DB_USER"),
-			os.Getenv("DB_PASSWORD"),
-			os.Getenv("DB_HOST"),
-			os.Getenv("DB_PORT"),
-			os.Getenv("DB_NAME"),
-		)
-
-		DB, err = gorm.Open(mysql.Open(dsn), &gorm.Config{
-			Logger: logger.Default.LogMode(logger.Info),
-		})
+		return db.Driver, dsn.String()
+	case DriverSQLite:
+		return db.Driver, db.Name
+	default:
+		return db.Driver, fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
+			db.User, db.Password, db.Host, db.Port, db.Name)
+	}
+}
+
+// Open connects to a database with one of the supported drivers
+func Open(driver, dsn string, gormLogger logger.Interface) (*gorm.DB, error) {
+	var dialector gorm.Dialector
+	switch driver {
+	case DriverMySQL:
+		dialector = mysql.Open(dsn)
+	case DriverPostgres:
+		dialector = postgres.Open(dsn)
+	case DriverSQLite:
+		dialector = sqlite.Open(dsn)
+	default:
+		return nil, fmt.Errorf("unsupported database driver %q, use mysql, postgres or sqlite", driver)
+	}
+
+	db, err := gorm.Open(dialector, &gorm.Config{Logger: gormLogger})
+	if err != nil {
+		return nil, err
+	}
+
+	// Every connection to ":memory:" opens a separate database, so keep
+	// the pool to a single connection to share one schema across goroutines
+	if driver == DriverSQLite && dsn == ":memory:" {
+		sqlDB, err := db.DB()
 		if err != nil {
-			log.Fatal("Failed to connect to database:", err)
+			return nil, err
 		}
+		sqlDB.SetMaxOpenConns(1)
 	}
+	return db, nil
+}
 
-	// Auto migrate the schema
-	err = DB.AutoMigrate(
-		&models.Brand{},
-		&models.Voucher{},
-		&models.Customer{},
-		&models.Transaction{},
-		&models.TransactionItem{},
-	)
-
+// DropAllTables drops every table in the database. It is only meant for
+// resetting test databases.
+func DropAllTables(db *gorm.DB) error {
+	tables, err := db.Migrator().GetTables()
 	if err != nil {
-		log.Fatal("Failed to migrate database:", err)
+		return err
+	}
+
+	var drop []interface{}
+	for _, table := range tables {
+		if !strings.HasPrefix(table, "sqlite_") {
+			drop = append(drop, table)
+		}
+	}
+	if len(drop) == 0 {
+		return nil
 	}
+	return db.Migrator().DropTable(drop...)
+}
 
-	if os.Getenv("TEST_MODE") != "true" {
-		log.Println("Database connected and migrated successfully")
+// Close closes the connection pool opened by Connect
+func Close() error {
+	if DB == nil {
+		return nil
+	}
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return err
 	}
+	return sqlDB.Close()
 }
 
 // GetDB returns the database instance
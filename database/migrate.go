@@ -0,0 +1,556 @@
+package database
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MigrationsTable records which migrations have been applied
+const MigrationsTable = "schema_migrations"
+
+// Section markers splitting a migration file into its up and down SQL
+const (
+	upMarker   = "-- +migrate Up"
+	downMarker = "-- +migrate Down"
+)
+
+var migrationFileName = regexp.MustCompile(`^(\d+)_(\w+)\.sql$`)
+
+var (
+	// ErrChecksumMismatch is returned when an applied migration file was edited
+	ErrChecksumMismatch = errors.New("applied migration has been modified")
+	// ErrUnknownMigration is returned when the database has a migration the binary does not know
+	ErrUnknownMigration = errors.New("applied migration is missing from the migration files")
+	// ErrNotBaselined is returned when the schema predates the migration runner
+	ErrNotBaselined = errors.New("database has tables but no migration history; run the migrate baseline command first")
+	// ErrIrreversible is returned when rolling back a migration without a down section
+	ErrIrreversible = errors.New("migration has no down section")
+	// ErrLocked is returned when another process holds the migration lock for longer than LockTimeout
+	ErrLocked = errors.New("another process is migrating the database")
+)
+
+// LockTimeout is how long Up, Down and Baseline wait for another process,
+// such as a replica starting at the same time, to finish migrating
+const LockTimeout = time.Minute
+
+// lockName names the advisory lock taken while migrating. PostgreSQL advisory
+// locks take a number, derived from the name with FNV-1a.
+const lockName = "my-backend-app:" + MigrationsTable
+
+// Migration is a single versioned schema change
+type Migration struct {
+	Version  int
+	Name     string
+	Up       []string
+	Down     []string
+	Checksum string
+}
+
+// AppliedMigration is a row of the schema_migrations table
+type AppliedMigration struct {
+	Version   int       `gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"size:255;not null"`
+	Checksum  string    `gorm:"size:64;not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+// TableName overrides the default pluralised table name
+func (AppliedMigration) TableName() string {
+	return MigrationsTable
+}
+
+// MigrationStatus reports whether a known migration has been applied
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time
+}
+
+// Migrator applies and rolls back the migrations of one database. In dry-run
+// mode it writes the SQL it would run to Out and leaves the database untouched.
+type Migrator struct {
+	DB         *gorm.DB
+	Migrations []Migration
+	DryRun     bool
+	Out        io.Writer
+}
+
+// NewMigrator loads the migrations for the dialect of db from source, which
+// holds one directory per dialect
+func NewMigrator(db *gorm.DB, source fs.FS) (*Migrator, error) {
+	migrations, err := LoadMigrations(source, db.Dialector.Name())
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{DB: db, Migrations: migrations, Out: io.Discard}, nil
+}
+
+// LoadMigrations reads and parses the migration files of a dialect, ordered by version
+func LoadMigrations(source fs.FS, dialect string) ([]Migration, error) {
+	entries, err := fs.ReadDir(source, dialect)
+	if err != nil {
+		return nil, fmt.Errorf("no migrations for dialect %q: %w", dialect, err)
+	}
+
+	var migrations []Migration
+	seen := make(map[int]string)
+	for _, entry := range entries {
+		match := migrationFileName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+		version, _ := strconv.Atoi(match[1])
+		if other, ok := seen[version]; ok {
+			return nil, fmt.Errorf("migrations %s and %s share version %d", other, entry.Name(), version)
+		}
+		seen[version] = entry.Name()
+
+		content, err := fs.ReadFile(source, path.Join(dialect, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		migration, err := parseMigration(version, match[2], dialect, content)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", entry.Name(), err)
+		}
+		migrations = append(migrations, migration)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+func parseMigration(version int, name, dialect string, content []byte) (Migration, error) {
+	sum := sha256.Sum256(content)
+	migration := Migration{Version: version, Name: name, Checksum: hex.EncodeToString(sum[:])}
+
+	var up, down strings.Builder
+	var current *strings.Builder
+	scanner := bufio.NewScanner(strings.NewReader(string(content)))
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch strings.TrimSpace(line) {
+		case upMarker:
+			current = &up
+			continue
+		case downMarker:
+			current = &down
+			continue
+		}
+		// Anything before the first marker is the file header
+		if current != nil {
+			current.WriteString(line)
+			current.WriteString("\n")
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return migration, err
+	}
+
+	migration.Up = splitStatements(up.String(), dialect)
+	migration.Down = splitStatements(down.String(), dialect)
+	if len(migration.Up) == 0 {
+		return migration, errors.New("missing " + upMarker + " section")
+	}
+	return migration, nil
+}
+
+// splitStatements splits a script on semicolons and drops comments, since
+// drivers run one statement per Exec. Semicolons inside quoted strings, quoted
+// identifiers, comments and PostgreSQL dollar-quoted bodies such as function
+// definitions do not end a statement. Backslash escapes inside strings are
+// only recognised on MySQL, the one dialect that enables them by default.
+func splitStatements(script, dialect string) []string {
+	var statements []string
+	var current strings.Builder
+
+	flush := func() {
+		if statement := strings.TrimSpace(current.String()); statement != "" {
+			statements = append(statements, statement)
+		}
+		current.Reset()
+	}
+
+	for i := 0; i < len(script); i++ {
+		ch := script[i]
+		switch {
+		case ch == '\'' || ch == '"' || ch == '`':
+			end := closingQuote(script, i, dialect == DriverMySQL && ch != '`')
+			current.WriteString(script[i:end])
+			i = end - 1
+			continue
+		case ch == '$' && dialect == DriverPostgres && (i == 0 || !isIdentifierByte(script[i-1])):
+			if tag := dollarQuote.FindString(script[i:]); tag != "" {
+				end := len(script)
+				if n := strings.Index(script[i+len(tag):], tag); n >= 0 {
+					end = i + len(tag) + n + len(tag)
+				}
+				current.WriteString(script[i:end])
+				i = end - 1
+				continue
+			}
+		case ch == '-' && strings.HasPrefix(script[i:], "--"):
+			for i < len(script) && script[i] != '\n' {
+				i++
+			}
+			ch = '\n'
+		case ch == '/' && strings.HasPrefix(script[i:], "/*"):
+			end := strings.Index(script[i+2:], "*/")
+			if end < 0 {
+				i = len(script)
+			} else {
+				i += 2 + end + 1
+			}
+			ch = ' '
+		case ch == ';':
+			flush()
+			continue
+		}
+		if i < len(script) {
+			current.WriteByte(ch)
+		}
+	}
+	flush()
+	return statements
+}
+
+// dollarQuote matches the opening tag of a PostgreSQL dollar-quoted string,
+// such as $$ or $body$
+var dollarQuote = regexp.MustCompile(`^\$([A-Za-z_][A-Za-z0-9_]*)?\$`)
+
+// isIdentifierByte reports whether ch may appear in an unquoted identifier,
+// where a $ does not open a dollar-quoted string
+func isIdentifierByte(ch byte) bool {
+	return ch == '_' || ch == '$' || ch >= '0' && ch <= '9' || ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z'
+}
+
+// closingQuote returns the index just past the quote that closes the string
+// or identifier opened at start, or len(script) if it is never closed.
+// Doubled quotes stand for the quote itself.
+func closingQuote(script string, start int, backslashEscapes bool) int {
+	quote := script[start]
+	for i := start + 1; i < len(script); i++ {
+		switch script[i] {
+		case '\\':
+			if backslashEscapes {
+				i++
+			}
+		case quote:
+			if i+1 < len(script) && script[i+1] == quote {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return len(script)
+}
+
+// Applied returns the recorded migrations ordered by version
+func (m *Migrator) Applied() ([]AppliedMigration, error) {
+	var applied []AppliedMigration
+	if !m.DB.Migrator().HasTable(MigrationsTable) {
+		return applied, nil
+	}
+	err := m.DB.Order("version").Find(&applied).Error
+	return applied, err
+}
+
+// Version returns the highest applied migration version, or 0 for none
+func (m *Migrator) Version() (int, error) {
+	applied, err := m.Applied()
+	if err != nil || len(applied) == 0 {
+		return 0, err
+	}
+	return applied[len(applied)-1].Version, nil
+}
+
+// Status lists every known migration with the time it was applied, if it was
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	applied, err := m.verify()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(m.Migrations))
+	for _, migration := range m.Migrations {
+		status := MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if record, ok := applied[migration.Version]; ok {
+			status.AppliedAt = &record.AppliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// Up applies every pending migration up to and including target, or all of
+// them when target is 0, and returns the migrations it applied
+func (m *Migrator) Up(target int) ([]Migration, error) {
+	return m.locked(func() ([]Migration, error) { return m.up(target) })
+}
+
+func (m *Migrator) up(target int) ([]Migration, error) {
+	applied, err := m.verify()
+	if err != nil {
+		return nil, err
+	}
+	if len(applied) == 0 {
+		if err := m.checkBaselined(); err != nil {
+			return nil, err
+		}
+	}
+
+	var done []Migration
+	for _, migration := range m.Migrations {
+		if target > 0 && migration.Version > target {
+			break
+		}
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+		if err := m.run(migration, migration.Up, true); err != nil {
+			return done, err
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// Down rolls back the last steps applied migrations, newest first, and
+// returns the migrations it rolled back
+func (m *Migrator) Down(steps int) ([]Migration, error) {
+	return m.locked(func() ([]Migration, error) { return m.down(steps) })
+}
+
+func (m *Migrator) down(steps int) ([]Migration, error) {
+	applied, err := m.verify()
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for i := len(m.Migrations) - 1; i >= 0 && len(done) < steps; i-- {
+		migration := m.Migrations[i]
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+		if len(migration.Down) == 0 {
+			return done, fmt.Errorf("%03d_%s: %w", migration.Version, migration.Name, ErrIrreversible)
+		}
+		if err := m.run(migration, migration.Down, false); err != nil {
+			return done, err
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// Baseline records every migration up to and including version as applied
+// without running it, for databases whose schema was created before the
+// migration runner existed
+func (m *Migrator) Baseline(version int) ([]Migration, error) {
+	return m.locked(func() ([]Migration, error) { return m.baseline(version) })
+}
+
+func (m *Migrator) baseline(version int) ([]Migration, error) {
+	applied, err := m.verify()
+	if err != nil {
+		return nil, err
+	}
+	if len(applied) > 0 {
+		return nil, errors.New("database already has a migration history")
+	}
+
+	var done []Migration
+	for _, migration := range m.Migrations {
+		if migration.Version > version {
+			break
+		}
+		if err := m.run(migration, nil, true); err != nil {
+			return done, err
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// locked runs migrate while holding a database-wide advisory lock, so that
+// processes starting together apply each migration once. The lock belongs to
+// a connection taken from the pool for the duration. SQLite has no advisory
+// locks and allows a single writer, so there migrate runs unlocked, as it
+// does in dry-run mode.
+func (m *Migrator) locked(migrate func() ([]Migration, error)) (done []Migration, err error) {
+	dialect := m.DB.Dialector.Name()
+	if m.DryRun || (dialect != DriverMySQL && dialect != DriverPostgres) {
+		return migrate()
+	}
+
+	sqlDB, err := m.DB.DB()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), LockTimeout)
+	defer cancel()
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	var unlock func() error
+	switch dialect {
+	case DriverMySQL:
+		var acquired sql.NullInt64
+		err = conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", lockName, int(LockTimeout/time.Second)).Scan(&acquired)
+		if err == nil && acquired.Int64 != 1 {
+			err = ErrLocked
+		}
+		unlock = func() error {
+			_, err := conn.ExecContext(context.Background(), "SELECT RELEASE_LOCK(?)", lockName)
+			return err
+		}
+	case DriverPostgres:
+		key := postgresLockKey()
+		_, err = conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", key)
+		unlock = func() error {
+			_, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", key)
+			return err
+		}
+	}
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		err = ErrLocked
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to take the migration lock: %w", err)
+	}
+
+	defer func() {
+		if unlockErr := unlock(); unlockErr != nil {
+			// Ending the session releases the lock, so drop the connection
+			// instead of returning it to the pool still holding the lock
+			_ = conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+			if err == nil {
+				err = fmt.Errorf("failed to release the migration lock: %w", unlockErr)
+			}
+		}
+	}()
+	return migrate()
+}
+
+// postgresLockKey derives the PostgreSQL advisory lock key from lockName
+func postgresLockKey() int64 {
+	h := fnv.New64a()
+	h.Write([]byte(lockName))
+	return int64(h.Sum64())
+}
+
+// verify checks that every applied migration is still known and unchanged,
+// and returns the applied migrations by version
+func (m *Migrator) verify() (map[int]AppliedMigration, error) {
+	records, err := m.Applied()
+	if err != nil {
+		return nil, err
+	}
+
+	known := make(map[int]Migration, len(m.Migrations))
+	for _, migration := range m.Migrations {
+		known[migration.Version] = migration
+	}
+
+	applied := make(map[int]AppliedMigration, len(records))
+	for _, record := range records {
+		migration, ok := known[record.Version]
+		if !ok {
+			return nil, fmt.Errorf("%03d_%s: %w", record.Version, record.Name, ErrUnknownMigration)
+		}
+		if migration.Checksum != record.Checksum {
+			return nil, fmt.Errorf("%03d_%s: %w", record.Version, record.Name, ErrChecksumMismatch)
+		}
+		applied[record.Version] = record
+	}
+	return applied, nil
+}
+
+// checkBaselined refuses to migrate a database that already has tables but
+// no migration history, since the first migrations would fail half way
+func (m *Migrator) checkBaselined() error {
+	tables, err := m.DB.Migrator().GetTables()
+	if err != nil {
+		return err
+	}
+	for _, table := range tables {
+		if table != MigrationsTable && !strings.HasPrefix(table, "sqlite_") {
+			return ErrNotBaselined
+		}
+	}
+	return nil
+}
+
+// run executes statements and records (up) or removes (down) the migration in
+// one transaction. MySQL commits DDL implicitly, so a failed MySQL migration
+// may be left partly applied and has to be repaired by hand.
+func (m *Migrator) run(migration Migration, statements []string, up bool) error {
+	direction := "down"
+	if up {
+		direction = "up"
+	}
+	fmt.Fprintf(m.Out, "-- %03d_%s (%s)\n", migration.Version, migration.Name, direction)
+
+	if m.DryRun {
+		for _, statement := range statements {
+			fmt.Fprintf(m.Out, "%s;\n", statement)
+		}
+		return nil
+	}
+
+	if err := m.ensureTable(); err != nil {
+		return err
+	}
+
+	err := m.DB.Transaction(func(tx *gorm.DB) error {
+		for _, statement := range statements {
+			if err := tx.Exec(statement).Error; err != nil {
+				return fmt.Errorf("%w\n%s", err, statement)
+			}
+		}
+		if up {
+			return tx.Create(&AppliedMigration{
+				Version:   migration.Version,
+				Name:      migration.Name,
+				Checksum:  migration.Checksum,
+				AppliedAt: time.Now(),
+			}).Error
+		}
+		return tx.Delete(&AppliedMigration{}, "version = ?", migration.Version).Error
+	})
+	if err != nil {
+		return fmt.Errorf("migration %03d_%s (%s) failed: %w", migration.Version, migration.Name, direction, err)
+	}
+	return nil
+}
+
+func (m *Migrator) ensureTable() error {
+	return m.DB.Exec(`CREATE TABLE IF NOT EXISTS ` + MigrationsTable + ` (
+    version BIGINT NOT NULL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    checksum CHAR(64) NOT NULL,
+    applied_at TIMESTAMP NOT NULL
+)`).Error
+}
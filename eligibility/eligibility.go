@@ -0,0 +1,119 @@
+package eligibility
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"my-backend-app/models"
+	"my-backend-app/repository"
+
+	"github.com/google/uuid"
+)
+
+// Reason codes returned when a customer or item cannot be redeemed
+const (
+	CodeCustomerInactive   = "CUSTOMER_INACTIVE"
+	CodeInvalidVoucherID   = "INVALID_VOUCHER_ID"
+	CodeVoucherNotFound    = "VOUCHER_NOT_FOUND"
+	CodeVoucherInactive    = "VOUCHER_INACTIVE"
+	CodeBrandInactive      = "BRAND_INACTIVE"
+	CodeVoucherNotYetValid = "VOUCHER_NOT_YET_VALID"
+	CodeVoucherExpired     = "VOUCHER_EXPIRED"
+)
+
+// Reason explains why a customer or item is not eligible
+type Reason struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ItemResult lists the reasons one requested item cannot be redeemed. Index is
+// the position of the item in the request.
+type ItemResult struct {
+	Index     int      `json:"index"`
+	VoucherID string   `json:"voucher_id"`
+	Reasons   []Reason `json:"reasons"`
+}
+
+// Result is the outcome of Check. Customer and Items only hold failures;
+// Vouchers holds every requested voucher that could be loaded, with its brand.
+type Result struct {
+	Customer []Reason                     `json:"customer,omitempty"`
+	Items    []ItemResult                 `json:"items,omitempty"`
+	Vouchers map[uuid.UUID]models.Voucher `json:"-"`
+}
+
+// Eligible reports whether the customer may redeem every requested item
+func (r *Result) Eligible() bool {
+	return len(r.Customer) == 0 && len(r.Items) == 0
+}
+
+// Check loads the requested vouchers and reports every reason the customer or
+// any item cannot be redeemed at now, rather than stopping at the first one
+func Check(ctx context.Context, vouchers repository.VoucherRepository, customer models.Customer, voucherIDs []string, now time.Time) (*Result, error) {
+	result := &Result{
+		Customer: CustomerReasons(customer),
+		Vouchers: make(map[uuid.UUID]models.Voucher),
+	}
+
+	for i, rawID := range voucherIDs {
+		reasons, err := itemReasons(ctx, vouchers, result.Vouchers, rawID, now)
+		if err != nil {
+			return nil, err
+		}
+		if len(reasons) > 0 {
+			result.Items = append(result.Items, ItemResult{Index: i, VoucherID: rawID, Reasons: reasons})
+		}
+	}
+	return result, nil
+}
+
+// itemReasons checks one requested voucher, loading it into vouchers on first use
+func itemReasons(ctx context.Context, repo repository.VoucherRepository, vouchers map[uuid.UUID]models.Voucher, rawID string, now time.Time) ([]Reason, error) {
+	voucherID, err := uuid.Parse(rawID)
+	if err != nil {
+		return []Reason{{Code: CodeInvalidVoucherID, Message: "Invalid voucher ID"}}, nil
+	}
+
+	voucher, ok := vouchers[voucherID]
+	if !ok {
+		// Deleted brands are loaded too so they can be reported as unavailable
+		voucher, err = repo.Get(ctx, voucherID, false)
+		if errors.Is(err, repository.ErrNotFound) {
+			return []Reason{{Code: CodeVoucherNotFound, Message: "Voucher not found"}}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		vouchers[voucherID] = voucher
+	}
+	return VoucherReasons(voucher, now), nil
+}
+
+// CustomerReasons reports why the customer cannot redeem anything
+func CustomerReasons(customer models.Customer) []Reason {
+	if !customer.IsActive {
+		return []Reason{{Code: CodeCustomerInactive, Message: "Customer is inactive"}}
+	}
+	return nil
+}
+
+// VoucherReasons reports why the voucher cannot be redeemed at now. The
+// voucher's Brand must be loaded.
+func VoucherReasons(voucher models.Voucher, now time.Time) []Reason {
+	var reasons []Reason
+	if !voucher.IsActive {
+		reasons = append(reasons, Reason{Code: CodeVoucherInactive, Message: "Voucher is inactive"})
+	}
+	if !voucher.Brand.IsActive || voucher.Brand.DeletedAt.Valid {
+		reasons = append(reasons, Reason{Code: CodeBrandInactive, Message: "Brand is inactive"})
+	}
+	if !voucher.ValidFrom.IsZero() && now.Before(voucher.ValidFrom) {
+		reasons = append(reasons, Reason{Code: CodeVoucherNotYetValid, Message: "Voucher is not yet valid"})
+	}
+	if !voucher.ValidTo.IsZero() && now.After(voucher.ValidTo) {
+		reasons = append(reasons, Reason{Code: CodeVoucherExpired, Message: "Voucher has expired"})
+	}
+	return reasons
+}
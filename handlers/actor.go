@@ -0,0 +1,23 @@
+package handlers
+
+import (
+	"my-backend-app/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ActorContextKey is the gin context key holding the identity of the caller.
+// It is recorded on ledger entries so every balance change can be attributed.
+const ActorContextKey = "actor"
+
+// actorFromContext returns the caller identity set by upstream middleware,
+// falling back to the authenticated principal
+func actorFromContext(c *gin.Context) string {
+	if actor := c.GetString(ActorContextKey); actor != "" {
+		return actor
+	}
+	if principal := auth.FromContext(c); principal != nil {
+		return principal.Actor()
+	}
+	return "anonymous"
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"my-backend-app/apierror"
+	"my-backend-app/auth"
+	"my-backend-app/models"
+	"my-backend-app/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// CreateAPIKeyRequest represents the request body for creating an API key
+type CreateAPIKeyRequest struct {
+	Name       string `json:"name" binding:"required,max=255"`
+	Role       string `json:"role" binding:"required"`
+	BrandID    string `json:"brand_id"`
+	CustomerID string `json:"customer_id"`
+}
+
+// CreateAPIKey issues a new API key. The plaintext key is only returned in
+// this response; the database keeps its hash.
+func (h *Handler) CreateAPIKey(c *gin.Context) {
+	var req CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindingError(c, err)
+		return
+	}
+
+	apiKey := models.APIKey{Name: req.Name, Role: req.Role}
+
+	if req.BrandID != "" {
+		brandID, err := uuid.Parse(req.BrandID)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, apierror.CodeInvalidID, "Invalid brand ID")
+			return
+		}
+		if _, err := h.services.Brands.Get(c.Request.Context(), brandID, false); err != nil {
+			respondError(c, http.StatusBadRequest, apierror.CodeBrandNotFound, "Brand not found")
+			return
+		}
+		apiKey.BrandID = &brandID
+	}
+
+	if req.CustomerID != "" {
+		customerID, err := uuid.Parse(req.CustomerID)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, apierror.CodeInvalidID, "Invalid customer ID")
+			return
+		}
+		if _, err := h.services.Customers.Get(c.Request.Context(), customerID, false); err != nil {
+			respondError(c, http.StatusBadRequest, apierror.CodeCustomerNotFound, "Customer not found")
+			return
+		}
+		apiKey.CustomerID = &customerID
+	}
+
+	plaintext, err := h.services.APIKeys.Create(c.Request.Context(), &apiKey)
+	if errors.Is(err, auth.ErrInvalidRole) {
+		apierror.Respond(c, apierror.Field("role", "Role must be one of admin, brand_operator or customer"))
+		return
+	}
+	if errors.Is(err, auth.ErrInvalidScope) {
+		apierror.Respond(c, apierror.Field("role", "Brand operators need a brand_id, customers need a customer_id and admins need neither"))
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to create API key")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "API key created successfully. Store the key now, it will not be shown again",
+		"data":    apiKey,
+		"key":     plaintext,
+	})
+}
+
+// GetAPIKeys lists API keys with pagination, without their secrets
+func (h *Handler) GetAPIKeys(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	offset := (page - 1) * limit
+
+	apiKeys, total, err := h.services.APIKeys.List(c.Request.Context(), repository.ListOptions{Offset: offset, Limit: limit})
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch API keys")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": apiKeys,
+		"pagination": gin.H{
+			"page":  page,
+			"limit": limit,
+			"total": total,
+		},
+	})
+}
+
+// RevokeAPIKey revokes an API key so it can no longer authenticate
+func (h *Handler) RevokeAPIKey(c *gin.Context) {
+	keyID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierror.CodeInvalidID, "Invalid API key ID")
+		return
+	}
+
+	if err := h.services.APIKeys.Revoke(c.Request.Context(), keyID); err != nil {
+		respondServiceError(c, err, "Failed to revoke API key")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "API key revoked successfully"})
+}
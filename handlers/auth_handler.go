@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"my-backend-app/apierror"
+	"my-backend-app/auth"
+	"my-backend-app/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestLoginCodeRequest represents the request body for requesting a one-time login code
+type RequestLoginCodeRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// LoginRequest represents the request body for customer login. Exactly one of
+// Password and OTP must be given.
+type LoginRequest struct {
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password"`
+	OTP      string `json:"otp"`
+}
+
+// RefreshTokenRequest represents the request body for refreshing or revoking tokens
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// RequestLoginCode sends a one-time login code to an active customer. The
+// response is the same whether or not the email is known, so it cannot be
+// used to find out which emails are registered.
+func (h *Handler) RequestLoginCode(c *gin.Context) {
+	var req RequestLoginCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindingError(c, err)
+		return
+	}
+
+	if err := h.services.Auth.RequestOTP(c.Request.Context(), req.Email, time.Now()); err != nil {
+		if errors.Is(err, auth.ErrOTPDisabled) {
+			respondError(c, http.StatusServiceUnavailable, apierror.CodeLoginDisabled, "One-time code login is not configured")
+			return
+		}
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to send login code")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "If the email belongs to an active customer, a login code has been sent"})
+}
+
+// Login authenticates a customer with a password or one-time code and issues
+// an access token and a refresh token
+func (h *Handler) Login(c *gin.Context) {
+	var req LoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindingError(c, err)
+		return
+	}
+	if (req.Password == "") == (req.OTP == "") {
+		apierror.Respond(c, apierror.Field("password", "Provide either a password or a one-time code"))
+		return
+	}
+
+	customer, err := h.services.Customers.GetActiveByEmail(c.Request.Context(), req.Email)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, apierror.CodeInvalidCredentials, "Invalid email or credentials")
+		return
+	}
+
+	now := time.Now()
+	if req.Password != "" {
+		if !auth.CheckPassword(customer, req.Password) {
+			respondError(c, http.StatusUnauthorized, apierror.CodeInvalidCredentials, "Invalid email or credentials")
+			return
+		}
+	} else if err := h.services.Auth.VerifyOTP(c.Request.Context(), customer.ID, req.OTP, now); err != nil {
+		if errors.Is(err, auth.ErrInvalidCredentials) {
+			respondError(c, http.StatusUnauthorized, apierror.CodeInvalidCredentials, "Invalid email or credentials")
+			return
+		}
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to verify login code")
+		return
+	}
+
+	h.issueTokens(c, customer, now)
+}
+
+// RefreshToken exchanges a refresh token for a new token pair. Each refresh
+// token can only be used once.
+func (h *Handler) RefreshToken(c *gin.Context) {
+	var req RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindingError(c, err)
+		return
+	}
+
+	now := time.Now()
+	customerID, err := h.services.Auth.RotateRefreshToken(c.Request.Context(), req.RefreshToken, now)
+	if err != nil {
+		respondTokenError(c, err)
+		return
+	}
+
+	customer, err := h.services.Customers.GetActive(c.Request.Context(), customerID)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, apierror.CodeInvalidCredentials, "Customer is no longer active")
+		return
+	}
+
+	h.issueTokens(c, customer, now)
+}
+
+// Logout revokes a refresh token. Access tokens stay valid until they expire.
+func (h *Handler) Logout(c *gin.Context) {
+	var req RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindingError(c, err)
+		return
+	}
+
+	if err := h.services.Auth.RevokeRefreshToken(c.Request.Context(), req.RefreshToken, time.Now()); err != nil {
+		respondTokenError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
+}
+
+// issueTokens writes a new token pair for the customer as the response
+func (h *Handler) issueTokens(c *gin.Context, customer models.Customer, now time.Time) {
+	tokens, err := h.services.Auth.IssueTokens(c.Request.Context(), customer.ID, now)
+	if err != nil {
+		respondTokenError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Login successful",
+		"data":    tokens,
+	})
+}
+
+// respondTokenError maps token errors to responses
+func respondTokenError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, auth.ErrTokensDisabled):
+		respondError(c, http.StatusServiceUnavailable, apierror.CodeLoginDisabled, "Customer login is not configured")
+	case errors.Is(err, auth.ErrInvalidToken), errors.Is(err, auth.ErrExpiredToken):
+		respondError(c, http.StatusUnauthorized, apierror.CodeInvalidToken, "Invalid or expired refresh token")
+	default:
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to issue tokens")
+	}
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"net/http"
+
+	"my-backend-app/apierror"
+	"my-backend-app/auth"
+	"my-backend-app/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// authorize responds with 401 or 403 and returns false unless allowed accepts
+// the authenticated principal
+func authorize(c *gin.Context, allowed func(*auth.Principal) bool) bool {
+	principal := auth.FromContext(c)
+	if principal == nil {
+		respondError(c, http.StatusUnauthorized, apierror.CodeUnauthorized, "API key required")
+		return false
+	}
+	if !allowed(principal) {
+		respondError(c, http.StatusForbidden, apierror.CodeForbidden, "Insufficient permissions")
+		return false
+	}
+	return true
+}
+
+// authorizeBrand checks that the caller may manage the brand and its vouchers
+func authorizeBrand(c *gin.Context, brandID uuid.UUID) bool {
+	return authorize(c, func(p *auth.Principal) bool { return p.CanAccessBrand(brandID) })
+}
+
+// authorizeCustomer checks that the caller may act for the customer
+func authorizeCustomer(c *gin.Context, customerID uuid.UUID) bool {
+	return authorize(c, func(p *auth.Principal) bool { return p.CanAccessCustomer(customerID) })
+}
+
+// authorizeAdmin checks that the caller is an admin
+func authorizeAdmin(c *gin.Context) bool {
+	return authorize(c, (*auth.Principal).IsAdmin)
+}
+
+// requestedCustomer resolves the customer a request acts on. Customers act on
+// their own account, so for them requested may be empty but must not name
+// anyone else. Other callers must name the customer in the given field.
+func requestedCustomer(c *gin.Context, field, requested string) (uuid.UUID, bool) {
+	principal := auth.FromContext(c)
+	if principal == nil {
+		respondError(c, http.StatusUnauthorized, apierror.CodeUnauthorized, "API key required")
+		return uuid.Nil, false
+	}
+
+	if principal.Role == models.RoleCustomer && principal.CustomerID != nil {
+		if requested != "" {
+			customerID, err := uuid.Parse(requested)
+			if err != nil || customerID != *principal.CustomerID {
+				respondError(c, http.StatusForbidden, apierror.CodeForbidden, "Customers can only act on their own account")
+				return uuid.Nil, false
+			}
+		}
+		return *principal.CustomerID, true
+	}
+
+	if requested == "" {
+		apierror.Respond(c, apierror.Field(field, "Customer ID is required"))
+		return uuid.Nil, false
+	}
+	customerID, err := uuid.Parse(requested)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierror.CodeInvalidID, "Invalid customer ID")
+		return uuid.Nil, false
+	}
+	if !authorizeCustomer(c, customerID) {
+		return uuid.Nil, false
+	}
+	return customerID, true
+}
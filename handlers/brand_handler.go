@@ -4,8 +4,9 @@ import (
 	"net/http"
 	"strconv"
 
-	"my-backend-app/database"
+	"my-backend-app/apierror"
 	"my-backend-app/models"
+	"my-backend-app/repository"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -20,16 +21,10 @@ type CreateBrandRequest struct {
 }
 
 // CreateBrand creates a new brand
-func CreateBrand(c *gin.Context) {
+func (h *Handler) CreateBrand(c *gin.Context) {
 	var req CreateBrandRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-
-	// Validate name length
-	if len(req.Name) < 2 || len(req.Name) > 255 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Brand name must be between 2 and 255 characters"})
+		respondBindingError(c, err)
 		return
 	}
 
@@ -40,8 +35,8 @@ func CreateBrand(c *gin.Context) {
 		IsActive:    req.IsActive,
 	}
 
-	if err := database.GetDB().Create(&brand).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create brand"})
+	if err := h.services.Brands.Create(c.Request.Context(), &brand); err != nil {
+		respondServiceError(c, err, "Failed to create brand")
 		return
 	}
 
@@ -51,36 +46,46 @@ func CreateBrand(c *gin.Context) {
 	})
 }
 
+// PatchBrandRequest represents the request body for partially updating a brand.
+// Only the fields present in the body are changed.
+type PatchBrandRequest struct {
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+	LogoURL     *string `json:"logo_url"`
+	IsActive    *bool   `json:"is_active"`
+}
+
 // GetBrand gets a single brand by ID
-func GetBrand(c *gin.Context) {
+func (h *Handler) GetBrand(c *gin.Context) {
 	id := c.Param("id")
 	brandID, err := uuid.Parse(id)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid brand ID"})
+		respondError(c, http.StatusBadRequest, apierror.CodeInvalidID, "Invalid brand ID")
 		return
 	}
 
-	var brand models.Brand
-	if err := database.GetDB().First(&brand, "id = ?", brandID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Brand not found"})
+	brand, err := h.services.Brands.Get(c.Request.Context(), brandID, includeDeleted(c))
+	if err != nil {
+		respondServiceError(c, err, "Failed to fetch brand")
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"data": brand})
 }
 
-// GetBrands gets all brands with pagination
-func GetBrands(c *gin.Context) {
+// GetBrands gets all brands with pagination. Deleted brands are only listed with include_deleted=true.
+func (h *Handler) GetBrands(c *gin.Context) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
 	offset := (page - 1) * limit
 
-	var brands []models.Brand
-	var total int64
-
-	database.GetDB().Model(&models.Brand{}).Count(&total)
-	if err := database.GetDB().Offset(offset).Limit(limit).Find(&brands).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch brands"})
+	brands, total, err := h.services.Brands.List(c.Request.Context(), repository.ListOptions{
+		Offset:         offset,
+		Limit:          limit,
+		IncludeDeleted: includeDeleted(c),
+	})
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch brands")
 		return
 	}
 
@@ -93,3 +98,120 @@ func GetBrands(c *gin.Context) {
 		},
 	})
 }
+
+// UpdateBrand replaces every editable field of a brand
+func (h *Handler) UpdateBrand(c *gin.Context) {
+	brandID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierror.CodeInvalidID, "Invalid brand ID")
+		return
+	}
+
+	var req CreateBrandRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindingError(c, err)
+		return
+	}
+
+	brand, err := h.services.Brands.Get(c.Request.Context(), brandID, false)
+	if err != nil {
+		respondServiceError(c, err, "Failed to update brand")
+		return
+	}
+
+	brand.Name = req.Name
+	brand.Description = req.Description
+	brand.LogoURL = req.LogoURL
+	brand.IsActive = req.IsActive
+
+	h.saveBrand(c, &brand)
+}
+
+// PatchBrand updates only the brand fields present in the request body
+func (h *Handler) PatchBrand(c *gin.Context) {
+	brandID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierror.CodeInvalidID, "Invalid brand ID")
+		return
+	}
+
+	var req PatchBrandRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindingError(c, err)
+		return
+	}
+
+	brand, err := h.services.Brands.Get(c.Request.Context(), brandID, false)
+	if err != nil {
+		respondServiceError(c, err, "Failed to update brand")
+		return
+	}
+
+	if req.Name != nil {
+		brand.Name = *req.Name
+	}
+	if req.Description != nil {
+		brand.Description = *req.Description
+	}
+	if req.LogoURL != nil {
+		brand.LogoURL = *req.LogoURL
+	}
+	if req.IsActive != nil {
+		brand.IsActive = *req.IsActive
+	}
+
+	h.saveBrand(c, &brand)
+}
+
+// saveBrand validates and persists an updated brand and writes the response
+func (h *Handler) saveBrand(c *gin.Context, brand *models.Brand) {
+	if err := h.services.Brands.Update(c.Request.Context(), brand); err != nil {
+		respondServiceError(c, err, "Failed to update brand")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Brand updated successfully",
+		"data":    brand,
+	})
+}
+
+// DeleteBrand soft deletes a brand together with its vouchers
+func (h *Handler) DeleteBrand(c *gin.Context) {
+	brandID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierror.CodeInvalidID, "Invalid brand ID")
+		return
+	}
+
+	if err := h.services.Brands.Delete(c.Request.Context(), brandID); err != nil {
+		respondServiceError(c, err, "Failed to delete brand")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Brand deleted successfully"})
+}
+
+// RestoreBrand restores a soft-deleted brand and the vouchers deleted along with it
+func (h *Handler) RestoreBrand(c *gin.Context) {
+	brandID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierror.CodeInvalidID, "Invalid brand ID")
+		return
+	}
+
+	brand, err := h.services.Brands.Restore(c.Request.Context(), brandID)
+	if err != nil {
+		apiErr := serviceError(err, "Failed to restore brand")
+		if apiErr.Code == apierror.CodeBrandNotFound {
+			apiErr.Message = "Deleted brand not found"
+		}
+		apierror.Respond(c, apiErr)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Brand restored successfully",
+		"data":    brand,
+	})
+}
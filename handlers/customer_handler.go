@@ -3,40 +3,32 @@ package handlers
 import (
 	"net/http"
 	"strconv"
+	"time"
 
-	"my-backend-app/database"
+	"my-backend-app/apierror"
+	"my-backend-app/auth"
 	"my-backend-app/models"
+	"my-backend-app/repository"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
-// CreateCustomerRequest represents the request body for creating a customer
+// CreateCustomerRequest represents the request body for creating a customer.
+// The password is optional; customers without one log in with a one-time code.
 type CreateCustomerRequest struct {
-	Name   string `json:"name" binding:"required"`
-	Email  string `json:"email" binding:"required,email"`
-	Phone  string `json:"phone"`
-	Points int    `json:"points"`
+	Name     string `json:"name" binding:"required"`
+	Email    string `json:"email" binding:"required,email"`
+	Phone    string `json:"phone"`
+	Points   int    `json:"points"`
+	Password string `json:"password" binding:"omitempty,min=8,max=72"`
 }
 
 // CreateCustomer creates a new customer
-func CreateCustomer(c *gin.Context) {
+func (h *Handler) CreateCustomer(c *gin.Context) {
 	var req CreateCustomerRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-
-	// Validate name length
-	if len(req.Name) < 2 || len(req.Name) > 255 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Customer name must be between 2 and 255 characters"})
-		return
-	}
-
-	// Check if email already exists
-	var existingCustomer models.Customer
-	if err := database.GetDB().Where("email = ?", req.Email).First(&existingCustomer).Error; err == nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Email already exists"})
+		respondBindingError(c, err)
 		return
 	}
 
@@ -49,12 +41,21 @@ func CreateCustomer(c *gin.Context) {
 		Name:     req.Name,
 		Email:    req.Email,
 		Phone:    req.Phone,
-		Points:   req.Points,
 		IsActive: true,
 	}
 
-	if err := database.GetDB().Create(&customer).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create customer"})
+	if req.Password != "" {
+		hash, err := auth.HashPassword(req.Password)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to create customer")
+			return
+		}
+		customer.PasswordHash = hash
+	}
+
+	// Opening points go through the ledger like any other credit
+	if err := h.services.Customers.Create(c.Request.Context(), &customer, req.Points, actorFromContext(c)); err != nil {
+		respondServiceError(c, err, "Failed to create customer")
 		return
 	}
 
@@ -64,36 +65,77 @@ func CreateCustomer(c *gin.Context) {
 	})
 }
 
+// UpdateCustomerRequest represents the request body for replacing a customer's
+// details. Points are changed through UpdateCustomerPoints so they stay on the ledger.
+// IsActive is required so a replacement never silently keeps the old status.
+type UpdateCustomerRequest struct {
+	Name     string `json:"name" binding:"required"`
+	Email    string `json:"email" binding:"required,email"`
+	Phone    string `json:"phone"`
+	IsActive *bool  `json:"is_active"`
+}
+
+// PatchCustomerRequest represents the request body for partially updating a customer.
+// Only the fields present in the body are changed.
+type PatchCustomerRequest struct {
+	Name     *string `json:"name"`
+	Email    *string `json:"email" binding:"omitempty,email"`
+	Phone    *string `json:"phone"`
+	IsActive *bool   `json:"is_active"`
+}
+
 // GetCustomer gets a single customer by ID
-func GetCustomer(c *gin.Context) {
+func (h *Handler) GetCustomer(c *gin.Context) {
 	id := c.Param("id")
 	customerID, err := uuid.Parse(id)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid customer ID"})
+		respondError(c, http.StatusBadRequest, apierror.CodeInvalidID, "Invalid customer ID")
+		return
+	}
+
+	if !authorizeCustomer(c, customerID) {
+		return
+	}
+
+	customer, err := h.services.Customers.Get(c.Request.Context(), customerID, includeDeleted(c))
+	if err != nil {
+		respondServiceError(c, err, "Failed to fetch customer")
 		return
 	}
 
-	var customer models.Customer
-	if err := database.GetDB().First(&customer, "id = ?", customerID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Customer not found"})
+	expiring, err := h.services.Customers.ExpiringSoon(c.Request.Context(), customerID, time.Now())
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch expiring points")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"data": customer})
+	expiringPoints := 0
+	for _, lot := range expiring {
+		expiringPoints += lot.RemainingPoints
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": customer,
+		"points_expiring_soon": gin.H{
+			"points": expiringPoints,
+			"lots":   expiring,
+		},
+	})
 }
 
-// GetCustomers gets all customers with pagination
-func GetCustomers(c *gin.Context) {
+// GetCustomers gets all customers with pagination. Deleted customers are only listed with include_deleted=true.
+func (h *Handler) GetCustomers(c *gin.Context) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
 	offset := (page - 1) * limit
 
-	var customers []models.Customer
-	var total int64
-
-	database.GetDB().Model(&models.Customer{}).Count(&total)
-	if err := database.GetDB().Offset(offset).Limit(limit).Find(&customers).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch customers"})
+	customers, total, err := h.services.Customers.List(c.Request.Context(), repository.ListOptions{
+		Offset:         offset,
+		Limit:          limit,
+		IncludeDeleted: includeDeleted(c),
+	})
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch customers")
 		return
 	}
 
@@ -107,36 +149,223 @@ func GetCustomers(c *gin.Context) {
 	})
 }
 
-// UpdateCustomerPoints updates customer points
-func UpdateCustomerPoints(c *gin.Context) {
-	id := c.Param("id")
-	customerID, err := uuid.Parse(id)
+// UpdateCustomer replaces a customer's name, email, phone and status
+func (h *Handler) UpdateCustomer(c *gin.Context) {
+	customerID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid customer ID"})
+		respondError(c, http.StatusBadRequest, apierror.CodeInvalidID, "Invalid customer ID")
 		return
 	}
 
-	var req struct {
-		Points int `json:"points" binding:"required"`
+	if !authorizeCustomer(c, customerID) {
+		return
 	}
+
+	var req UpdateCustomerRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindingError(c, err)
 		return
 	}
 
-	if req.Points < 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Points cannot be negative"})
+	// A replacement must say whether the customer stays active
+	if req.IsActive == nil {
+		apierror.Respond(c, apierror.Field("is_active", "is_active is required; use PATCH to change only some fields"))
+		return
+	}
+
+	customer, err := h.services.Customers.Get(c.Request.Context(), customerID, false)
+	if err != nil {
+		respondServiceError(c, err, "Failed to update customer")
+		return
+	}
+
+	// Customers may replace their profile but only admins change its status
+	if *req.IsActive != customer.IsActive && !authorizeAdmin(c) {
+		return
+	}
+
+	customer.Name = req.Name
+	customer.Email = req.Email
+	customer.Phone = req.Phone
+	customer.IsActive = *req.IsActive
+
+	h.saveCustomer(c, &customer)
+}
+
+// PatchCustomer updates only the customer fields present in the request body
+func (h *Handler) PatchCustomer(c *gin.Context) {
+	customerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierror.CodeInvalidID, "Invalid customer ID")
 		return
 	}
 
-	var customer models.Customer
-	if err := database.GetDB().First(&customer, "id = ?", customerID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Customer not found"})
+	if !authorizeCustomer(c, customerID) {
 		return
 	}
 
-	if err := database.GetDB().Model(&customer).Update("points", req.Points).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update customer points"})
+	var req PatchCustomerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindingError(c, err)
+		return
+	}
+
+	customer, err := h.services.Customers.Get(c.Request.Context(), customerID, false)
+	if err != nil {
+		respondServiceError(c, err, "Failed to update customer")
+		return
+	}
+
+	if req.Name != nil {
+		customer.Name = *req.Name
+	}
+	if req.Email != nil {
+		customer.Email = *req.Email
+	}
+	if req.Phone != nil {
+		customer.Phone = *req.Phone
+	}
+	if req.IsActive != nil {
+		// Customers may edit their profile but only admins change its status
+		if !authorizeAdmin(c) {
+			return
+		}
+		customer.IsActive = *req.IsActive
+	}
+
+	h.saveCustomer(c, &customer)
+}
+
+// saveCustomer validates and persists an updated customer and writes the response.
+// Points are left untouched so a concurrent redemption is never overwritten.
+func (h *Handler) saveCustomer(c *gin.Context, customer *models.Customer) {
+	if err := h.services.Customers.Update(c.Request.Context(), customer); err != nil {
+		respondServiceError(c, err, "Failed to update customer")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Customer updated successfully",
+		"data":    customer,
+	})
+}
+
+// SetCustomerPasswordRequest represents the request body for setting a
+// customer's password. Customers changing an existing password must confirm
+// the current one; admins may reset it without.
+type SetCustomerPasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	Password        string `json:"password" binding:"required,min=8,max=72"`
+}
+
+// SetCustomerPassword sets or changes the password a customer logs in with
+func (h *Handler) SetCustomerPassword(c *gin.Context) {
+	customerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierror.CodeInvalidID, "Invalid customer ID")
+		return
+	}
+
+	if !authorizeCustomer(c, customerID) {
+		return
+	}
+
+	var req SetCustomerPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindingError(c, err)
+		return
+	}
+
+	customer, err := h.services.Customers.Get(c.Request.Context(), customerID, false)
+	if err != nil {
+		respondServiceError(c, err, "Failed to set password")
+		return
+	}
+
+	if principal := auth.FromContext(c); !principal.IsAdmin() && customer.PasswordHash != "" {
+		if !auth.CheckPassword(customer, req.CurrentPassword) {
+			respondError(c, http.StatusUnauthorized, apierror.CodeInvalidCredentials, "Current password is incorrect")
+			return
+		}
+	}
+
+	hash, err := auth.HashPassword(req.Password)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to set password")
+		return
+	}
+	if err := h.services.Customers.SetPasswordHash(c.Request.Context(), customer.ID, hash); err != nil {
+		respondServiceError(c, err, "Failed to set password")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password updated successfully"})
+}
+
+// DeleteCustomer soft deletes a customer, keeping their transactions and ledger
+func (h *Handler) DeleteCustomer(c *gin.Context) {
+	customerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierror.CodeInvalidID, "Invalid customer ID")
+		return
+	}
+
+	if err := h.services.Customers.Delete(c.Request.Context(), customerID); err != nil {
+		respondServiceError(c, err, "Failed to delete customer")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Customer deleted successfully"})
+}
+
+// RestoreCustomer restores a soft-deleted customer
+func (h *Handler) RestoreCustomer(c *gin.Context) {
+	customerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierror.CodeInvalidID, "Invalid customer ID")
+		return
+	}
+
+	customer, err := h.services.Customers.Restore(c.Request.Context(), customerID)
+	if err != nil {
+		apiErr := serviceError(err, "Failed to restore customer")
+		if apiErr.Code == apierror.CodeCustomerNotFound {
+			apiErr.Message = "Deleted customer not found"
+		}
+		apierror.Respond(c, apiErr)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Customer restored successfully",
+		"data":    customer,
+	})
+}
+
+// UpdateCustomerPointsRequest represents the request body for setting a customer's points
+type UpdateCustomerPointsRequest struct {
+	Points int    `json:"points" binding:"required"`
+	Reason string `json:"reason"`
+}
+
+// UpdateCustomerPoints sets customer points by posting an adjustment for the difference
+func (h *Handler) UpdateCustomerPoints(c *gin.Context) {
+	id := c.Param("id")
+	customerID, err := uuid.Parse(id)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierror.CodeInvalidID, "Invalid customer ID")
+		return
+	}
+
+	var req UpdateCustomerPointsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindingError(c, err)
+		return
+	}
+
+	customer, err := h.services.Customers.SetPoints(c.Request.Context(), customerID, req.Points, req.Reason, actorFromContext(c))
+	if err != nil {
+		respondServiceError(c, err, "Failed to update customer points")
 		return
 	}
 
@@ -145,3 +374,46 @@ func UpdateCustomerPoints(c *gin.Context) {
 		"data":    customer,
 	})
 }
+
+// GetCustomerLedger gets a customer's point ledger entries, newest first, with pagination
+func (h *Handler) GetCustomerLedger(c *gin.Context) {
+	id := c.Param("id")
+	customerID, err := uuid.Parse(id)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierror.CodeInvalidID, "Invalid customer ID")
+		return
+	}
+
+	if !authorizeCustomer(c, customerID) {
+		return
+	}
+
+	customer, err := h.services.Customers.Get(c.Request.Context(), customerID, false)
+	if err != nil {
+		respondServiceError(c, err, "Failed to fetch ledger entries")
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	offset := (page - 1) * limit
+
+	entries, total, err := h.services.Customers.Ledger(c.Request.Context(), customerID, repository.ListOptions{
+		Offset: offset,
+		Limit:  limit,
+	})
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch ledger entries")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    entries,
+		"balance": customer.Points,
+		"pagination": gin.H{
+			"page":  page,
+			"limit": limit,
+			"total": total,
+		},
+	})
+}
@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"my-backend-app/apierror"
+	"my-backend-app/eligibility"
+	"my-backend-app/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// respondError writes an error response without details
+func respondError(c *gin.Context, status int, code, message string) {
+	apierror.Respond(c, apierror.New(status, code, message))
+}
+
+// serviceErrors maps the errors returned by the service layer to responses
+var serviceErrors = []struct {
+	err     error
+	status  int
+	code    string
+	message string
+}{
+	{service.ErrBrandNotFound, http.StatusNotFound, apierror.CodeBrandNotFound, "Brand not found"},
+	{service.ErrVoucherNotFound, http.StatusNotFound, apierror.CodeVoucherNotFound, "Voucher not found"},
+	{service.ErrCustomerNotFound, http.StatusNotFound, apierror.CodeCustomerNotFound, "Customer not found"},
+	{service.ErrTransactionNotFound, http.StatusNotFound, apierror.CodeTransactionNotFound, "Transaction not found"},
+	{service.ErrTransactionItemNotFound, http.StatusNotFound, apierror.CodeTransactionItemNotFound, "Transaction item not found"},
+	{service.ErrVoucherCodeNotFound, http.StatusNotFound, apierror.CodeVoucherCodeNotFound, "Voucher code not found"},
+	{service.ErrAPIKeyNotFound, http.StatusNotFound, apierror.CodeAPIKeyNotFound, "API key not found"},
+	{service.ErrInsufficientPoints, http.StatusBadRequest, apierror.CodeInsufficientPoints, "Insufficient points"},
+	{service.ErrOutOfStock, http.StatusBadRequest, apierror.CodeOutOfStock, "Voucher is out of stock"},
+	{service.ErrVoucherCodesUsed, http.StatusConflict, apierror.CodeVoucherCodesUsed, "Vouchers that have already been used cannot be cancelled"},
+	{service.ErrVoucherCodeUsed, http.StatusConflict, apierror.CodeVoucherCodeUsed, "Voucher code has already been used"},
+	{service.ErrVoucherCodeVoid, http.StatusConflict, apierror.CodeVoucherCodeVoid, "Voucher code has been voided"},
+	{service.ErrVoucherBrandChanged, http.StatusConflict, apierror.CodeVoucherBrandChanged, "Voucher moved to another brand, retry the update"},
+	{service.ErrVoucherCodeExpired, http.StatusBadRequest, apierror.CodeVoucherCodeExpired, "Voucher code has expired"},
+	{service.ErrVoucherNotYetValid, http.StatusBadRequest, eligibility.CodeVoucherNotYetValid, "Voucher is not yet valid"},
+}
+
+// serviceError converts an error returned by the service layer into a
+// response. Errors the service layer does not know about become a 500 with
+// message.
+func serviceError(err error, message string) *apierror.Error {
+	var apiErr *apierror.Error
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+
+	var fieldErr *service.FieldError
+	if errors.As(err, &fieldErr) {
+		return apierror.Field(fieldErr.Field, fieldErr.Message)
+	}
+
+	var limitErr *service.LimitError
+	if errors.As(err, &limitErr) {
+		if limitErr.Period == "" {
+			return apierror.New(http.StatusBadRequest, apierror.CodeCustomerLimitExceeded, "Voucher redemption limit per customer reached")
+		}
+		return apierror.New(http.StatusBadRequest, apierror.CodePeriodLimitExceeded, "Voucher redemption limit per "+limitErr.Period+" reached")
+	}
+
+	var notEligible *service.NotEligibleError
+	if errors.As(err, &notEligible) {
+		return &apierror.Error{
+			Status:  http.StatusBadRequest,
+			Code:    apierror.CodeNotEligible,
+			Message: "Redemption is not eligible",
+			Details: notEligible.Result,
+		}
+	}
+
+	var transitionErr *service.TransitionError
+	if errors.As(err, &transitionErr) {
+		return apierror.New(http.StatusConflict, apierror.CodeInvalidStateTransition, "Transaction cannot be cancelled in status "+transitionErr.Status)
+	}
+
+	if errors.Is(err, service.ErrEmailAlreadyExists) {
+		return &apierror.Error{
+			Status:  http.StatusBadRequest,
+			Code:    apierror.CodeEmailAlreadyExists,
+			Message: "Email already exists",
+			Details: []apierror.FieldError{{Field: "email", Message: "Email already exists"}},
+		}
+	}
+
+	for _, known := range serviceErrors {
+		if errors.Is(err, known.err) {
+			return apierror.New(known.status, known.code, known.message)
+		}
+	}
+	return apierror.New(http.StatusInternalServerError, apierror.CodeInternal, message)
+}
+
+// respondServiceError writes the response for an error returned by the
+// service layer, with message for unexpected errors
+func respondServiceError(c *gin.Context, err error, message string) {
+	apierror.Respond(c, serviceError(err, message))
+}
+
+// respondBindingError writes the error returned by ShouldBind*, listing every
+// field that failed validation
+func respondBindingError(c *gin.Context, err error) {
+	apierror.Respond(c, bindingError(err))
+}
+
+func bindingError(err error) *apierror.Error {
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		details := make([]apierror.FieldError, 0, len(validationErrs))
+		for _, fe := range validationErrs {
+			details = append(details, apierror.FieldError{Field: fieldPath(fe), Message: fieldMessage(fe)})
+		}
+		return &apierror.Error{
+			Status:  http.StatusBadRequest,
+			Code:    apierror.CodeValidationFailed,
+			Message: "Request validation failed",
+			Details: details,
+		}
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return apierror.Field(typeErr.Field, "Field "+typeErr.Field+" must be of type "+typeErr.Type.String())
+	}
+
+	return apierror.New(http.StatusBadRequest, apierror.CodeInvalidRequestBody, "Invalid request body")
+}
+
+// fieldPath returns the JSON path of the field, without the request type name
+func fieldPath(fe validator.FieldError) string {
+	namespace := fe.Namespace()
+	if i := strings.Index(namespace, "."); i >= 0 {
+		return namespace[i+1:]
+	}
+	return namespace
+}
+
+// fieldMessage describes a failed validation tag in plain words
+func fieldMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "url":
+		return "must be a valid URL"
+	case "min":
+		return "must be at least " + fe.Param()
+	case "max":
+		return "must be at most " + fe.Param()
+	}
+	return "failed the " + fe.Tag() + " check"
+}
+
+// Report validation errors with the JSON field names clients send
+func init() {
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(field reflect.StructField) string {
+			name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+			if name == "-" {
+				return ""
+			}
+			if name == "" {
+				return field.Name
+			}
+			return name
+		})
+	}
+}
@@ -0,0 +1,15 @@
+package handlers
+
+import (
+	"my-backend-app/service"
+)
+
+// Handler serves the HTTP API on top of the service layer
+type Handler struct {
+	services *service.Services
+}
+
+// New creates a Handler using services for business rules
+func New(services *service.Services) *Handler {
+	return &Handler{services: services}
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"my-backend-app/apierror"
+	"my-backend-app/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// GetCustomerVouchers gets the voucher codes issued to a customer with pagination.
+// An optional status query parameter filters by issued, used, expired or void.
+func (h *Handler) GetCustomerVouchers(c *gin.Context) {
+	id := c.Param("id")
+	customerID, err := uuid.Parse(id)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierror.CodeInvalidID, "Invalid customer ID")
+		return
+	}
+
+	if !authorizeCustomer(c, customerID) {
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	offset := (page - 1) * limit
+
+	issued, total, err := h.services.VoucherCodes.ListByCustomer(c.Request.Context(), customerID, c.Query("status"), repository.ListOptions{
+		Offset: offset,
+		Limit:  limit,
+	})
+	if err != nil {
+		respondServiceError(c, err, "Failed to fetch vouchers")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": issued,
+		"pagination": gin.H{
+			"page":  page,
+			"limit": limit,
+			"total": total,
+		},
+	})
+}
+
+// BurnVoucherCodeRequest represents the request body for marking a voucher code as used
+type BurnVoucherCodeRequest struct {
+	StoreID    string `json:"store_id" binding:"required,max=100"`
+	TerminalID string `json:"terminal_id" binding:"max=100"`
+}
+
+// GetBrandVoucherCode looks up a voucher code for a brand and reports whether it can be burned
+func (h *Handler) GetBrandVoucherCode(c *gin.Context) {
+	brandID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierror.CodeInvalidID, "Invalid brand ID")
+		return
+	}
+
+	if !authorizeBrand(c, brandID) {
+		return
+	}
+
+	issued, err := h.services.VoucherCodes.Find(c.Request.Context(), brandID, c.Param("code"))
+	if err != nil {
+		respondServiceError(c, err, "Failed to fetch voucher code")
+		return
+	}
+
+	response := gin.H{"data": issued, "valid": true, "reason": ""}
+	if err := h.services.VoucherCodes.Problem(issued, time.Now()); err != nil {
+		problem := serviceError(err, "Failed to check voucher code")
+		response["valid"] = false
+		response["reason"] = problem.Message
+		response["reason_code"] = problem.Code
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// BurnVoucherCode validates a voucher code for a brand and marks it as used.
+// The service guards the update on the status, so concurrent burns are safe.
+func (h *Handler) BurnVoucherCode(c *gin.Context) {
+	brandID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierror.CodeInvalidID, "Invalid brand ID")
+		return
+	}
+
+	if !authorizeBrand(c, brandID) {
+		return
+	}
+
+	var req BurnVoucherCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindingError(c, err)
+		return
+	}
+
+	issued, err := h.services.VoucherCodes.Burn(c.Request.Context(), brandID, c.Param("code"), req.StoreID, req.TerminalID)
+	if err != nil {
+		respondServiceError(c, err, "Failed to burn voucher code")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Voucher code burned successfully",
+		"data":    issued,
+	})
+}
@@ -0,0 +1,17 @@
+package handlers
+
+import (
+	"my-backend-app/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+// includeDeleted reports whether the request asks for soft-deleted records
+// with include_deleted=true. Only admins may see deleted records; the flag is
+// ignored for everyone else.
+func includeDeleted(c *gin.Context) bool {
+	if principal := auth.FromContext(c); principal == nil || !principal.IsAdmin() {
+		return false
+	}
+	return c.Query("include_deleted") == "true"
+}
@@ -2,10 +2,11 @@ package handlers
 
 import (
 	"net/http"
-	"time"
 
-	"my-backend-app/database"
-	"my-backend-app/models"
+	"my-backend-app/apierror"
+	"my-backend-app/auth"
+	"my-backend-app/metrics"
+	"my-backend-app/service"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -17,175 +18,168 @@ type RedemptionItem struct {
 	Quantity  int    `json:"quantity" binding:"required,min=1"`
 }
 
-// RedemptionRequest represents the request body for redemption
+// RedemptionRequest represents the request body for redemption. Customers
+// authenticated with a token or customer API key may omit CustomerID.
 type RedemptionRequest struct {
-	CustomerID string           `json:"customer_id" binding:"required"`
-	Items      []RedemptionItem `json:"items" binding:"required,min=1"`
+	CustomerID string           `json:"customer_id"`
+	Items      []RedemptionItem `json:"items" binding:"required,min=1,dive"`
 }
 
 // CreateRedemption creates a new redemption transaction
-func CreateRedemption(c *gin.Context) {
+func (h *Handler) CreateRedemption(c *gin.Context) {
 	var req RedemptionRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apiErr := bindingError(err)
+		metrics.ObserveRedemptionFailure(apiErr.Code)
+		apierror.Respond(c, apiErr)
 		return
 	}
 
-	// Parse customer ID
-	customerID, err := uuid.Parse(req.CustomerID)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid customer ID"})
+	customerID, ok := requestedCustomer(c, "customer_id", req.CustomerID)
+	if !ok {
 		return
 	}
 
-	// Check if customer exists
-	var customer models.Customer
-	if err := database.GetDB().First(&customer, "id = ?", customerID).Error; err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Customer not found"})
-		return
+	items := make([]service.RedemptionItem, len(req.Items))
+	for i, item := range req.Items {
+		items[i] = service.RedemptionItem{VoucherID: item.VoucherID, Quantity: item.Quantity}
 	}
 
-	// Calculate total points and validate vouchers
-	totalPoints := 0
-	var transactionItems []models.TransactionItem
-
-	for _, item := range req.Items {
-		// Parse voucher ID
-		voucherID, err := uuid.Parse(item.VoucherID)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid voucher ID"})
-			return
-		}
-
-		// Get voucher details
-		var voucher models.Voucher
-		if err := database.GetDB().First(&voucher, "id = ? AND is_active = ?", voucherID, true).Error; err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Voucher not found or inactive"})
-			return
-		}
-
-		// Validate voucher validity period
-		now := time.Now()
-		if !voucher.ValidFrom.IsZero() && now.Before(voucher.ValidFrom) {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Voucher is not yet valid"})
-			return
-		}
-		if !voucher.ValidTo.IsZero() && now.After(voucher.ValidTo) {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Voucher has expired"})
-			return
+	created, err := h.services.Transactions.Redeem(c.Request.Context(), customerID, items, actorFromContext(c))
+	if err != nil {
+		apiErr := serviceError(err, "Failed to create redemption")
+		if apiErr.Code == apierror.CodeCustomerNotFound {
+			apiErr.Status = http.StatusBadRequest
 		}
+		metrics.ObserveRedemptionFailure(apiErr.Code)
+		apierror.Respond(c, apiErr)
+		return
+	}
+	metrics.ObserveRedemption(created)
 
-		// Calculate points for this item
-		itemTotalPoints := voucher.CostInPoint * item.Quantity
-		totalPoints += itemTotalPoints
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Redemption successful",
+		"data":    created,
+	})
+}
 
-		// Create transaction item
-		transactionItem := models.TransactionItem{
-			VoucherID:     voucherID,
-			Quantity:      item.Quantity,
-			PointsPerUnit: voucher.CostInPoint,
-			TotalPoints:   itemTotalPoints,
-		}
-		transactionItems = append(transactionItems, transactionItem)
+// GetTransactionDetail gets transaction details by transaction ID
+func (h *Handler) GetTransactionDetail(c *gin.Context) {
+	transactionID := c.Query("transactionId")
+	if transactionID == "" {
+		apierror.Respond(c, apierror.Field("transactionId", "Transaction ID is required"))
+		return
 	}
 
-	// Check if customer has enough points
-	if customer.Points < totalPoints {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Insufficient points"})
+	parsedTransactionID, err := uuid.Parse(transactionID)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierror.CodeInvalidID, "Invalid transaction ID")
 		return
 	}
 
-	// Start transaction
-	tx := database.GetDB().Begin()
-
-	// Create transaction record
-	transaction := models.Transaction{
-		CustomerID:  customerID,
-		TotalPoints: totalPoints,
-		Status:      "completed",
+	transaction, err := h.services.Transactions.Get(c.Request.Context(), parsedTransactionID)
+	if err != nil {
+		respondServiceError(c, err, "Failed to fetch transaction")
+		return
 	}
 
-	if err := tx.Create(&transaction).Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create transaction"})
+	// Transactions of other customers are reported as missing, so callers
+	// cannot probe which transaction IDs exist
+	if principal := auth.FromContext(c); principal != nil && !principal.CanAccessCustomer(transaction.CustomerID) {
+		respondServiceError(c, service.ErrTransactionNotFound, "Failed to fetch transaction")
 		return
 	}
-
-	// Create transaction items
-	for i := range transactionItems {
-		transactionItems[i].TransactionID = transaction.ID
-		if err := tx.Create(&transactionItems[i]).Error; err != nil {
-			tx.Rollback()
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create transaction items"})
-			return
-		}
+	if !authorizeCustomer(c, transaction.CustomerID) {
+		return
 	}
 
-	// Deduct points from customer
-	if err := tx.Model(&customer).Update("points", customer.Points-totalPoints).Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update customer points"})
+	c.JSON(http.StatusOK, gin.H{"data": transaction})
+}
+
+// GetCustomerTransactions gets all transactions for a customer
+func (h *Handler) GetCustomerTransactions(c *gin.Context) {
+	parsedCustomerID, ok := requestedCustomer(c, "customerId", c.Query("customerId"))
+	if !ok {
 		return
 	}
 
-	// Commit transaction
-	if err := tx.Commit().Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+	transactions, err := h.services.Transactions.ListByCustomer(c.Request.Context(), parsedCustomerID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch transactions")
 		return
 	}
 
-	// Load transaction with items and customer details
-	var result models.Transaction
-	database.GetDB().Preload("Items.Voucher.Brand").Preload("Customer").First(&result, transaction.ID)
+	c.JSON(http.StatusOK, gin.H{"data": transactions})
+}
 
-	c.JSON(http.StatusCreated, gin.H{
-		"message": "Redemption successful",
-		"data":    result,
-	})
+// CancelRedemptionRequest represents the request body for cancelling a whole redemption
+type CancelRedemptionRequest struct {
+	Reason string `json:"reason" binding:"required"`
 }
 
-// GetTransactionDetail gets transaction details by transaction ID
-func GetTransactionDetail(c *gin.Context) {
-	transactionID := c.Query("transactionId")
-	if transactionID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Transaction ID is required"})
+// CancelRedemptionItemRequest represents the request body for cancelling part of a redemption.
+// When Quantity is omitted every remaining unit of the item is cancelled.
+type CancelRedemptionItemRequest struct {
+	Quantity int    `json:"quantity" binding:"omitempty,min=1"`
+	Reason   string `json:"reason" binding:"required"`
+}
+
+// CancelRedemption cancels every remaining item of a redemption and refunds its points
+func (h *Handler) CancelRedemption(c *gin.Context) {
+	transactionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierror.CodeInvalidID, "Invalid transaction ID")
 		return
 	}
 
-	parsedTransactionID, err := uuid.Parse(transactionID)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid transaction ID"})
+	var req CancelRedemptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindingError(c, err)
 		return
 	}
 
-	var transaction models.Transaction
-	if err := database.GetDB().Preload("Items.Voucher.Brand").Preload("Customer").First(&transaction, "id = ?", parsedTransactionID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Transaction not found"})
+	cancelled, err := h.services.Transactions.Cancel(c.Request.Context(), transactionID, req.Reason, actorFromContext(c))
+	if err != nil {
+		respondServiceError(c, err, "Failed to refund transaction items")
 		return
 	}
+	metrics.ObserveRefund(cancelled)
 
-	c.JSON(http.StatusOK, gin.H{"data": transaction})
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Redemption cancelled successfully",
+		"data":    cancelled,
+	})
 }
 
-// GetCustomerTransactions gets all transactions for a customer
-func GetCustomerTransactions(c *gin.Context) {
-	customerID := c.Query("customerId")
-	if customerID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Customer ID is required"})
+// CancelRedemptionItem cancels some or all remaining units of a single redemption item
+func (h *Handler) CancelRedemptionItem(c *gin.Context) {
+	transactionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierror.CodeInvalidID, "Invalid transaction ID")
 		return
 	}
 
-	parsedCustomerID, err := uuid.Parse(customerID)
+	itemID, err := uuid.Parse(c.Param("itemId"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid customer ID"})
+		respondError(c, http.StatusBadRequest, apierror.CodeInvalidID, "Invalid transaction item ID")
 		return
 	}
 
-	var transactions []models.Transaction
-	if err := database.GetDB().Preload("Items.Voucher.Brand").Where("customer_id = ?", parsedCustomerID).Find(&transactions).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch transactions"})
+	var req CancelRedemptionItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindingError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"data": transactions})
+	cancelled, err := h.services.Transactions.CancelItem(c.Request.Context(), transactionID, itemID, req.Quantity, req.Reason, actorFromContext(c))
+	if err != nil {
+		respondServiceError(c, err, "Failed to refund transaction item")
+		return
+	}
+	metrics.ObserveRefund(cancelled)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Redemption item cancelled successfully",
+		"data":    cancelled,
+	})
 }
@@ -5,69 +5,68 @@ import (
 	"strconv"
 	"time"
 
-	"my-backend-app/database"
+	"my-backend-app/apierror"
 	"my-backend-app/models"
+	"my-backend-app/repository"
+	"my-backend-app/service"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
-// CreateVoucherRequest represents the request body for creating a voucher
+// CreateVoucherRequest represents the request body for creating or replacing
+// a voucher. Omit TotalStock to offer unlimited units. MaxPerPeriod requires a
+// LimitPeriod of day, week or month. New vouchers are active unless IsActive
+// is false; replacing a voucher requires IsActive.
 type CreateVoucherRequest struct {
-	BrandID     string    `json:"brand_id" binding:"required"`
-	Name        string    `json:"name" binding:"required"`
-	Description string    `json:"description"`
-	CostInPoint int       `json:"cost_in_point" binding:"required,min=1"`
-	ValidFrom   time.Time `json:"valid_from"`
-	ValidTo     time.Time `json:"valid_to"`
+	BrandID        string    `json:"brand_id" binding:"required"`
+	Name           string    `json:"name" binding:"required"`
+	Description    string    `json:"description"`
+	CostInPoint    int       `json:"cost_in_point" binding:"required,min=1"`
+	ValidFrom      time.Time `json:"valid_from"`
+	ValidTo        time.Time `json:"valid_to"`
+	TotalStock     *int      `json:"total_stock" binding:"omitempty,min=0"`
+	MaxPerCustomer *int      `json:"max_per_customer" binding:"omitempty,min=1"`
+	MaxPerPeriod   *int      `json:"max_per_period" binding:"omitempty,min=1"`
+	LimitPeriod    string    `json:"limit_period"`
+	IsActive       *bool     `json:"is_active"`
 }
 
 // CreateVoucher creates a new voucher
-func CreateVoucher(c *gin.Context) {
+func (h *Handler) CreateVoucher(c *gin.Context) {
 	var req CreateVoucherRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindingError(c, err)
 		return
 	}
 
 	// Parse brand ID
 	brandID, err := uuid.Parse(req.BrandID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid brand ID"})
+		respondError(c, http.StatusBadRequest, apierror.CodeInvalidID, "Invalid brand ID")
 		return
 	}
 
-	// Check if brand exists
-	var brand models.Brand
-	if err := database.GetDB().First(&brand, "id = ?", brandID).Error; err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Brand not found"})
-		return
-	}
-
-	// Validate cost in point
-	if req.CostInPoint <= 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Cost in point must be greater than 0"})
-		return
-	}
-
-	// Validate date range
-	if !req.ValidFrom.IsZero() && !req.ValidTo.IsZero() && req.ValidFrom.After(req.ValidTo) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Valid from date must be before valid to date"})
+	if !authorizeBrand(c, brandID) {
 		return
 	}
 
 	voucher := models.Voucher{
-		BrandID:     brandID,
-		Name:        req.Name,
-		Description: req.Description,
-		CostInPoint: req.CostInPoint,
-		ValidFrom:   req.ValidFrom,
-		ValidTo:     req.ValidTo,
-		IsActive:    true,
+		BrandID:        brandID,
+		Name:           req.Name,
+		Description:    req.Description,
+		CostInPoint:    req.CostInPoint,
+		ValidFrom:      req.ValidFrom,
+		ValidTo:        req.ValidTo,
+		IsActive:       req.IsActive == nil || *req.IsActive,
+		TotalStock:     req.TotalStock,
+		MaxPerCustomer: req.MaxPerCustomer,
+		MaxPerPeriod:   req.MaxPerPeriod,
+		LimitPeriod:    req.LimitPeriod,
 	}
 
-	if err := database.GetDB().Create(&voucher).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create voucher"})
+	if err := h.services.Vouchers.Create(c.Request.Context(), &voucher); err != nil {
+		respondVoucherError(c, err, "Failed to create voucher")
 		return
 	}
 
@@ -77,40 +76,56 @@ func CreateVoucher(c *gin.Context) {
 	})
 }
 
+// PatchVoucherRequest represents the request body for partially updating a voucher.
+// Only the fields present in the body are changed; use PUT to clear a limit.
+type PatchVoucherRequest struct {
+	BrandID        *string    `json:"brand_id"`
+	Name           *string    `json:"name"`
+	Description    *string    `json:"description"`
+	CostInPoint    *int       `json:"cost_in_point"`
+	ValidFrom      *time.Time `json:"valid_from"`
+	ValidTo        *time.Time `json:"valid_to"`
+	IsActive       *bool      `json:"is_active"`
+	TotalStock     *int       `json:"total_stock" binding:"omitempty,min=0"`
+	MaxPerCustomer *int       `json:"max_per_customer" binding:"omitempty,min=1"`
+	MaxPerPeriod   *int       `json:"max_per_period" binding:"omitempty,min=1"`
+	LimitPeriod    *string    `json:"limit_period"`
+}
+
 // GetVoucher gets a single voucher by ID
-func GetVoucher(c *gin.Context) {
+func (h *Handler) GetVoucher(c *gin.Context) {
 	id := c.Query("id")
 	if id == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Voucher ID is required"})
+		apierror.Respond(c, apierror.Field("id", "Voucher ID is required"))
 		return
 	}
 
 	voucherID, err := uuid.Parse(id)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid voucher ID"})
+		respondError(c, http.StatusBadRequest, apierror.CodeInvalidID, "Invalid voucher ID")
 		return
 	}
 
-	var voucher models.Voucher
-	if err := database.GetDB().Preload("Brand").First(&voucher, "id = ?", voucherID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Voucher not found"})
+	voucher, err := h.services.Vouchers.Get(c.Request.Context(), voucherID, includeDeleted(c))
+	if err != nil {
+		respondServiceError(c, err, "Failed to fetch voucher")
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"data": voucher})
 }
 
-// GetVouchersByBrand gets all vouchers for a specific brand
-func GetVouchersByBrand(c *gin.Context) {
+// GetVouchersByBrand gets all vouchers for a specific brand. Deleted vouchers are only listed with include_deleted=true.
+func (h *Handler) GetVouchersByBrand(c *gin.Context) {
 	brandID := c.Query("id")
 	if brandID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Brand ID is required"})
+		apierror.Respond(c, apierror.Field("id", "Brand ID is required"))
 		return
 	}
 
 	parsedBrandID, err := uuid.Parse(brandID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid brand ID"})
+		respondError(c, http.StatusBadRequest, apierror.CodeInvalidID, "Invalid brand ID")
 		return
 	}
 
@@ -118,12 +133,13 @@ func GetVouchersByBrand(c *gin.Context) {
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
 	offset := (page - 1) * limit
 
-	var vouchers []models.Voucher
-	var total int64
-
-	database.GetDB().Model(&models.Voucher{}).Where("brand_id = ?", parsedBrandID).Count(&total)
-	if err := database.GetDB().Where("brand_id = ?", parsedBrandID).Offset(offset).Limit(limit).Find(&vouchers).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch vouchers"})
+	vouchers, total, err := h.services.Vouchers.ListByBrand(c.Request.Context(), parsedBrandID, repository.ListOptions{
+		Offset:         offset,
+		Limit:          limit,
+		IncludeDeleted: includeDeleted(c),
+	})
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch vouchers")
 		return
 	}
 
@@ -137,18 +153,19 @@ func GetVouchersByBrand(c *gin.Context) {
 	})
 }
 
-// GetVouchers gets all vouchers with pagination
-func GetVouchers(c *gin.Context) {
+// GetVouchers gets all vouchers with pagination. Deleted vouchers are only listed with include_deleted=true.
+func (h *Handler) GetVouchers(c *gin.Context) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
 	offset := (page - 1) * limit
 
-	var vouchers []models.Voucher
-	var total int64
-
-	database.GetDB().Model(&models.Voucher{}).Count(&total)
-	if err := database.GetDB().Preload("Brand").Offset(offset).Limit(limit).Find(&vouchers).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch vouchers"})
+	vouchers, total, err := h.services.Vouchers.List(c.Request.Context(), repository.ListOptions{
+		Offset:         offset,
+		Limit:          limit,
+		IncludeDeleted: includeDeleted(c),
+	})
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch vouchers")
 		return
 	}
 
@@ -161,3 +178,184 @@ func GetVouchers(c *gin.Context) {
 		},
 	})
 }
+
+// UpdateVoucher replaces every editable field of a voucher
+func (h *Handler) UpdateVoucher(c *gin.Context) {
+	voucherID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierror.CodeInvalidID, "Invalid voucher ID")
+		return
+	}
+
+	var req CreateVoucherRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindingError(c, err)
+		return
+	}
+
+	// A replacement must say whether the voucher stays active
+	if req.IsActive == nil {
+		apierror.Respond(c, apierror.Field("is_active", "is_active is required; use PATCH to change only some fields"))
+		return
+	}
+
+	// Parse brand ID
+	brandID, err := uuid.Parse(req.BrandID)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierror.CodeInvalidID, "Invalid brand ID")
+		return
+	}
+
+	h.saveVoucher(c, voucherID, service.VoucherChanges{
+		BrandID:        &brandID,
+		Name:           &req.Name,
+		Description:    &req.Description,
+		CostInPoint:    &req.CostInPoint,
+		ValidFrom:      &req.ValidFrom,
+		ValidTo:        &req.ValidTo,
+		IsActive:       req.IsActive,
+		TotalStock:     req.TotalStock,
+		MaxPerCustomer: req.MaxPerCustomer,
+		MaxPerPeriod:   req.MaxPerPeriod,
+		LimitPeriod:    &req.LimitPeriod,
+		Replace:        true,
+	})
+}
+
+// PatchVoucher updates only the voucher fields present in the request body
+func (h *Handler) PatchVoucher(c *gin.Context) {
+	voucherID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierror.CodeInvalidID, "Invalid voucher ID")
+		return
+	}
+
+	var req PatchVoucherRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindingError(c, err)
+		return
+	}
+
+	changes := service.VoucherChanges{
+		Name:           req.Name,
+		Description:    req.Description,
+		CostInPoint:    req.CostInPoint,
+		ValidFrom:      req.ValidFrom,
+		ValidTo:        req.ValidTo,
+		IsActive:       req.IsActive,
+		TotalStock:     req.TotalStock,
+		MaxPerCustomer: req.MaxPerCustomer,
+		MaxPerPeriod:   req.MaxPerPeriod,
+		LimitPeriod:    req.LimitPeriod,
+	}
+	if req.BrandID != nil {
+		brandID, err := uuid.Parse(*req.BrandID)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, apierror.CodeInvalidID, "Invalid brand ID")
+			return
+		}
+		changes.BrandID = &brandID
+	}
+
+	h.saveVoucher(c, voucherID, changes)
+}
+
+// saveVoucher checks that the caller manages the voucher's brand, and the
+// brand it moves to if any, then applies changes and writes the response
+func (h *Handler) saveVoucher(c *gin.Context, voucherID uuid.UUID, changes service.VoucherChanges) {
+	current, err := h.services.Vouchers.Get(c.Request.Context(), voucherID, false)
+	if err != nil {
+		respondServiceError(c, err, "Failed to update voucher")
+		return
+	}
+
+	// Operators may only move vouchers between brands they manage
+	if !authorizeBrand(c, current.BrandID) {
+		return
+	}
+	if changes.BrandID != nil && !authorizeBrand(c, *changes.BrandID) {
+		return
+	}
+	changes.FromBrandID = current.BrandID
+
+	voucher, err := h.services.Vouchers.Update(c.Request.Context(), voucherID, changes)
+	if err != nil {
+		respondVoucherError(c, err, "Failed to update voucher")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Voucher updated successfully",
+		"data":    voucher,
+	})
+}
+
+// respondVoucherError writes the response for a failed voucher create or
+// update. A missing brand is a problem with the request body, not the URL.
+func respondVoucherError(c *gin.Context, err error, message string) {
+	apiErr := serviceError(err, message)
+	if apiErr.Code == apierror.CodeBrandNotFound {
+		apiErr.Status = http.StatusBadRequest
+	}
+	apierror.Respond(c, apiErr)
+}
+
+// DeleteVoucher soft deletes a voucher
+func (h *Handler) DeleteVoucher(c *gin.Context) {
+	voucherID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierror.CodeInvalidID, "Invalid voucher ID")
+		return
+	}
+
+	voucher, err := h.services.Vouchers.Get(c.Request.Context(), voucherID, false)
+	if err != nil {
+		respondServiceError(c, err, "Failed to delete voucher")
+		return
+	}
+
+	if !authorizeBrand(c, voucher.BrandID) {
+		return
+	}
+
+	if err := h.services.Vouchers.Delete(c.Request.Context(), voucherID); err != nil {
+		respondServiceError(c, err, "Failed to delete voucher")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Voucher deleted successfully"})
+}
+
+// RestoreVoucher restores a soft-deleted voucher
+func (h *Handler) RestoreVoucher(c *gin.Context) {
+	voucherID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierror.CodeInvalidID, "Invalid voucher ID")
+		return
+	}
+
+	voucher, err := h.services.Vouchers.GetDeleted(c.Request.Context(), voucherID)
+	if err != nil {
+		apiErr := serviceError(err, "Failed to restore voucher")
+		if apiErr.Code == apierror.CodeVoucherNotFound {
+			apiErr.Message = "Deleted voucher not found"
+		}
+		apierror.Respond(c, apiErr)
+		return
+	}
+
+	if !authorizeBrand(c, voucher.BrandID) {
+		return
+	}
+
+	voucher, err = h.services.Vouchers.Restore(c.Request.Context(), voucherID)
+	if err != nil {
+		respondServiceError(c, err, "Failed to restore voucher")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Voucher restored successfully",
+		"data":    voucher,
+	})
+}
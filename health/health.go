@@ -0,0 +1,178 @@
+// Package health reports whether the service is alive and ready for traffic
+package health
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"my-backend-app/database"
+	"my-backend-app/jobs"
+
+	"gorm.io/gorm"
+)
+
+// DefaultTimeout bounds the dependency checks of one readiness probe
+const DefaultTimeout = 2 * time.Second
+
+// Status values of a report and its components
+const (
+	StatusOK       = "ok"
+	StatusDown     = "down"
+	StatusFailing  = "failing"
+	StatusDraining = "draining"
+)
+
+// Checker checks the dependencies of the service. Readiness fails for good
+// once Drain is called during shutdown.
+type Checker struct {
+	Timeout time.Duration
+
+	db        *gorm.DB
+	migrator  *database.Migrator
+	scheduler *jobs.Scheduler
+	draining  atomic.Bool
+}
+
+// NewChecker creates a checker for the given dependencies. A nil dependency
+// is left out of the report.
+func NewChecker(db *gorm.DB, migrator *database.Migrator, scheduler *jobs.Scheduler) *Checker {
+	return &Checker{Timeout: DefaultTimeout, db: db, migrator: migrator, scheduler: scheduler}
+}
+
+// Drain marks the service as shutting down so readiness fails
+func (c *Checker) Drain() {
+	c.draining.Store(true)
+}
+
+// Draining reports whether Drain has been called
+func (c *Checker) Draining() bool {
+	return c.draining.Load()
+}
+
+// Report is the readiness of the service and each of its dependencies
+type Report struct {
+	Status     string      `json:"status"`
+	Database   *Database   `json:"database,omitempty"`
+	Migrations *Migrations `json:"migrations,omitempty"`
+	Jobs       *Jobs       `json:"jobs,omitempty"`
+}
+
+// Database reports whether the database answers a ping and its pool usage
+type Database struct {
+	Status    string    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	LatencyMS int64     `json:"latency_ms"`
+	Pool      PoolStats `json:"pool"`
+}
+
+// PoolStats is a summary of sql.DBStats
+type PoolStats struct {
+	MaxOpenConnections int   `json:"max_open_connections"`
+	OpenConnections    int   `json:"open_connections"`
+	InUse              int   `json:"in_use"`
+	Idle               int   `json:"idle"`
+	WaitCount          int64 `json:"wait_count"`
+	WaitDurationMS     int64 `json:"wait_duration_ms"`
+}
+
+// Migrations reports the applied schema version against the latest one the
+// binary knows. The service is not ready while migrations are pending.
+type Migrations struct {
+	Status  string `json:"status"`
+	Error   string `json:"error,omitempty"`
+	Version int    `json:"version"`
+	Latest  int    `json:"latest"`
+}
+
+// Jobs reports the background jobs. A failing job is reported but does not
+// make the service unready, since requests can still be served.
+type Jobs struct {
+	Status string        `json:"status"`
+	Jobs   []jobs.Status `json:"jobs"`
+}
+
+// Ready checks every dependency and reports whether all of them are healthy
+func (c *Checker) Ready(ctx context.Context) (Report, bool) {
+	if c.Draining() {
+		return Report{Status: StatusDraining}, false
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	report := Report{Status: StatusOK}
+	ready := true
+	if c.db != nil {
+		report.Database = c.checkDatabase(ctx)
+		ready = ready && report.Database.Status == StatusOK
+	}
+	if c.migrator != nil {
+		report.Migrations = c.checkMigrations(ctx)
+		ready = ready && report.Migrations.Status == StatusOK
+	}
+	if c.scheduler != nil {
+		report.Jobs = c.checkJobs()
+	}
+	if !ready {
+		report.Status = StatusDown
+	}
+	return report, ready
+}
+
+func (c *Checker) checkDatabase(ctx context.Context) *Database {
+	result := &Database{Status: StatusOK}
+	sqlDB, err := c.db.DB()
+	if err != nil {
+		result.Status, result.Error = StatusDown, err.Error()
+		return result
+	}
+
+	start := time.Now()
+	err = sqlDB.PingContext(ctx)
+	result.LatencyMS = time.Since(start).Milliseconds()
+	if err != nil {
+		result.Status, result.Error = StatusDown, err.Error()
+	}
+
+	stats := sqlDB.Stats()
+	result.Pool = PoolStats{
+		MaxOpenConnections: stats.MaxOpenConnections,
+		OpenConnections:    stats.OpenConnections,
+		InUse:              stats.InUse,
+		Idle:               stats.Idle,
+		WaitCount:          stats.WaitCount,
+		WaitDurationMS:     stats.WaitDuration.Milliseconds(),
+	}
+	return result
+}
+
+func (c *Checker) checkMigrations(ctx context.Context) *Migrations {
+	result := &Migrations{Status: StatusOK}
+	if n := len(c.migrator.Migrations); n > 0 {
+		result.Latest = c.migrator.Migrations[n-1].Version
+	}
+
+	// Run the lookup on a copy bound to ctx so it honours the timeout
+	migrator := *c.migrator
+	migrator.DB = migrator.DB.WithContext(ctx)
+	version, err := migrator.Version()
+	result.Version = version
+	switch {
+	case err != nil:
+		result.Status, result.Error = StatusDown, err.Error()
+	case version < result.Latest:
+		result.Status, result.Error = StatusDown, "migrations are pending"
+	}
+	return result
+}
+
+func (c *Checker) checkJobs() *Jobs {
+	result := &Jobs{Status: StatusOK, Jobs: c.scheduler.Statuses()}
+	for _, job := range result.Jobs {
+		if job.LastError != "" {
+			result.Status = StatusFailing
+		}
+	}
+	return result
+}
@@ -0,0 +1,107 @@
+package jobs
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Status reports the most recent outcome of a scheduled job
+type Status struct {
+	Name      string        `json:"name"`
+	Interval  time.Duration `json:"interval"`
+	LastRunAt time.Time     `json:"last_run_at"`
+	LastError string        `json:"last_error,omitempty"`
+}
+
+type job struct {
+	name     string
+	interval time.Duration
+	run      func(ctx context.Context) error
+
+	mu     sync.Mutex
+	status Status
+}
+
+// Scheduler runs registered jobs at fixed intervals in background goroutines
+type Scheduler struct {
+	jobs   []*job
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewScheduler creates an empty scheduler
+func NewScheduler() *Scheduler {
+	return &Scheduler{}
+}
+
+// Every registers run to be called once per interval. Jobs must be registered
+// before Start is called.
+func (s *Scheduler) Every(name string, interval time.Duration, run func(ctx context.Context) error) {
+	s.jobs = append(s.jobs, &job{
+		name:     name,
+		interval: interval,
+		run:      run,
+		status:   Status{Name: name, Interval: interval},
+	})
+}
+
+// Start runs every registered job immediately and then once per interval
+func (s *Scheduler) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+
+	for _, j := range s.jobs {
+		s.wg.Add(1)
+		go func(j *job) {
+			defer s.wg.Done()
+
+			ticker := time.NewTicker(j.interval)
+			defer ticker.Stop()
+
+			for {
+				j.execute(ctx)
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+			}
+		}(j)
+	}
+}
+
+// Stop cancels running jobs and waits for them to return
+func (s *Scheduler) Stop() {
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.wg.Wait()
+}
+
+// Statuses returns the latest status of every registered job
+func (s *Scheduler) Statuses() []Status {
+	statuses := make([]Status, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		j.mu.Lock()
+		statuses = append(statuses, j.status)
+		j.mu.Unlock()
+	}
+	return statuses
+}
+
+func (j *job) execute(ctx context.Context) {
+	err := j.run(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "job failed", "job", j.name, "error", err)
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.status.LastRunAt = time.Now()
+	j.status.LastError = ""
+	if err != nil {
+		j.status.LastError = err.Error()
+	}
+}
@@ -0,0 +1,237 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"my-backend-app/models"
+	"my-backend-app/repository"
+
+	"github.com/google/uuid"
+)
+
+// ErrInsufficientPoints is returned when a debit would take a balance below zero
+var ErrInsufficientPoints = repository.ErrInsufficientPoints
+
+// ErrLotShortfall is returned when a customer's open lots hold fewer points
+// than a debit the cached balance covers. Migration 005 opened a lot for every
+// balance that predates lot tracking, so this means the lots and the balance
+// have drifted apart.
+var ErrLotShortfall = errors.New("point lots do not cover the balance")
+
+// ActorSystem identifies entries posted by the application itself
+const ActorSystem = "system"
+
+// PointLifetimeMonths is how long credited points remain spendable
+const PointLifetimeMonths = 12
+
+// ExpiringSoonWindow is how far ahead ExpiringSoon looks for lots about to expire
+const ExpiringSoonWindow = 30 * 24 * time.Hour
+
+// Post records entry and applies its amount to the customer's cached balance.
+// Debits (negative amounts) are only applied if the balance covers them. Credits
+// open a new point lot and debits consume existing lots oldest first. Post must
+// run inside Store.Atomic so all writes commit together.
+func Post(ctx context.Context, customers repository.CustomerRepository, entry *models.PointLedgerEntry) error {
+	if err := record(ctx, customers, entry); err != nil {
+		return err
+	}
+
+	if entry.Amount > 0 {
+		return openLot(ctx, customers, entry, entry.Amount)
+	}
+	return consumeLots(ctx, customers, entry, -entry.Amount)
+}
+
+// Refund records entry, a credit that gives back points taken by the
+// customer's debits referencing debitReferenceID. The points return to the
+// lots those debits consumed, latest expiry first, so they keep the expiry
+// they were earned with; points returned to a lot that has already expired
+// expire again on the next expiry run. Points the debits cannot account for,
+// such as debits posted before consumptions were recorded, open a new lot.
+// Refund must run inside Store.Atomic.
+func Refund(ctx context.Context, customers repository.CustomerRepository, entry *models.PointLedgerEntry, debitReferenceID uuid.UUID) error {
+	if entry.Amount <= 0 {
+		return fmt.Errorf("refund amount must be positive, got %d", entry.Amount)
+	}
+	if err := record(ctx, customers, entry); err != nil {
+		return err
+	}
+
+	consumptions, err := customers.DebitConsumptions(ctx, entry.CustomerID, debitReferenceID)
+	if err != nil {
+		return err
+	}
+	sort.SliceStable(consumptions, func(i, j int) bool {
+		return consumptions[i].Lot.ExpiresAt.After(consumptions[j].Lot.ExpiresAt)
+	})
+
+	points := entry.Amount
+	for i := range consumptions {
+		if points == 0 {
+			break
+		}
+		consumption := &consumptions[i]
+		restored := consumption.Points - consumption.RestoredPoints
+		if restored > points {
+			restored = points
+		}
+		if restored <= 0 {
+			continue
+		}
+
+		consumption.RestoredPoints += restored
+		if err := customers.UpdateLotConsumption(ctx, consumption); err != nil {
+			return err
+		}
+		lot := &consumption.Lot
+		lot.RemainingPoints += restored
+		if err := customers.UpdateLot(ctx, lot); err != nil {
+			return err
+		}
+		points -= restored
+	}
+
+	if points > 0 {
+		return openLot(ctx, customers, entry, points)
+	}
+	return nil
+}
+
+// record applies entry to the cached balance and stores it
+func record(ctx context.Context, customers repository.CustomerRepository, entry *models.PointLedgerEntry) error {
+	balance, err := customers.AddPoints(ctx, entry.CustomerID, entry.Amount)
+	if err != nil {
+		return err
+	}
+	entry.BalanceAfter = balance
+
+	return customers.CreateLedgerEntry(ctx, entry)
+}
+
+// openLot opens a lot of points earned now for the credit entry
+func openLot(ctx context.Context, customers repository.CustomerRepository, entry *models.PointLedgerEntry, points int) error {
+	earnedAt := time.Now()
+	return customers.CreateLot(ctx, &models.PointLot{
+		CustomerID:      entry.CustomerID,
+		LedgerEntryID:   entry.ID,
+		Points:          points,
+		RemainingPoints: points,
+		EarnedAt:        earnedAt,
+		ExpiresAt:       earnedAt.AddDate(0, PointLifetimeMonths, 0),
+	})
+}
+
+// consumeLots takes points for the debit entry from the customer's open lots,
+// soonest expiry first, and records what it took from each lot
+func consumeLots(ctx context.Context, customers repository.CustomerRepository, entry *models.PointLedgerEntry, points int) error {
+	lots, err := customers.OpenLots(ctx, entry.CustomerID, time.Time{}, time.Time{})
+	if err != nil {
+		return err
+	}
+
+	for i := range lots {
+		if points == 0 {
+			break
+		}
+		lot := &lots[i]
+		used := lot.RemainingPoints
+		if used > points {
+			used = points
+		}
+		lot.RemainingPoints -= used
+		if err := customers.UpdateLot(ctx, lot); err != nil {
+			return err
+		}
+		if err := customers.CreateLotConsumption(ctx, &models.PointLotConsumption{
+			LedgerEntryID: entry.ID,
+			LotID:         lot.ID,
+			Points:        used,
+		}); err != nil {
+			return err
+		}
+		points -= used
+	}
+
+	if points > 0 {
+		return fmt.Errorf("%w: customer %s is short %d points", ErrLotShortfall, entry.CustomerID, points)
+	}
+	return nil
+}
+
+// ExpireCustomerLots debits every lot of the customer that has expired by now
+// and returns how many lots were expired. It must run inside Store.Atomic,
+// ideally with the customer row locked.
+func ExpireCustomerLots(ctx context.Context, customers repository.CustomerRepository, customerID uuid.UUID, now time.Time) (int, error) {
+	lots, err := customers.OpenLots(ctx, customerID, time.Time{}, now)
+	if err != nil {
+		return 0, err
+	}
+
+	// Expired lots sort ahead of every live lot, so the FIFO consumption in
+	// Post drains exactly the lot each entry refers to
+	for i := range lots {
+		lot := &lots[i]
+		if err := Post(ctx, customers, &models.PointLedgerEntry{
+			CustomerID:  customerID,
+			EntryType:   models.LedgerEntryExpiry,
+			Amount:      -lot.RemainingPoints,
+			Reason:      "Points expired",
+			Actor:       ActorSystem,
+			ReferenceID: &lot.ID,
+		}); err != nil {
+			return 0, err
+		}
+		lot.RemainingPoints = 0
+		lot.ExpiredAt = &now
+		if err := customers.UpdateLot(ctx, lot); err != nil {
+			return 0, err
+		}
+	}
+	return len(lots), nil
+}
+
+// ExpireDueLots expires the lots of every customer that have expired by now,
+// one database transaction per customer, and returns how many lots were expired
+func ExpireDueLots(ctx context.Context, store repository.Store, now time.Time) (int, error) {
+	customerIDs, err := store.Customers().CustomersWithExpiredLots(ctx, now)
+	if err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for _, customerID := range customerIDs {
+		err := store.Atomic(ctx, func(tx repository.Store) error {
+			if _, err := tx.Customers().GetForUpdate(ctx, customerID, true); err != nil {
+				return err
+			}
+			n, err := ExpireCustomerLots(ctx, tx.Customers(), customerID, now)
+			if err != nil {
+				return err
+			}
+			expired += n
+			return nil
+		})
+		if err != nil {
+			return expired, err
+		}
+	}
+	return expired, nil
+}
+
+// ExpiringSoon returns the customer's open lots that expire within ExpiringSoonWindow
+func ExpiringSoon(ctx context.Context, customers repository.CustomerRepository, customerID uuid.UUID, now time.Time) ([]models.PointLot, error) {
+	return customers.OpenLots(ctx, customerID, now, now.Add(ExpiringSoonWindow))
+}
+
+// Mismatch describes a customer whose cached balance disagrees with the ledger
+type Mismatch = repository.BalanceMismatch
+
+// Reconcile compares every customer's cached balance with the sum of their
+// ledger entries and returns the customers that disagree
+func Reconcile(ctx context.Context, customers repository.CustomerRepository) ([]Mismatch, error) {
+	return customers.BalanceMismatches(ctx)
+}
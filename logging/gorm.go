@@ -0,0 +1,93 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// GormLogger writes GORM logs through slog so SQL statements carry the
+// request ID of their context. Failed statements are logged as errors,
+// statements slower than SlowThreshold as warnings with slow set, and every
+// other statement at debug level when the GORM level is Info.
+type GormLogger struct {
+	Logger        *slog.Logger
+	Level         gormlogger.LogLevel
+	SlowThreshold time.Duration
+}
+
+// NewGormLogger creates a GORM logger. A zero slowThreshold disables the slow
+// query warning.
+func NewGormLogger(logger *slog.Logger, level gormlogger.LogLevel, slowThreshold time.Duration) *GormLogger {
+	return &GormLogger{Logger: logger, Level: level, SlowThreshold: slowThreshold}
+}
+
+// LogMode returns a copy logging at level
+func (l *GormLogger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	clone := *l
+	clone.Level = level
+	return &clone
+}
+
+func (l *GormLogger) Info(ctx context.Context, msg string, data ...interface{}) {
+	if l.Level >= gormlogger.Info {
+		l.Logger.InfoContext(ctx, fmt.Sprintf(msg, data...))
+	}
+}
+
+func (l *GormLogger) Warn(ctx context.Context, msg string, data ...interface{}) {
+	if l.Level >= gormlogger.Warn {
+		l.Logger.WarnContext(ctx, fmt.Sprintf(msg, data...))
+	}
+}
+
+func (l *GormLogger) Error(ctx context.Context, msg string, data ...interface{}) {
+	if l.Level >= gormlogger.Error {
+		l.Logger.ErrorContext(ctx, fmt.Sprintf(msg, data...))
+	}
+}
+
+// Trace logs a statement after it ran. Lookups that find nothing are not
+// errors.
+func (l *GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	if l.Level <= gormlogger.Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	slow := l.SlowThreshold > 0 && elapsed > l.SlowThreshold
+	failed := err != nil && !errors.Is(err, gorm.ErrRecordNotFound)
+
+	var level slog.Level
+	var msg string
+	switch {
+	case failed && l.Level >= gormlogger.Error:
+		level, msg = slog.LevelError, "query failed"
+	case slow && l.Level >= gormlogger.Warn:
+		level, msg = slog.LevelWarn, "slow query"
+	case l.Level >= gormlogger.Info:
+		level, msg = slog.LevelDebug, "query"
+	default:
+		return
+	}
+	if !l.Logger.Enabled(ctx, level) {
+		return
+	}
+
+	sql, rows := fc()
+	attrs := []slog.Attr{
+		slog.String("sql", sql),
+		slog.Int64("rows", rows),
+		slog.Float64("duration_ms", float64(elapsed.Microseconds())/1000),
+		slog.Bool("slow", slow),
+	}
+	if failed {
+		attrs = append(attrs, slog.Any("error", err))
+	}
+	l.Logger.LogAttrs(ctx, level, msg, attrs...)
+}
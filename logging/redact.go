@@ -0,0 +1,32 @@
+package logging
+
+import "regexp"
+
+// Placeholders written in place of personal data
+const (
+	RedactedEmail = "[REDACTED_EMAIL]"
+	RedactedPhone = "[REDACTED_PHONE]"
+)
+
+var (
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+
+	// Phone numbers in international format (+62 812-3456-7890), with an
+	// area code in parentheses ((021) 555-0100) or as local digits starting
+	// with 0 (081234567890). Bare digits must not follow a letter, digit or
+	// hyphen so the tail of a UUID is left alone.
+	phonePatterns = []*regexp.Regexp{
+		regexp.MustCompile(`\+\d[\d\s().-]{6,18}\d`),
+		regexp.MustCompile(`\(\d{2,4}\)\s?\d{3,4}[\s.-]?\d{3,4}`),
+	}
+	localPhonePattern = regexp.MustCompile(`(^|[^\w-])0\d{8,13}\b`)
+)
+
+// Redact replaces email addresses and phone numbers in s with placeholders
+func Redact(s string) string {
+	s = emailPattern.ReplaceAllString(s, RedactedEmail)
+	for _, pattern := range phonePatterns {
+		s = pattern.ReplaceAllString(s, RedactedPhone)
+	}
+	return localPhonePattern.ReplaceAllString(s, "${1}"+RedactedPhone)
+}
@@ -1,59 +1,157 @@
 package main
 
 import (
+	"context"
 	"log"
+	"log/slog"
+	"net"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"my-backend-app/auth"
+	"my-backend-app/config"
 	"my-backend-app/database"
+	"my-backend-app/health"
+	"my-backend-app/jobs"
+	"my-backend-app/logging"
+	"my-backend-app/metrics"
+	"my-backend-app/middleware"
+	"my-backend-app/migrations"
+	"my-backend-app/ratelimit"
+	"my-backend-app/repository"
 	"my-backend-app/routes"
+	"my-backend-app/server"
+	"my-backend-app/service"
+	"my-backend-app/tracing"
 
 	"github.com/gin-gonic/gin"
-	"github.com/joho/godotenv"
 )
 
 func main() {
-	// Load environment variables
-	if err := godotenv.Load("config.env"); err != nil {
-		log.Println("Warning: config.env file not found, using system environment variables")
+	// Load and validate the configuration
+	cfg, err := config.Load("config.env")
+	if err != nil {
+		log.Fatal(err)
+	}
+	logging.Setup(cfg.Log, os.Stderr)
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		fatal("Failed to set up tracing", err)
 	}
 
-	// Initialize database
-	database.InitDB()
-
-	// Set Gin mode
-	if os.Getenv("GIN_MODE") == "release" {
-		gin.SetMode(gin.ReleaseMode)
+	// The migrate command manages the schema itself, so it connects without
+	// applying pending migrations first
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		database.Connect(cfg)
+		os.Exit(runMigrate(os.Args[2:]))
 	}
 
-	// Create Gin router
-	r := gin.Default()
+	// Initialize database and apply pending migrations
+	database.InitDB(cfg)
+	db := database.GetDB()
 
-	// Add CORS middleware
-	r.Use(func(c *gin.Context) {
-		c.Header("Access-Control-Allow-Origin", "*")
-		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization")
+	// Build the service layer on the GORM repositories. One-time code login
+	// stays disabled until an SMS or email sender is passed with
+	// service.WithOTPSender.
+	store := repository.NewStore(db)
+	services := service.New(store, service.WithTokens(auth.NewTokens(cfg.Auth)))
 
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(204)
-			return
+	// Run a maintenance command instead of the server when one is given
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "reconcile-points":
+			os.Exit(reconcilePoints(services))
+		case "create-api-key":
+			os.Exit(createAPIKey(services, os.Args[2:]))
+		default:
+			log.Fatalf("Unknown command %q", os.Args[1])
 		}
+	}
 
-		c.Next()
+	// Start background jobs
+	scheduler := jobs.NewScheduler()
+	scheduler.Every("point-expiry", time.Hour, func(ctx context.Context) error {
+		expired, err := services.Customers.ExpirePoints(ctx, time.Now())
+		if expired > 0 {
+			slog.InfoContext(ctx, "Expired point lots", "count", expired)
+		}
+		return err
 	})
+	scheduler.Every("idempotency-purge", time.Hour, func(ctx context.Context) error {
+		purged, err := store.Idempotency().DeleteExpired(ctx, time.Now())
+		if purged > 0 {
+			slog.InfoContext(ctx, "Purged expired idempotency keys", "count", purged)
+		}
+		return err
+	})
+	scheduler.Start()
+
+	// Set Gin mode
+	if cfg.Server.Mode != "" {
+		gin.SetMode(cfg.Server.Mode)
+	}
+
+	// Create Gin router with request IDs, tracing, structured access logs and
+	// panic recovery
+	r := gin.New()
+	// Only believe X-Forwarded-For from configured proxies, so clients cannot
+	// pick their own IP to dodge the per-IP login limit
+	if err := r.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		fatal("Failed to set trusted proxies", err)
+	}
+	r.Use(middleware.RequestID(), middleware.Tracing(), middleware.Logger(), middleware.Recovery())
+
+	// Export the connection pool statistics
+	if err := metrics.RegisterDB(db, cfg.Database.Driver); err != nil {
+		fatal("Failed to register database metrics", err)
+	}
+
+	// Add CORS and request metrics middleware
+	r.Use(middleware.CORS(cfg.CORS.AllowedOrigins), middleware.Metrics())
 
 	// Setup routes
-	routes.SetupRoutes(r)
+	migrator, err := database.NewMigrator(db, migrations.FS)
+	if err != nil {
+		fatal("Failed to load migrations", err)
+	}
+	checker := health.NewChecker(db, migrator, scheduler)
+	routes.SetupHealthRoutes(r, checker)
+	routes.SetupMetricsRoutes(r)
+	routes.SetupRoutes(r, services, store, cfg.RateLimit, ratelimit.NewMemoryStore())
+
+	// Serve until SIGINT or SIGTERM, then drain in-flight requests
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	// Get port from environment variable
-	port := os.Getenv("SERVER_PORT")
-	if port == "" {
-		port = "8080"
+	srv := server.New(cfg.Server, r)
+	listener, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
+		fatal("Failed to start server", err)
 	}
 
-	// Start server
-	log.Printf("Server starting on port %s", port)
-	if err := r.Run(":" + port); err != nil {
-		log.Fatal("Failed to start server:", err)
+	slog.Info("Server starting", "port", cfg.Server.Port)
+	if err := server.Serve(ctx, srv, listener, checker, cfg.Server); err != nil {
+		slog.Error("Server did not shut down cleanly", "error", err)
 	}
+
+	// Stop background jobs, release the database connections and flush the
+	// remaining spans
+	scheduler.Stop()
+	if err := database.Close(); err != nil {
+		slog.Error("Failed to close database", "error", err)
+	}
+	flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := shutdownTracing(flushCtx); err != nil {
+		slog.Error("Failed to flush traces", "error", err)
+	}
+	slog.Info("Server stopped")
+}
+
+// fatal logs err and exits
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
 }
@@ -0,0 +1,96 @@
+// Package metrics defines the Prometheus metrics of the service and serves
+// them in the text exposition format
+package metrics
+
+import (
+	"net/http"
+
+	"my-backend-app/models"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"gorm.io/gorm"
+)
+
+// Registry holds every metric exposed on /metrics
+var Registry = prometheus.NewRegistry()
+
+// HTTP metrics, labelled with the route pattern rather than the raw path so
+// IDs do not create a series per resource
+var (
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "HTTP requests by method, route and status code.",
+	}, []string{"method", "route", "status"})
+
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "HTTP request latency by method and route.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route"})
+)
+
+// Business metrics
+var (
+	Redemptions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "voucher_redemptions_total",
+		Help: "Redemption transactions by the status they reached: completed when created, refunded or partially_refunded when cancelled.",
+	}, []string{"status"})
+
+	PointsRedeemed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "voucher_points_redeemed_total",
+		Help: "Points spent on redemptions by voucher brand.",
+	}, []string{"brand_id"})
+
+	RedemptionFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "voucher_redemption_failures_total",
+		Help: "Rejected redemption requests by error code.",
+	}, []string{"code"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests,
+		HTTPRequestDuration,
+		Redemptions,
+		PointsRedeemed,
+		RedemptionFailures,
+	)
+}
+
+// Handler serves the metrics in Registry
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// RegisterDB exposes the connection pool statistics of db as the go_sql_*
+// gauges, labelled with name
+func RegisterDB(db *gorm.DB, name string) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return Registry.Register(collectors.NewDBStatsCollector(sqlDB, name))
+}
+
+// ObserveRedemption counts a completed redemption and the points it spent
+// per brand. The items must have their vouchers loaded.
+func ObserveRedemption(transaction models.Transaction) {
+	Redemptions.WithLabelValues(transaction.Status).Inc()
+	for _, item := range transaction.Items {
+		PointsRedeemed.WithLabelValues(item.Voucher.BrandID.String()).Add(float64(item.TotalPoints))
+	}
+}
+
+// ObserveRefund counts a cancellation by the status it left the transaction in
+func ObserveRefund(transaction models.Transaction) {
+	Redemptions.WithLabelValues(transaction.Status).Inc()
+}
+
+// ObserveRedemptionFailure counts a rejected redemption by its error code
+func ObserveRedemptionFailure(code string) {
+	RedemptionFailures.WithLabelValues(code).Inc()
+}
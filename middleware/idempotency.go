@@ -0,0 +1,161 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"my-backend-app/apierror"
+	"my-backend-app/auth"
+	"my-backend-app/models"
+	"my-backend-app/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IdempotencyKeyHeader is the request header clients use to make a POST safe to retry
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// responseRecorder captures the response body so it can be stored for replays
+type responseRecorder struct {
+	gin.ResponseWriter
+	body *bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Idempotency returns a middleware that stores in store the response of requests
+// sent with an Idempotency-Key header for the given TTL. Keys are scoped to the
+// authenticated caller, so callers choosing the same key never see each
+// other's responses. A retry with the same key and body replays the stored
+// response, while a retry with the same key and a different body is rejected
+// with 422. Requests without the header pass through.
+func Idempotency(store repository.Store, ttl time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+
+		if len(key) > 255 {
+			apierror.Abort(c, http.StatusBadRequest, apierror.CodeValidationFailed, "Idempotency key must be at most 255 characters")
+			return
+		}
+
+		// Read the body so it can be hashed, then restore it for the handler
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			apierror.Abort(c, http.StatusBadRequest, apierror.CodeInvalidRequestBody, "Failed to read request body")
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		caller := ""
+		if principal := auth.FromContext(c); principal != nil {
+			caller = principal.Identity()
+		}
+		requestHash := hashRequest(c.Request.Method, c.FullPath(), body)
+
+		keys := store.Idempotency()
+		record, err := keys.Get(c.Request.Context(), caller, key)
+		if err == nil {
+			if time.Now().After(record.ExpiresAt) {
+				// Expired keys are released so the request can run again
+				release(c, keys, record)
+			} else {
+				replay(c, record, requestHash)
+				return
+			}
+		} else if !errors.Is(err, repository.ErrNotFound) {
+			slog.ErrorContext(c.Request.Context(), "Failed to look up idempotency key", "error", err)
+		}
+
+		// Claim the key before running the handler. A concurrent request with
+		// the same key loses the insert race and is told to retry later.
+		record = models.IdempotencyKey{
+			Caller:      caller,
+			Key:         key,
+			RequestHash: requestHash,
+			ExpiresAt:   time.Now().Add(ttl),
+		}
+		if err := keys.Claim(c.Request.Context(), &record); err != nil {
+			apierror.Abort(c, http.StatusConflict, apierror.CodeIdempotencyKeyInProgress, "A request with this idempotency key is already in progress")
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer, body: &bytes.Buffer{}}
+		c.Writer = recorder
+
+		// A panicking handler leaves no response to store, so release the
+		// key on the way out instead of blocking retries until it expires
+		completed := false
+		defer func() {
+			if !completed {
+				release(c, keys, record)
+			}
+		}()
+		c.Next()
+		completed = true
+
+		// Server errors are not cached so the client can retry them
+		status := recorder.Status()
+		if status >= http.StatusInternalServerError {
+			release(c, keys, record)
+			return
+		}
+
+		if err := keys.SaveResponse(c.Request.Context(), caller, key, status, recorder.body.String()); err != nil {
+			// The key stays claimed, so retries get 409 until it expires
+			slog.ErrorContext(c.Request.Context(), "Failed to store idempotent response", "error", err)
+		}
+	}
+}
+
+// release deletes a stored key so a request with it can run again
+func release(c *gin.Context, keys repository.IdempotencyRepository, record models.IdempotencyKey) {
+	if err := keys.Delete(c.Request.Context(), record.Caller, record.Key); err != nil {
+		slog.ErrorContext(c.Request.Context(), "Failed to release idempotency key", "error", err)
+	}
+}
+
+// replay answers a request whose key has already been seen
+func replay(c *gin.Context, record models.IdempotencyKey, requestHash string) {
+	if record.RequestHash != requestHash {
+		apierror.Abort(c, http.StatusUnprocessableEntity, apierror.CodeIdempotencyKeyReused, "Idempotency key has already been used with a different request")
+		return
+	}
+
+	if record.ResponseStatus == 0 {
+		apierror.Abort(c, http.StatusConflict, apierror.CodeIdempotencyKeyInProgress, "A request with this idempotency key is already in progress")
+		return
+	}
+
+	c.Header("Idempotent-Replayed", "true")
+	c.Data(record.ResponseStatus, "application/json; charset=utf-8", []byte(record.ResponseBody))
+	c.Abort()
+}
+
+// hashRequest fingerprints the parts of a request that must match on replay
+func hashRequest(method, path string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method))
+	h.Write([]byte{0})
+	h.Write([]byte(path))
+	h.Write([]byte{0})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
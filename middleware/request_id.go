@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"my-backend-app/logging"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader carries the ID that ties together the logs of one request
+const RequestIDHeader = "X-Request-ID"
+
+// RequestID returns a middleware that keeps the client's X-Request-ID, or
+// assigns a new one when it is missing or malformed, echoes it in the response
+// and stores it in the request context for logging
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = uuid.NewString()
+		}
+
+		c.Header(RequestIDHeader, id)
+		c.Request = c.Request.WithContext(logging.WithRequestID(c.Request.Context(), id))
+		c.Next()
+	}
+}
+
+// validRequestID accepts up to 128 printable ASCII characters without spaces
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
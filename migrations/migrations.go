@@ -0,0 +1,11 @@
+// Package migrations embeds the versioned SQL migrations. Each database
+// dialect has its own directory of files named NNN_description.sql, split into
+// "-- +migrate Up" and "-- +migrate Down" sections.
+package migrations
+
+import "embed"
+
+// FS holds the migration files of every dialect
+//
+//go:embed mysql/*.sql postgres/*.sql sqlite/*.sql
+var FS embed.FS
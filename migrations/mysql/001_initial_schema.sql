@@ -1,6 +1,7 @@
 -- Migration: 001_initial_schema.sql
 -- Description: Initial database schema for voucher system
 
+-- +migrate Up
 -- Create brands table
 CREATE TABLE IF NOT EXISTS brands (
     id CHAR(36) PRIMARY KEY,
@@ -70,4 +71,11 @@ CREATE INDEX idx_vouchers_is_active ON vouchers(is_active);
 CREATE INDEX idx_transactions_customer_id ON transactions(customer_id);
 CREATE INDEX idx_transaction_items_transaction_id ON transaction_items(transaction_id);
 CREATE INDEX idx_transaction_items_voucher_id ON transaction_items(voucher_id);
-CREATE INDEX idx_customers_email ON customers(email);
\ No newline at end of file
+CREATE INDEX idx_customers_email ON customers(email);
+
+-- +migrate Down
+DROP TABLE IF EXISTS transaction_items;
+DROP TABLE IF EXISTS transactions;
+DROP TABLE IF EXISTS customers;
+DROP TABLE IF EXISTS vouchers;
+DROP TABLE IF EXISTS brands;
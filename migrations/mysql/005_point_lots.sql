@@ -0,0 +1,34 @@
+-- Migration: 005_point_lots.sql
+-- Description: Expiring lots of earned points, consumed oldest first
+
+-- +migrate Up
+-- Create point_lots table
+CREATE TABLE IF NOT EXISTS point_lots (
+    id CHAR(36) PRIMARY KEY,
+    customer_id CHAR(36) NOT NULL,
+    ledger_entry_id CHAR(36) NOT NULL,
+    points INT NOT NULL,
+    remaining_points INT NOT NULL,
+    earned_at DATETIME(6) NOT NULL,
+    expires_at DATETIME(6) NOT NULL,
+    expired_at DATETIME(6) NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (customer_id) REFERENCES customers(id) ON DELETE CASCADE,
+    FOREIGN KEY (ledger_entry_id) REFERENCES point_ledger(id)
+);
+
+-- Create indexes for better performance
+CREATE INDEX idx_point_lots_customer_id ON point_lots(customer_id, remaining_points);
+CREATE INDEX idx_point_lots_expires_at ON point_lots(expires_at);
+
+-- Existing balances become a single lot earned today
+INSERT INTO point_lots (id, customer_id, ledger_entry_id, points, remaining_points, earned_at, expires_at)
+SELECT UUID(), c.id,
+       (SELECT l.id FROM point_ledger l WHERE l.customer_id = c.id ORDER BY l.created_at DESC LIMIT 1),
+       c.points, c.points, NOW(6), DATE_ADD(NOW(6), INTERVAL 12 MONTH)
+FROM customers c
+WHERE c.points > 0;
+
+-- +migrate Down
+DROP TABLE IF EXISTS point_lots;
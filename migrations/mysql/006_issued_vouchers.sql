@@ -0,0 +1,27 @@
+-- Migration: 006_issued_vouchers.sql
+-- Description: Unique voucher codes minted for each redeemed unit
+
+-- +migrate Up
+-- Create issued_vouchers table
+CREATE TABLE IF NOT EXISTS issued_vouchers (
+    id CHAR(36) PRIMARY KEY,
+    code VARCHAR(32) NOT NULL UNIQUE,
+    transaction_item_id CHAR(36) NOT NULL,
+    voucher_id CHAR(36) NOT NULL,
+    customer_id CHAR(36) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'issued',
+    expires_at TIMESTAMP NULL,
+    used_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (transaction_item_id) REFERENCES transaction_items(id) ON DELETE CASCADE,
+    FOREIGN KEY (voucher_id) REFERENCES vouchers(id) ON DELETE CASCADE,
+    FOREIGN KEY (customer_id) REFERENCES customers(id) ON DELETE CASCADE
+);
+
+-- Create indexes for better performance
+CREATE INDEX idx_issued_vouchers_transaction_item_id ON issued_vouchers(transaction_item_id);
+CREATE INDEX idx_issued_vouchers_customer_id ON issued_vouchers(customer_id);
+
+-- +migrate Down
+DROP TABLE IF EXISTS issued_vouchers;
@@ -0,0 +1,10 @@
+-- Migration: 007_voucher_code_burns.sql
+-- Description: Record where an issued voucher code was used
+
+-- +migrate Up
+ALTER TABLE issued_vouchers ADD COLUMN store_id VARCHAR(100) NULL AFTER used_at;
+ALTER TABLE issued_vouchers ADD COLUMN terminal_id VARCHAR(100) NULL AFTER store_id;
+
+-- +migrate Down
+ALTER TABLE issued_vouchers DROP COLUMN terminal_id;
+ALTER TABLE issued_vouchers DROP COLUMN store_id;
@@ -0,0 +1,19 @@
+-- Migration: 010_soft_delete.sql
+-- Description: Soft delete brands, vouchers and customers so transaction history keeps its references
+
+-- +migrate Up
+ALTER TABLE brands ADD COLUMN deleted_at DATETIME(6) NULL AFTER updated_at;
+ALTER TABLE vouchers ADD COLUMN deleted_at DATETIME(6) NULL AFTER updated_at;
+ALTER TABLE customers ADD COLUMN deleted_at DATETIME(6) NULL AFTER updated_at;
+
+CREATE INDEX idx_brands_deleted_at ON brands(deleted_at);
+CREATE INDEX idx_vouchers_deleted_at ON vouchers(deleted_at);
+CREATE INDEX idx_customers_deleted_at ON customers(deleted_at);
+
+-- +migrate Down
+DROP INDEX idx_customers_deleted_at ON customers;
+DROP INDEX idx_vouchers_deleted_at ON vouchers;
+DROP INDEX idx_brands_deleted_at ON brands;
+ALTER TABLE customers DROP COLUMN deleted_at;
+ALTER TABLE vouchers DROP COLUMN deleted_at;
+ALTER TABLE brands DROP COLUMN deleted_at;
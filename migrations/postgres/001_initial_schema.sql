@@ -0,0 +1,81 @@
+-- Migration: 001_initial_schema.sql
+-- Description: Initial database schema for voucher system
+
+-- +migrate Up
+-- Create brands table
+CREATE TABLE IF NOT EXISTS brands (
+    id UUID PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    logo_url VARCHAR(500),
+    is_active BOOLEAN DEFAULT TRUE,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create vouchers table
+CREATE TABLE IF NOT EXISTS vouchers (
+    id UUID PRIMARY KEY,
+    brand_id UUID NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    cost_in_point INT NOT NULL,
+    valid_from TIMESTAMPTZ NULL,
+    valid_to TIMESTAMPTZ NULL,
+    is_active BOOLEAN DEFAULT TRUE,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (brand_id) REFERENCES brands(id) ON DELETE CASCADE
+);
+
+-- Create customers table
+CREATE TABLE IF NOT EXISTS customers (
+    id UUID PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    email VARCHAR(255) UNIQUE NOT NULL,
+    phone VARCHAR(20),
+    points INT DEFAULT 0,
+    is_active BOOLEAN DEFAULT TRUE,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create transactions table
+CREATE TABLE IF NOT EXISTS transactions (
+    id UUID PRIMARY KEY,
+    customer_id UUID NOT NULL,
+    total_points INT NOT NULL,
+    status VARCHAR(50) DEFAULT 'pending',
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (customer_id) REFERENCES customers(id) ON DELETE CASCADE
+);
+
+-- Create transaction_items table
+CREATE TABLE IF NOT EXISTS transaction_items (
+    id UUID PRIMARY KEY,
+    transaction_id UUID NOT NULL,
+    voucher_id UUID NOT NULL,
+    quantity INT NOT NULL,
+    points_per_unit INT NOT NULL,
+    total_points INT NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (transaction_id) REFERENCES transactions(id) ON DELETE CASCADE,
+    FOREIGN KEY (voucher_id) REFERENCES vouchers(id) ON DELETE CASCADE
+);
+
+-- Create indexes for better performance
+CREATE INDEX idx_vouchers_brand_id ON vouchers(brand_id);
+CREATE INDEX idx_vouchers_is_active ON vouchers(is_active);
+CREATE INDEX idx_transactions_customer_id ON transactions(customer_id);
+CREATE INDEX idx_transaction_items_transaction_id ON transaction_items(transaction_id);
+CREATE INDEX idx_transaction_items_voucher_id ON transaction_items(voucher_id);
+CREATE INDEX idx_customers_email ON customers(email);
+
+-- +migrate Down
+DROP TABLE IF EXISTS transaction_items;
+DROP TABLE IF EXISTS transactions;
+DROP TABLE IF EXISTS customers;
+DROP TABLE IF EXISTS vouchers;
+DROP TABLE IF EXISTS brands;
@@ -0,0 +1,20 @@
+-- Migration: 002_idempotency_keys.sql
+-- Description: Stored responses for requests sent with an Idempotency-Key header
+
+-- +migrate Up
+-- Create idempotency_keys table
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    "key" VARCHAR(255) PRIMARY KEY,
+    request_hash VARCHAR(64) NOT NULL,
+    response_status INT DEFAULT 0,
+    response_body TEXT,
+    expires_at TIMESTAMPTZ NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create indexes for better performance
+CREATE INDEX idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
+
+-- +migrate Down
+DROP TABLE IF EXISTS idempotency_keys;
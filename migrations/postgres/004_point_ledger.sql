@@ -0,0 +1,30 @@
+-- Migration: 004_point_ledger.sql
+-- Description: Immutable ledger of point credits and debits per customer
+
+-- +migrate Up
+-- Create point_ledger table
+CREATE TABLE IF NOT EXISTS point_ledger (
+    id UUID PRIMARY KEY,
+    customer_id UUID NOT NULL,
+    entry_type VARCHAR(50) NOT NULL,
+    amount INT NOT NULL,
+    balance_after INT NOT NULL,
+    reason VARCHAR(500),
+    actor VARCHAR(255) NOT NULL,
+    reference_id UUID NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (customer_id) REFERENCES customers(id) ON DELETE CASCADE
+);
+
+-- Create indexes for better performance
+CREATE INDEX idx_point_ledger_customer_id ON point_ledger(customer_id, created_at);
+
+-- Record existing balances as opening entries so the ledger reconciles.
+-- gen_random_uuid() is built in from PostgreSQL 13.
+INSERT INTO point_ledger (id, customer_id, entry_type, amount, balance_after, reason, actor)
+SELECT gen_random_uuid(), id, 'adjustment', points, points, 'Opening balance', 'system'
+FROM customers
+WHERE points <> 0;
+
+-- +migrate Down
+DROP TABLE IF EXISTS point_ledger;
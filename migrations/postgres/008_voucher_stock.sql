@@ -0,0 +1,10 @@
+-- Migration: 008_voucher_stock.sql
+-- Description: Optional stock limits on vouchers (NULL means unlimited)
+
+-- +migrate Up
+ALTER TABLE vouchers ADD COLUMN total_stock INT NULL;
+ALTER TABLE vouchers ADD COLUMN remaining_stock INT NULL;
+
+-- +migrate Down
+ALTER TABLE vouchers DROP COLUMN remaining_stock;
+ALTER TABLE vouchers DROP COLUMN total_stock;
@@ -0,0 +1,12 @@
+-- Migration: 009_voucher_redemption_limits.sql
+-- Description: Optional per-customer and per-period redemption limits on vouchers
+
+-- +migrate Up
+ALTER TABLE vouchers ADD COLUMN max_per_customer INT NULL;
+ALTER TABLE vouchers ADD COLUMN max_per_period INT NULL;
+ALTER TABLE vouchers ADD COLUMN limit_period VARCHAR(20) NULL;
+
+-- +migrate Down
+ALTER TABLE vouchers DROP COLUMN limit_period;
+ALTER TABLE vouchers DROP COLUMN max_per_period;
+ALTER TABLE vouchers DROP COLUMN max_per_customer;
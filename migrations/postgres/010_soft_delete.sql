@@ -0,0 +1,19 @@
+-- Migration: 010_soft_delete.sql
+-- Description: Soft delete brands, vouchers and customers so transaction history keeps its references
+
+-- +migrate Up
+ALTER TABLE brands ADD COLUMN deleted_at TIMESTAMPTZ NULL;
+ALTER TABLE vouchers ADD COLUMN deleted_at TIMESTAMPTZ NULL;
+ALTER TABLE customers ADD COLUMN deleted_at TIMESTAMPTZ NULL;
+
+CREATE INDEX idx_brands_deleted_at ON brands(deleted_at);
+CREATE INDEX idx_vouchers_deleted_at ON vouchers(deleted_at);
+CREATE INDEX idx_customers_deleted_at ON customers(deleted_at);
+
+-- +migrate Down
+DROP INDEX IF EXISTS idx_customers_deleted_at;
+DROP INDEX IF EXISTS idx_vouchers_deleted_at;
+DROP INDEX IF EXISTS idx_brands_deleted_at;
+ALTER TABLE customers DROP COLUMN deleted_at;
+ALTER TABLE vouchers DROP COLUMN deleted_at;
+ALTER TABLE brands DROP COLUMN deleted_at;
@@ -0,0 +1,27 @@
+-- Migration: 011_api_keys.sql
+-- Description: Hashed API keys with admin, brand operator and customer roles
+
+-- +migrate Up
+-- Create api_keys table
+CREATE TABLE IF NOT EXISTS api_keys (
+    id UUID PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    prefix VARCHAR(16) NOT NULL,
+    key_hash VARCHAR(64) NOT NULL UNIQUE,
+    role VARCHAR(20) NOT NULL,
+    brand_id UUID NULL,
+    customer_id UUID NULL,
+    last_used_at TIMESTAMPTZ NULL,
+    revoked_at TIMESTAMPTZ NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (brand_id) REFERENCES brands(id) ON DELETE CASCADE,
+    FOREIGN KEY (customer_id) REFERENCES customers(id) ON DELETE CASCADE
+);
+
+-- Create indexes for better performance
+CREATE INDEX idx_api_keys_brand_id ON api_keys(brand_id);
+CREATE INDEX idx_api_keys_customer_id ON api_keys(customer_id);
+
+-- +migrate Down
+DROP TABLE IF EXISTS api_keys;
@@ -0,0 +1,38 @@
+-- Migration: 013_idempotency_key_scope.sql
+-- Description: Scope idempotency keys to the caller that sent them
+
+-- +migrate Up
+-- Stored responses only live for a day, so the table is recreated rather than migrated
+DROP TABLE IF EXISTS idempotency_keys;
+
+-- Create idempotency_keys table keyed by caller and key
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    caller VARCHAR(255) NOT NULL,
+    "key" VARCHAR(255) NOT NULL,
+    request_hash VARCHAR(64) NOT NULL,
+    response_status INT DEFAULT 0,
+    response_body TEXT,
+    expires_at TIMESTAMPTZ NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (caller, "key")
+);
+
+-- Create indexes for better performance
+CREATE INDEX idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
+
+-- +migrate Down
+DROP TABLE IF EXISTS idempotency_keys;
+
+-- Restore the unscoped idempotency_keys table
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    "key" VARCHAR(255) PRIMARY KEY,
+    request_hash VARCHAR(64) NOT NULL,
+    response_status INT DEFAULT 0,
+    response_body TEXT,
+    expires_at TIMESTAMPTZ NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
@@ -0,0 +1,22 @@
+-- Migration: 014_point_lot_consumptions.sql
+-- Description: Points each debit took from each lot, returned to the same lots on refund
+
+-- +migrate Up
+-- Create point_lot_consumptions table
+CREATE TABLE IF NOT EXISTS point_lot_consumptions (
+    id UUID PRIMARY KEY,
+    ledger_entry_id UUID NOT NULL,
+    lot_id UUID NOT NULL,
+    points INT NOT NULL,
+    restored_points INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (ledger_entry_id) REFERENCES point_ledger(id),
+    FOREIGN KEY (lot_id) REFERENCES point_lots(id) ON DELETE CASCADE
+);
+
+-- Create indexes for better performance
+CREATE INDEX idx_point_lot_consumptions_ledger_entry_id ON point_lot_consumptions(ledger_entry_id);
+
+-- +migrate Down
+DROP TABLE IF EXISTS point_lot_consumptions;
@@ -0,0 +1,29 @@
+-- Migration: 003_transaction_refunds.sql
+-- Description: Track refunded quantities and refund records for cancelled redemptions
+
+-- +migrate Up
+-- Track refunded amounts on existing tables
+ALTER TABLE transactions ADD COLUMN refunded_points INT DEFAULT 0;
+ALTER TABLE transaction_items ADD COLUMN refunded_quantity INT DEFAULT 0;
+
+-- Create refunds table
+CREATE TABLE IF NOT EXISTS refunds (
+    id CHAR(36) PRIMARY KEY,
+    transaction_id CHAR(36) NOT NULL,
+    transaction_item_id CHAR(36) NOT NULL,
+    quantity INT NOT NULL,
+    points INT NOT NULL,
+    reason VARCHAR(500) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (transaction_id) REFERENCES transactions(id) ON DELETE CASCADE,
+    FOREIGN KEY (transaction_item_id) REFERENCES transaction_items(id) ON DELETE CASCADE
+);
+
+-- Create indexes for better performance
+CREATE INDEX idx_refunds_transaction_id ON refunds(transaction_id);
+
+-- +migrate Down
+DROP TABLE IF EXISTS refunds;
+ALTER TABLE transaction_items DROP COLUMN refunded_quantity;
+ALTER TABLE transactions DROP COLUMN refunded_points;
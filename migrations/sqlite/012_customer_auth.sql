@@ -0,0 +1,37 @@
+-- Migration: 012_customer_auth.sql
+-- Description: Customer passwords, one-time login codes and refresh tokens
+
+-- +migrate Up
+-- Add password hash to customers
+ALTER TABLE customers ADD COLUMN password_hash VARCHAR(255) NULL;
+
+-- Create customer_otps table
+CREATE TABLE IF NOT EXISTS customer_otps (
+    id CHAR(36) PRIMARY KEY,
+    customer_id CHAR(36) NOT NULL,
+    code_hash VARCHAR(64) NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP NOT NULL,
+    consumed_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (customer_id) REFERENCES customers(id) ON DELETE CASCADE
+);
+
+-- Create refresh_tokens table
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id CHAR(36) PRIMARY KEY,
+    customer_id CHAR(36) NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (customer_id) REFERENCES customers(id) ON DELETE CASCADE
+);
+
+-- Create indexes for better performance
+CREATE INDEX idx_customer_otps_customer_id ON customer_otps(customer_id);
+CREATE INDEX idx_refresh_tokens_customer_id ON refresh_tokens(customer_id);
+
+-- +migrate Down
+DROP TABLE IF EXISTS refresh_tokens;
+DROP TABLE IF EXISTS customer_otps;
+ALTER TABLE customers DROP COLUMN password_hash;
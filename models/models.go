@@ -1,6 +1,7 @@
 package models
 
 import (
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
@@ -9,67 +10,180 @@ import (
 
 // Brand represents a brand entity
 type Brand struct {
-	ID          uuid.UUID `json:"id" gorm:"type:char(36);primary_key"`
-	Name        string    `json:"name" gorm:"size:255;not null"`
-	Description string    `json:"description" gorm:"type:text"`
-	LogoURL     string    `json:"logo_url" gorm:"size:500"`
-	IsActive    bool      `json:"is_active" gorm:"default:true"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
-	Vouchers    []Voucher `json:"vouchers,omitempty" gorm:"foreignKey:BrandID"`
+	ID          uuid.UUID      `json:"id" gorm:"type:char(36);primary_key"`
+	Name        string         `json:"name" gorm:"size:255;not null"`
+	Description string         `json:"description" gorm:"type:text"`
+	LogoURL     string         `json:"logo_url" gorm:"size:500"`
+	IsActive    bool           `json:"is_active" gorm:"default:true"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+	Vouchers    []Voucher      `json:"vouchers,omitempty" gorm:"foreignKey:BrandID"`
 }
 
-// Voucher represents a voucher entity
+// Voucher limit periods. Windows are rolling, counted back from the time of redemption.
+const (
+	LimitPeriodDay   = "day"
+	LimitPeriodWeek  = "week"
+	LimitPeriodMonth = "month"
+)
+
+// limitPeriodWindows maps each limit period to its rolling window
+var limitPeriodWindows = map[string]time.Duration{
+	LimitPeriodDay:   24 * time.Hour,
+	LimitPeriodWeek:  7 * 24 * time.Hour,
+	LimitPeriodMonth: 30 * 24 * time.Hour,
+}
+
+// LimitPeriodWindow returns the rolling window for a limit period and whether the period is known
+func LimitPeriodWindow(period string) (time.Duration, bool) {
+	window, ok := limitPeriodWindows[period]
+	return window, ok
+}
+
+// Voucher represents a voucher entity. TotalStock and RemainingStock are nil
+// for vouchers with unlimited stock. MaxPerCustomer caps the units a customer
+// may ever redeem and MaxPerPeriod caps the units per LimitPeriod; nil means
+// no limit.
 type Voucher struct {
-	ID          uuid.UUID `json:"id" gorm:"type:char(36);primary_key"`
-	BrandID     uuid.UUID `json:"brand_id" gorm:"type:char(36);not null"`
-	Name        string    `json:"name" gorm:"size:255;not null"`
-	Description string    `json:"description" gorm:"type:text"`
-	CostInPoint int       `json:"cost_in_point" gorm:"not null"`
-	ValidFrom   time.Time `json:"valid_from"`
-	ValidTo     time.Time `json:"valid_to"`
-	IsActive    bool      `json:"is_active" gorm:"default:true"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
-	Brand       Brand     `json:"brand,omitempty" gorm:"foreignKey:BrandID"`
-}
-
-// Customer represents a customer entity
+	ID             uuid.UUID      `json:"id" gorm:"type:char(36);primary_key"`
+	BrandID        uuid.UUID      `json:"brand_id" gorm:"type:char(36);not null"`
+	Name           string         `json:"name" gorm:"size:255;not null"`
+	Description    string         `json:"description" gorm:"type:text"`
+	CostInPoint    int            `json:"cost_in_point" gorm:"not null"`
+	ValidFrom      time.Time      `json:"valid_from"`
+	ValidTo        time.Time      `json:"valid_to"`
+	IsActive       bool           `json:"is_active" gorm:"default:true"`
+	TotalStock     *int           `json:"total_stock"`
+	RemainingStock *int           `json:"remaining_stock"`
+	MaxPerCustomer *int           `json:"max_per_customer"`
+	MaxPerPeriod   *int           `json:"max_per_period"`
+	LimitPeriod    string         `json:"limit_period,omitempty" gorm:"size:20"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+	Brand          Brand          `json:"brand,omitempty" gorm:"foreignKey:BrandID"`
+}
+
+// Customer represents a customer entity. PasswordHash is the bcrypt hash of
+// the customer's login password, empty if they only log in with one-time codes.
 type Customer struct {
-	ID        uuid.UUID `json:"id" gorm:"type:char(36);primary_key"`
-	Name      string    `json:"name" gorm:"size:255;not null"`
-	Email     string    `json:"email" gorm:"size:255;unique;not null"`
-	Phone     string    `json:"phone" gorm:"size:20"`
-	Points    int       `json:"points" gorm:"default:0"`
-	IsActive  bool      `json:"is_active" gorm:"default:true"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID           uuid.UUID      `json:"id" gorm:"type:char(36);primary_key"`
+	Name         string         `json:"name" gorm:"size:255;not null"`
+	Email        string         `json:"email" gorm:"size:255;unique;not null"`
+	Phone        string         `json:"phone" gorm:"size:20"`
+	Points       int            `json:"points" gorm:"default:0"`
+	IsActive     bool           `json:"is_active" gorm:"default:true"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+	PasswordHash string         `json:"-" gorm:"size:255"`
+}
+
+// Transaction statuses
+const (
+	TransactionStatusPending           = "pending"
+	TransactionStatusCompleted         = "completed"
+	TransactionStatusPartiallyRefunded = "partially_refunded"
+	TransactionStatusRefunded          = "refunded"
+)
+
+// transactionTransitions lists the statuses each transaction status may move to
+var transactionTransitions = map[string][]string{
+	TransactionStatusPending:           {TransactionStatusCompleted},
+	TransactionStatusCompleted:         {TransactionStatusPartiallyRefunded, TransactionStatusRefunded},
+	TransactionStatusPartiallyRefunded: {TransactionStatusPartiallyRefunded, TransactionStatusRefunded},
 }
 
 // Transaction represents a redemption transaction
 type Transaction struct {
-	ID          uuid.UUID         `json:"id" gorm:"type:char(36);primary_key"`
-	CustomerID  uuid.UUID         `json:"customer_id" gorm:"type:char(36);not null"`
-	TotalPoints int               `json:"total_points" gorm:"not null"`
-	Status      string            `json:"status" gorm:"size:50;default:'pending'"`
-	CreatedAt   time.Time         `json:"created_at"`
-	UpdatedAt   time.Time         `json:"updated_at"`
-	Customer    Customer          `json:"customer,omitempty" gorm:"foreignKey:CustomerID"`
-	Items       []TransactionItem `json:"items,omitempty" gorm:"foreignKey:TransactionID"`
+	ID             uuid.UUID         `json:"id" gorm:"type:char(36);primary_key"`
+	CustomerID     uuid.UUID         `json:"customer_id" gorm:"type:char(36);not null"`
+	TotalPoints    int               `json:"total_points" gorm:"not null"`
+	RefundedPoints int               `json:"refunded_points" gorm:"default:0"`
+	Status         string            `json:"status" gorm:"size:50;default:'pending'"`
+	CreatedAt      time.Time         `json:"created_at"`
+	UpdatedAt      time.Time         `json:"updated_at"`
+	Customer       Customer          `json:"customer,omitempty" gorm:"foreignKey:CustomerID"`
+	Items          []TransactionItem `json:"items,omitempty" gorm:"foreignKey:TransactionID"`
+	Refunds        []Refund          `json:"refunds,omitempty" gorm:"foreignKey:TransactionID"`
+}
+
+// CanTransitionTo reports whether the transaction may move to the given status
+func (transaction *Transaction) CanTransitionTo(status string) bool {
+	for _, next := range transactionTransitions[transaction.Status] {
+		if next == status {
+			return true
+		}
+	}
+	return false
 }
 
 // TransactionItem represents individual voucher items in a transaction
 type TransactionItem struct {
-	ID            uuid.UUID   `json:"id" gorm:"type:char(36);primary_key"`
-	TransactionID uuid.UUID   `json:"transaction_id" gorm:"type:char(36);not null"`
-	VoucherID     uuid.UUID   `json:"voucher_id" gorm:"type:char(36);not null"`
-	Quantity      int         `json:"quantity" gorm:"not null"`
-	PointsPerUnit int         `json:"points_per_unit" gorm:"not null"`
-	TotalPoints   int         `json:"total_points" gorm:"not null"`
-	CreatedAt     time.Time   `json:"created_at"`
-	UpdatedAt     time.Time   `json:"updated_at"`
-	Transaction   Transaction `json:"transaction,omitempty" gorm:"foreignKey:TransactionID"`
-	Voucher       Voucher     `json:"voucher,omitempty" gorm:"foreignKey:VoucherID"`
+	ID               uuid.UUID       `json:"id" gorm:"type:char(36);primary_key"`
+	TransactionID    uuid.UUID       `json:"transaction_id" gorm:"type:char(36);not null"`
+	VoucherID        uuid.UUID       `json:"voucher_id" gorm:"type:char(36);not null"`
+	Quantity         int             `json:"quantity" gorm:"not null"`
+	RefundedQuantity int             `json:"refunded_quantity" gorm:"default:0"`
+	PointsPerUnit    int             `json:"points_per_unit" gorm:"not null"`
+	TotalPoints      int             `json:"total_points" gorm:"not null"`
+	CreatedAt        time.Time       `json:"created_at"`
+	UpdatedAt        time.Time       `json:"updated_at"`
+	Transaction      Transaction     `json:"transaction,omitempty" gorm:"foreignKey:TransactionID"`
+	Voucher          Voucher         `json:"voucher,omitempty" gorm:"foreignKey:VoucherID"`
+	IssuedVouchers   []IssuedVoucher `json:"issued_vouchers,omitempty" gorm:"foreignKey:TransactionItemID"`
+}
+
+// RemainingQuantity returns the number of units that have not been refunded
+func (item *TransactionItem) RemainingQuantity() int {
+	return item.Quantity - item.RefundedQuantity
+}
+
+// Issued voucher statuses
+const (
+	IssuedVoucherStatusIssued  = "issued"
+	IssuedVoucherStatusUsed    = "used"
+	IssuedVoucherStatusExpired = "expired"
+	IssuedVoucherStatusVoid    = "void"
+)
+
+// IssuedVoucher is a single redeemable voucher code minted for one unit of a
+// redeemed transaction item
+type IssuedVoucher struct {
+	ID                uuid.UUID  `json:"id" gorm:"type:char(36);primary_key"`
+	Code              string     `json:"code" gorm:"size:32;uniqueIndex;not null"`
+	TransactionItemID uuid.UUID  `json:"transaction_item_id" gorm:"type:char(36);not null;index"`
+	VoucherID         uuid.UUID  `json:"voucher_id" gorm:"type:char(36);not null"`
+	CustomerID        uuid.UUID  `json:"customer_id" gorm:"type:char(36);not null;index"`
+	Status            string     `json:"status" gorm:"size:20;not null;default:'issued'"`
+	ExpiresAt         *time.Time `json:"expires_at,omitempty"`
+	UsedAt            *time.Time `json:"used_at,omitempty"`
+	StoreID           string     `json:"store_id,omitempty" gorm:"size:100"`
+	TerminalID        string     `json:"terminal_id,omitempty" gorm:"size:100"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+	Voucher           *Voucher   `json:"voucher,omitempty" gorm:"foreignKey:VoucherID"`
+}
+
+// AfterFind reports issued codes past their expiry as expired
+func (issued *IssuedVoucher) AfterFind(tx *gorm.DB) error {
+	if issued.Status == IssuedVoucherStatusIssued && issued.ExpiresAt != nil && time.Now().After(*issued.ExpiresAt) {
+		issued.Status = IssuedVoucherStatusExpired
+	}
+	return nil
+}
+
+// Refund records points returned to a customer for cancelled transaction items
+type Refund struct {
+	ID                uuid.UUID `json:"id" gorm:"type:char(36);primary_key"`
+	TransactionID     uuid.UUID `json:"transaction_id" gorm:"type:char(36);not null;index"`
+	TransactionItemID uuid.UUID `json:"transaction_item_id" gorm:"type:char(36);not null"`
+	Quantity          int       `json:"quantity" gorm:"not null"`
+	Points            int       `json:"points" gorm:"not null"`
+	Reason            string    `json:"reason" gorm:"size:500;not null"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
 }
 
 // BeforeCreate will set a UUID rather than numeric ID
@@ -107,3 +221,188 @@ func (item *TransactionItem) BeforeCreate(tx *gorm.DB) error {
 	}
 	return nil
 }
+
+func (issued *IssuedVoucher) BeforeCreate(tx *gorm.DB) error {
+	if issued.ID == uuid.Nil {
+		issued.ID = uuid.New()
+	}
+	return nil
+}
+
+func (refund *Refund) BeforeCreate(tx *gorm.DB) error {
+	if refund.ID == uuid.Nil {
+		refund.ID = uuid.New()
+	}
+	return nil
+}
+
+// Point ledger entry types
+const (
+	LedgerEntryAdjustment = "adjustment"
+	LedgerEntryRedemption = "redemption"
+	LedgerEntryRefund     = "refund"
+	LedgerEntryExpiry     = "expiry"
+)
+
+// ErrImmutableLedgerEntry is returned when code tries to change a posted ledger entry
+var ErrImmutableLedgerEntry = errors.New("point ledger entries are immutable")
+
+// PointLedgerEntry is an immutable credit (positive amount) or debit (negative
+// amount) against a customer's point balance. Customer.Points caches the sum
+// of a customer's entries.
+type PointLedgerEntry struct {
+	ID           uuid.UUID  `json:"id" gorm:"type:char(36);primary_key"`
+	CustomerID   uuid.UUID  `json:"customer_id" gorm:"type:char(36);not null;index"`
+	EntryType    string     `json:"entry_type" gorm:"size:50;not null"`
+	Amount       int        `json:"amount" gorm:"not null"`
+	BalanceAfter int        `json:"balance_after" gorm:"not null"`
+	Reason       string     `json:"reason" gorm:"size:500"`
+	Actor        string     `json:"actor" gorm:"size:255;not null"`
+	ReferenceID  *uuid.UUID `json:"reference_id,omitempty" gorm:"type:char(36)"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// TableName overrides the default pluralised table name
+func (PointLedgerEntry) TableName() string {
+	return "point_ledger"
+}
+
+// PointLot is a batch of earned points that expires as a unit. Debits consume
+// the lots that expire soonest first.
+type PointLot struct {
+	ID              uuid.UUID  `json:"id" gorm:"type:char(36);primary_key"`
+	CustomerID      uuid.UUID  `json:"customer_id" gorm:"type:char(36);not null;index"`
+	LedgerEntryID   uuid.UUID  `json:"ledger_entry_id" gorm:"type:char(36);not null"`
+	Points          int        `json:"points" gorm:"not null"`
+	RemainingPoints int        `json:"remaining_points" gorm:"not null"`
+	EarnedAt        time.Time  `json:"earned_at" gorm:"not null"`
+	ExpiresAt       time.Time  `json:"expires_at" gorm:"not null;index"`
+	ExpiredAt       *time.Time `json:"expired_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// PointLotConsumption records how many points a debit took from a lot, so that
+// refunding the debit can return the points to the same lot
+type PointLotConsumption struct {
+	ID             uuid.UUID `json:"id" gorm:"type:char(36);primary_key"`
+	LedgerEntryID  uuid.UUID `json:"ledger_entry_id" gorm:"type:char(36);not null;index"`
+	LotID          uuid.UUID `json:"lot_id" gorm:"type:char(36);not null"`
+	Points         int       `json:"points" gorm:"not null"`
+	RestoredPoints int       `json:"restored_points" gorm:"not null;default:0"`
+	Lot            PointLot  `json:"lot" gorm:"foreignKey:LotID"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// IdempotencyKey stores the outcome of a request made with an Idempotency-Key
+// header so that retries can be answered without re-running the handler. Keys
+// are scoped to the Caller that sent them.
+type IdempotencyKey struct {
+	Caller         string    `json:"caller" gorm:"size:255;primary_key"`
+	Key            string    `json:"key" gorm:"size:255;primary_key"`
+	RequestHash    string    `json:"request_hash" gorm:"size:64;not null"`
+	ResponseStatus int       `json:"response_status" gorm:"default:0"`
+	ResponseBody   string    `json:"response_body" gorm:"type:text"`
+	ExpiresAt      time.Time `json:"expires_at" gorm:"index"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+func (lot *PointLot) BeforeCreate(tx *gorm.DB) error {
+	if lot.ID == uuid.Nil {
+		lot.ID = uuid.New()
+	}
+	return nil
+}
+
+func (consumption *PointLotConsumption) BeforeCreate(tx *gorm.DB) error {
+	if consumption.ID == uuid.Nil {
+		consumption.ID = uuid.New()
+	}
+	return nil
+}
+
+func (entry *PointLedgerEntry) BeforeCreate(tx *gorm.DB) error {
+	if entry.ID == uuid.Nil {
+		entry.ID = uuid.New()
+	}
+	return nil
+}
+
+// BeforeUpdate rejects changes to posted entries
+func (entry *PointLedgerEntry) BeforeUpdate(tx *gorm.DB) error {
+	return ErrImmutableLedgerEntry
+}
+
+// BeforeDelete rejects removal of posted entries
+func (entry *PointLedgerEntry) BeforeDelete(tx *gorm.DB) error {
+	return ErrImmutableLedgerEntry
+}
+
+// API key roles
+const (
+	RoleAdmin         = "admin"
+	RoleBrandOperator = "brand_operator"
+	RoleCustomer      = "customer"
+)
+
+// APIKey authenticates a caller. Only the SHA-256 hash of the key is stored;
+// Prefix keeps the first characters so keys can be told apart in listings.
+// Brand operators are scoped to BrandID and customers to CustomerID.
+type APIKey struct {
+	ID         uuid.UUID  `json:"id" gorm:"type:char(36);primary_key"`
+	Name       string     `json:"name" gorm:"size:255;not null"`
+	Prefix     string     `json:"prefix" gorm:"size:16;not null"`
+	KeyHash    string     `json:"-" gorm:"size:64;not null;uniqueIndex"`
+	Role       string     `json:"role" gorm:"size:20;not null"`
+	BrandID    *uuid.UUID `json:"brand_id,omitempty" gorm:"type:char(36);index"`
+	CustomerID *uuid.UUID `json:"customer_id,omitempty" gorm:"type:char(36);index"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+func (key *APIKey) BeforeCreate(tx *gorm.DB) error {
+	if key.ID == uuid.Nil {
+		key.ID = uuid.New()
+	}
+	return nil
+}
+
+// CustomerOTP is a one-time login code sent to a customer. Only its hash is
+// stored, and it stops working after too many wrong attempts.
+type CustomerOTP struct {
+	ID         uuid.UUID  `json:"id" gorm:"type:char(36);primary_key"`
+	CustomerID uuid.UUID  `json:"customer_id" gorm:"type:char(36);not null;index"`
+	CodeHash   string     `json:"-" gorm:"size:64;not null"`
+	Attempts   int        `json:"attempts" gorm:"default:0"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	ConsumedAt *time.Time `json:"consumed_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// RefreshToken tracks an issued refresh token by its JWT ID so it can be
+// rotated on use and revoked on logout
+type RefreshToken struct {
+	ID         uuid.UUID  `json:"id" gorm:"type:char(36);primary_key"`
+	CustomerID uuid.UUID  `json:"customer_id" gorm:"type:char(36);not null;index"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func (otp *CustomerOTP) BeforeCreate(tx *gorm.DB) error {
+	if otp.ID == uuid.Nil {
+		otp.ID = uuid.New()
+	}
+	return nil
+}
+
+func (token *RefreshToken) BeforeCreate(tx *gorm.DB) error {
+	if token.ID == uuid.Nil {
+		token.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+// Package ratelimit implements token bucket rate limiting behind a Store
+// interface, so the in-memory buckets can be swapped for a shared store when
+// the service runs on several instances
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"my-backend-app/config"
+)
+
+// Result is the state of a bucket after a request took, or failed to take, a token
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// RetryAfter is the time until the next token, when the request was denied
+	RetryAfter time.Duration
+	// Reset is the time until the bucket is full again
+	Reset time.Duration
+}
+
+// Store keeps one token bucket per key. A bucket holds up to limit.Requests
+// tokens and refills at limit.Requests per limit.Period. Implementations must
+// be safe for concurrent use.
+type Store interface {
+	Take(ctx context.Context, key string, limit config.RateLimit, now time.Time) (Result, error)
+}
+
+// bucket is a token bucket as of updated
+type bucket struct {
+	tokens   float64
+	capacity float64
+	rate     float64 // tokens per second
+	updated  time.Time
+}
+
+// refill adds the tokens earned since the last update
+func (b *bucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.updated).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(b.capacity, b.tokens+elapsed*b.rate)
+		b.updated = now
+	}
+}
+
+// sweepInterval is how often the memory store drops full buckets
+const sweepInterval = time.Minute
+
+// MemoryStore keeps buckets in process memory. Each instance of the service
+// limits on its own, so the effective limit grows with the instance count.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]*bucket)}
+}
+
+// Take removes a token from the bucket of key if one is available
+func (s *MemoryStore) Take(ctx context.Context, key string, limit config.RateLimit, now time.Time) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep(now)
+
+	capacity := float64(limit.Requests)
+	rate := capacity / limit.Period.Seconds()
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, updated: now}
+		s.buckets[key] = b
+	}
+	// A changed limit applies from now on
+	b.capacity, b.rate = capacity, rate
+	b.refill(now)
+
+	result := Result{Limit: limit.Requests}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = seconds((1 - b.tokens) / rate)
+	}
+	result.Remaining = int(b.tokens)
+	result.Reset = seconds((capacity - b.tokens) / rate)
+	return result, nil
+}
+
+// sweep drops buckets that have refilled completely, since a new bucket
+// starts full anyway
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+	for key, b := range s.buckets {
+		b.refill(now)
+		if b.tokens >= b.capacity {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
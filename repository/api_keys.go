@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"my-backend-app/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type apiKeyRepository struct {
+	db *gorm.DB
+}
+
+func (r *apiKeyRepository) Create(ctx context.Context, key *models.APIKey) error {
+	return r.db.WithContext(ctx).Create(key).Error
+}
+
+func (r *apiKeyRepository) List(ctx context.Context, opts ListOptions) ([]models.APIKey, int64, error) {
+	var keys []models.APIKey
+	var total int64
+
+	db := r.db.WithContext(ctx)
+	if err := db.Model(&models.APIKey{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := db.Order("created_at DESC").Offset(opts.Offset).Limit(opts.Limit).Find(&keys).Error
+	return keys, total, err
+}
+
+func (r *apiKeyRepository) Revoke(ctx context.Context, id uuid.UUID, revokedAt time.Time) error {
+	return affected(r.db.WithContext(ctx).Model(&models.APIKey{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", revokedAt))
+}
+
+func (r *apiKeyRepository) GetByHash(ctx context.Context, hash string) (models.APIKey, error) {
+	var key models.APIKey
+	err := r.db.WithContext(ctx).Where("key_hash = ? AND revoked_at IS NULL", hash).First(&key).Error
+	return key, notFound(err)
+}
+
+func (r *apiKeyRepository) Touch(ctx context.Context, id uuid.UUID, usedAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.APIKey{}).
+		Where("id = ?", id).
+		UpdateColumn("last_used_at", usedAt).Error
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"my-backend-app/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type brandRepository struct {
+	db *gorm.DB
+}
+
+func (r *brandRepository) Create(ctx context.Context, brand *models.Brand) error {
+	return r.db.WithContext(ctx).Create(brand).Error
+}
+
+func (r *brandRepository) Get(ctx context.Context, id uuid.UUID, includeDeleted bool) (models.Brand, error) {
+	var brand models.Brand
+	err := withDeleted(r.db.WithContext(ctx), includeDeleted).First(&brand, "id = ?", id).Error
+	return brand, notFound(err)
+}
+
+func (r *brandRepository) GetDeleted(ctx context.Context, id uuid.UUID) (models.Brand, error) {
+	var brand models.Brand
+	err := r.db.WithContext(ctx).Unscoped().Where("deleted_at IS NOT NULL").First(&brand, "id = ?", id).Error
+	return brand, notFound(err)
+}
+
+func (r *brandRepository) List(ctx context.Context, opts ListOptions) ([]models.Brand, int64, error) {
+	var brands []models.Brand
+	var total int64
+
+	db := withDeleted(r.db.WithContext(ctx), opts.IncludeDeleted)
+	if err := db.Model(&models.Brand{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := db.Offset(opts.Offset).Limit(opts.Limit).Find(&brands).Error
+	return brands, total, err
+}
+
+func (r *brandRepository) Update(ctx context.Context, brand *models.Brand) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Save(brand).Error
+}
+
+func (r *brandRepository) SoftDelete(ctx context.Context, id uuid.UUID, deletedAt time.Time) error {
+	return affected(r.db.WithContext(ctx).Model(&models.Brand{}).Where("id = ?", id).Update("deleted_at", deletedAt))
+}
+
+func (r *brandRepository) Restore(ctx context.Context, id uuid.UUID) error {
+	return affected(r.db.WithContext(ctx).Unscoped().Model(&models.Brand{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Update("deleted_at", nil))
+}
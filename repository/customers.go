@@ -0,0 +1,180 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"my-backend-app/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// lotOrder is the order lots are consumed in: soonest expiry first
+const lotOrder = "expires_at ASC, earned_at ASC, id ASC"
+
+type customerRepository struct {
+	db *gorm.DB
+}
+
+func (r *customerRepository) Create(ctx context.Context, customer *models.Customer) error {
+	return r.db.WithContext(ctx).Create(customer).Error
+}
+
+func (r *customerRepository) Get(ctx context.Context, id uuid.UUID, includeDeleted bool) (models.Customer, error) {
+	var customer models.Customer
+	err := withDeleted(r.db.WithContext(ctx), includeDeleted).First(&customer, "id = ?", id).Error
+	return customer, notFound(err)
+}
+
+func (r *customerRepository) GetForUpdate(ctx context.Context, id uuid.UUID, includeDeleted bool) (models.Customer, error) {
+	var customer models.Customer
+	err := withDeleted(r.db.WithContext(ctx), includeDeleted).Clauses(forUpdate).First(&customer, "id = ?", id).Error
+	return customer, notFound(err)
+}
+
+func (r *customerRepository) GetByEmail(ctx context.Context, email string) (models.Customer, error) {
+	var customer models.Customer
+	err := r.db.WithContext(ctx).First(&customer, "email = ?", email).Error
+	return customer, notFound(err)
+}
+
+func (r *customerRepository) EmailTaken(ctx context.Context, email string, exceptID uuid.UUID) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Unscoped().Model(&models.Customer{}).
+		Where("email = ? AND id <> ?", email, exceptID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *customerRepository) List(ctx context.Context, opts ListOptions) ([]models.Customer, int64, error) {
+	var customers []models.Customer
+	var total int64
+
+	db := withDeleted(r.db.WithContext(ctx), opts.IncludeDeleted)
+	if err := db.Model(&models.Customer{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := db.Offset(opts.Offset).Limit(opts.Limit).Find(&customers).Error
+	return customers, total, err
+}
+
+func (r *customerRepository) UpdateProfile(ctx context.Context, customer *models.Customer) error {
+	return r.db.WithContext(ctx).Model(customer).Select("name", "email", "phone", "is_active").Updates(customer).Error
+}
+
+func (r *customerRepository) SetPasswordHash(ctx context.Context, id uuid.UUID, hash string) error {
+	return affected(r.db.WithContext(ctx).Model(&models.Customer{}).Where("id = ?", id).UpdateColumn("password_hash", hash))
+}
+
+func (r *customerRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return affected(r.db.WithContext(ctx).Delete(&models.Customer{}, "id = ?", id))
+}
+
+func (r *customerRepository) Restore(ctx context.Context, id uuid.UUID) error {
+	return affected(r.db.WithContext(ctx).Unscoped().Model(&models.Customer{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Update("deleted_at", nil))
+}
+
+// AddPoints guards debits in the update itself rather than relying on a row
+// lock, which keeps it safe on databases that ignore row locks (SQLite)
+func (r *customerRepository) AddPoints(ctx context.Context, id uuid.UUID, amount int) (int, error) {
+	db := r.db.WithContext(ctx)
+
+	// Refunds and expiry still apply to customers that were soft deleted
+	query := db.Unscoped().Model(&models.Customer{}).Where("id = ?", id)
+	if amount < 0 {
+		query = query.Where("points >= ?", -amount)
+	}
+
+	result := query.Update("points", gorm.Expr("points + ?", amount))
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if result.RowsAffected == 0 {
+		if amount < 0 {
+			return 0, ErrInsufficientPoints
+		}
+		return 0, ErrNotFound
+	}
+
+	var balance int
+	err := db.Unscoped().Model(&models.Customer{}).Select("points").Where("id = ?", id).Scan(&balance).Error
+	return balance, err
+}
+
+func (r *customerRepository) CreateLedgerEntry(ctx context.Context, entry *models.PointLedgerEntry) error {
+	return r.db.WithContext(ctx).Create(entry).Error
+}
+
+func (r *customerRepository) LedgerEntries(ctx context.Context, customerID uuid.UUID, opts ListOptions) ([]models.PointLedgerEntry, int64, error) {
+	var entries []models.PointLedgerEntry
+	var total int64
+
+	db := r.db.WithContext(ctx)
+	if err := db.Model(&models.PointLedgerEntry{}).Where("customer_id = ?", customerID).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := db.Where("customer_id = ?", customerID).Order("created_at DESC").Offset(opts.Offset).Limit(opts.Limit).Find(&entries).Error
+	return entries, total, err
+}
+
+func (r *customerRepository) CreateLot(ctx context.Context, lot *models.PointLot) error {
+	return r.db.WithContext(ctx).Create(lot).Error
+}
+
+func (r *customerRepository) OpenLots(ctx context.Context, customerID uuid.UUID, after, until time.Time) ([]models.PointLot, error) {
+	query := r.db.WithContext(ctx).Where("customer_id = ? AND remaining_points > 0", customerID)
+	if !after.IsZero() {
+		query = query.Where("expires_at > ?", after)
+	}
+	if !until.IsZero() {
+		query = query.Where("expires_at <= ?", until)
+	}
+
+	var lots []models.PointLot
+	err := query.Order(lotOrder).Find(&lots).Error
+	return lots, err
+}
+
+func (r *customerRepository) UpdateLot(ctx context.Context, lot *models.PointLot) error {
+	return r.db.WithContext(ctx).Model(lot).Select("remaining_points", "expired_at").Updates(lot).Error
+}
+
+func (r *customerRepository) CreateLotConsumption(ctx context.Context, consumption *models.PointLotConsumption) error {
+	return r.db.WithContext(ctx).Omit("Lot").Create(consumption).Error
+}
+
+func (r *customerRepository) DebitConsumptions(ctx context.Context, customerID, referenceID uuid.UUID) ([]models.PointLotConsumption, error) {
+	var consumptions []models.PointLotConsumption
+	err := r.db.WithContext(ctx).Preload("Lot").
+		Joins("JOIN point_ledger ON point_ledger.id = point_lot_consumptions.ledger_entry_id").
+		Where("point_ledger.customer_id = ? AND point_ledger.reference_id = ? AND point_ledger.amount < 0", customerID, referenceID).
+		Find(&consumptions).Error
+	return consumptions, err
+}
+
+func (r *customerRepository) UpdateLotConsumption(ctx context.Context, consumption *models.PointLotConsumption) error {
+	return r.db.WithContext(ctx).Model(consumption).Update("restored_points", consumption.RestoredPoints).Error
+}
+
+func (r *customerRepository) CustomersWithExpiredLots(ctx context.Context, now time.Time) ([]uuid.UUID, error) {
+	var customerIDs []uuid.UUID
+	err := r.db.WithContext(ctx).Model(&models.PointLot{}).
+		Where("remaining_points > 0 AND expires_at <= ?", now).
+		Distinct().
+		Pluck("customer_id", &customerIDs).Error
+	return customerIDs, err
+}
+
+func (r *customerRepository) BalanceMismatches(ctx context.Context) ([]BalanceMismatch, error) {
+	var mismatches []BalanceMismatch
+	err := r.db.WithContext(ctx).Table("customers").
+		Select("customers.id AS customer_id, customers.points AS cached_balance, COALESCE(SUM(point_ledger.amount), 0) AS ledger_balance").
+		Joins("LEFT JOIN point_ledger ON point_ledger.customer_id = customers.id").
+		Group("customers.id, customers.points").
+		Having("customers.points <> COALESCE(SUM(point_ledger.amount), 0)").
+		Scan(&mismatches).Error
+	return mismatches, err
+}
@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"my-backend-app/models"
+
+	"gorm.io/gorm"
+)
+
+type idempotencyRepository struct {
+	db *gorm.DB
+}
+
+// keyOf selects one key of a caller. The conditions are a map so GORM quotes
+// the key column, which is a reserved word in MySQL.
+func keyOf(caller, key string) map[string]interface{} {
+	return map[string]interface{}{"caller": caller, "key": key}
+}
+
+func (r *idempotencyRepository) Get(ctx context.Context, caller, key string) (models.IdempotencyKey, error) {
+	var record models.IdempotencyKey
+	err := r.db.WithContext(ctx).Where(keyOf(caller, key)).First(&record).Error
+	return record, notFound(err)
+}
+
+func (r *idempotencyRepository) Claim(ctx context.Context, record *models.IdempotencyKey) error {
+	return r.db.WithContext(ctx).Create(record).Error
+}
+
+func (r *idempotencyRepository) SaveResponse(ctx context.Context, caller, key string, status int, body string) error {
+	return affected(r.db.WithContext(ctx).Model(&models.IdempotencyKey{}).
+		Where(keyOf(caller, key)).
+		Updates(map[string]interface{}{"response_status": status, "response_body": body}))
+}
+
+func (r *idempotencyRepository) Delete(ctx context.Context, caller, key string) error {
+	return r.db.WithContext(ctx).Where(keyOf(caller, key)).Delete(&models.IdempotencyKey{}).Error
+}
+
+func (r *idempotencyRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Where("expires_at < ?", now).Delete(&models.IdempotencyKey{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,71 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"my-backend-app/models"
+	"my-backend-app/repository"
+
+	"github.com/google/uuid"
+)
+
+type apiKeyRepository struct {
+	store *Store
+}
+
+func (r *apiKeyRepository) Create(ctx context.Context, key *models.APIKey) error {
+	defer r.store.lock()()
+
+	now := time.Now()
+	key.ID = newID(key.ID)
+	key.CreatedAt, key.UpdatedAt = now, now
+	r.store.data.apiKeys = append(r.store.data.apiKeys, *key)
+	return nil
+}
+
+func (r *apiKeyRepository) List(ctx context.Context, opts repository.ListOptions) ([]models.APIKey, int64, error) {
+	defer r.store.lock()()
+
+	// Keys are appended in order, so walking backwards lists newest first
+	var keys []models.APIKey
+	for i := len(r.store.data.apiKeys) - 1; i >= 0; i-- {
+		keys = append(keys, r.store.data.apiKeys[i])
+	}
+	return page(keys, opts), int64(len(keys)), nil
+}
+
+func (r *apiKeyRepository) Revoke(ctx context.Context, id uuid.UUID, revokedAt time.Time) error {
+	defer r.store.lock()()
+
+	for i := range r.store.data.apiKeys {
+		if key := &r.store.data.apiKeys[i]; key.ID == id && key.RevokedAt == nil {
+			key.RevokedAt = &revokedAt
+			return nil
+		}
+	}
+	return repository.ErrNotFound
+}
+
+func (r *apiKeyRepository) GetByHash(ctx context.Context, hash string) (models.APIKey, error) {
+	defer r.store.lock()()
+
+	for _, key := range r.store.data.apiKeys {
+		if key.KeyHash == hash && key.RevokedAt == nil {
+			return key, nil
+		}
+	}
+	return models.APIKey{}, repository.ErrNotFound
+}
+
+func (r *apiKeyRepository) Touch(ctx context.Context, id uuid.UUID, usedAt time.Time) error {
+	defer r.store.lock()()
+
+	for i := range r.store.data.apiKeys {
+		if key := &r.store.data.apiKeys[i]; key.ID == id {
+			key.LastUsedAt = &usedAt
+			return nil
+		}
+	}
+	return nil
+}
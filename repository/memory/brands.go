@@ -0,0 +1,108 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"my-backend-app/models"
+	"my-backend-app/repository"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type brandRepository struct {
+	store *Store
+}
+
+// find returns the index of the brand, or -1
+func (r *brandRepository) find(id uuid.UUID) int {
+	for i, brand := range r.store.data.brands {
+		if brand.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (r *brandRepository) Create(ctx context.Context, brand *models.Brand) error {
+	defer r.store.lock()()
+
+	now := time.Now()
+	brand.ID = newID(brand.ID)
+	brand.CreatedAt, brand.UpdatedAt = now, now
+
+	row := *brand
+	row.Vouchers = nil
+	r.store.data.brands = append(r.store.data.brands, row)
+	return nil
+}
+
+func (r *brandRepository) Get(ctx context.Context, id uuid.UUID, includeDeleted bool) (models.Brand, error) {
+	defer r.store.lock()()
+
+	i := r.find(id)
+	if i < 0 || !visible(r.store.data.brands[i].DeletedAt, includeDeleted) {
+		return models.Brand{}, repository.ErrNotFound
+	}
+	return r.store.data.brands[i], nil
+}
+
+func (r *brandRepository) GetDeleted(ctx context.Context, id uuid.UUID) (models.Brand, error) {
+	defer r.store.lock()()
+
+	i := r.find(id)
+	if i < 0 || !r.store.data.brands[i].DeletedAt.Valid {
+		return models.Brand{}, repository.ErrNotFound
+	}
+	return r.store.data.brands[i], nil
+}
+
+func (r *brandRepository) List(ctx context.Context, opts repository.ListOptions) ([]models.Brand, int64, error) {
+	defer r.store.lock()()
+
+	var brands []models.Brand
+	for _, brand := range r.store.data.brands {
+		if visible(brand.DeletedAt, opts.IncludeDeleted) {
+			brands = append(brands, brand)
+		}
+	}
+	return page(brands, opts), int64(len(brands)), nil
+}
+
+func (r *brandRepository) Update(ctx context.Context, brand *models.Brand) error {
+	defer r.store.lock()()
+
+	i := r.find(brand.ID)
+	if i < 0 {
+		return repository.ErrNotFound
+	}
+	brand.UpdatedAt = time.Now()
+
+	row := *brand
+	row.Vouchers = nil
+	r.store.data.brands[i] = row
+	return nil
+}
+
+func (r *brandRepository) SoftDelete(ctx context.Context, id uuid.UUID, deletedAt time.Time) error {
+	defer r.store.lock()()
+
+	i := r.find(id)
+	if i < 0 || r.store.data.brands[i].DeletedAt.Valid {
+		return repository.ErrNotFound
+	}
+	r.store.data.brands[i].DeletedAt = deleted(deletedAt)
+	return nil
+}
+
+func (r *brandRepository) Restore(ctx context.Context, id uuid.UUID) error {
+	defer r.store.lock()()
+
+	i := r.find(id)
+	if i < 0 || !r.store.data.brands[i].DeletedAt.Valid {
+		return repository.ErrNotFound
+	}
+	r.store.data.brands[i].DeletedAt = gorm.DeletedAt{}
+	return nil
+}
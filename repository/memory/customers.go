@@ -0,0 +1,330 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"my-backend-app/models"
+	"my-backend-app/repository"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type customerRepository struct {
+	store *Store
+}
+
+// find returns the index of the customer, or -1
+func (r *customerRepository) find(id uuid.UUID) int {
+	for i, customer := range r.store.data.customers {
+		if customer.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// customer returns the customer, deleted or not
+func (t *tables) customer(id uuid.UUID) (models.Customer, bool) {
+	for _, customer := range t.customers {
+		if customer.ID == id {
+			return customer, true
+		}
+	}
+	return models.Customer{}, false
+}
+
+func (r *customerRepository) Create(ctx context.Context, customer *models.Customer) error {
+	defer r.store.lock()()
+
+	for _, existing := range r.store.data.customers {
+		if existing.Email == customer.Email {
+			return ErrDuplicateEmail
+		}
+	}
+
+	now := time.Now()
+	customer.ID = newID(customer.ID)
+	customer.CreatedAt, customer.UpdatedAt = now, now
+	r.store.data.customers = append(r.store.data.customers, *customer)
+	return nil
+}
+
+func (r *customerRepository) Get(ctx context.Context, id uuid.UUID, includeDeleted bool) (models.Customer, error) {
+	defer r.store.lock()()
+
+	customer, ok := r.store.data.customer(id)
+	if !ok || !visible(customer.DeletedAt, includeDeleted) {
+		return models.Customer{}, repository.ErrNotFound
+	}
+	return customer, nil
+}
+
+func (r *customerRepository) GetForUpdate(ctx context.Context, id uuid.UUID, includeDeleted bool) (models.Customer, error) {
+	return r.Get(ctx, id, includeDeleted)
+}
+
+func (r *customerRepository) GetByEmail(ctx context.Context, email string) (models.Customer, error) {
+	defer r.store.lock()()
+
+	for _, customer := range r.store.data.customers {
+		if customer.Email == email && !customer.DeletedAt.Valid {
+			return customer, nil
+		}
+	}
+	return models.Customer{}, repository.ErrNotFound
+}
+
+func (r *customerRepository) EmailTaken(ctx context.Context, email string, exceptID uuid.UUID) (bool, error) {
+	defer r.store.lock()()
+
+	for _, customer := range r.store.data.customers {
+		if customer.Email == email && customer.ID != exceptID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *customerRepository) List(ctx context.Context, opts repository.ListOptions) ([]models.Customer, int64, error) {
+	defer r.store.lock()()
+
+	var customers []models.Customer
+	for _, customer := range r.store.data.customers {
+		if visible(customer.DeletedAt, opts.IncludeDeleted) {
+			customers = append(customers, customer)
+		}
+	}
+	return page(customers, opts), int64(len(customers)), nil
+}
+
+func (r *customerRepository) UpdateProfile(ctx context.Context, customer *models.Customer) error {
+	defer r.store.lock()()
+
+	i := r.find(customer.ID)
+	if i < 0 {
+		return repository.ErrNotFound
+	}
+	for _, existing := range r.store.data.customers {
+		if existing.Email == customer.Email && existing.ID != customer.ID {
+			return ErrDuplicateEmail
+		}
+	}
+
+	row := &r.store.data.customers[i]
+	row.Name = customer.Name
+	row.Email = customer.Email
+	row.Phone = customer.Phone
+	row.IsActive = customer.IsActive
+	row.UpdatedAt = time.Now()
+	customer.UpdatedAt = row.UpdatedAt
+	return nil
+}
+
+func (r *customerRepository) SetPasswordHash(ctx context.Context, id uuid.UUID, hash string) error {
+	defer r.store.lock()()
+
+	i := r.find(id)
+	if i < 0 || r.store.data.customers[i].DeletedAt.Valid {
+		return repository.ErrNotFound
+	}
+	r.store.data.customers[i].PasswordHash = hash
+	return nil
+}
+
+func (r *customerRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	defer r.store.lock()()
+
+	i := r.find(id)
+	if i < 0 || r.store.data.customers[i].DeletedAt.Valid {
+		return repository.ErrNotFound
+	}
+	r.store.data.customers[i].DeletedAt = deleted(time.Now())
+	return nil
+}
+
+func (r *customerRepository) Restore(ctx context.Context, id uuid.UUID) error {
+	defer r.store.lock()()
+
+	i := r.find(id)
+	if i < 0 || !r.store.data.customers[i].DeletedAt.Valid {
+		return repository.ErrNotFound
+	}
+	r.store.data.customers[i].DeletedAt = gorm.DeletedAt{}
+	return nil
+}
+
+func (r *customerRepository) AddPoints(ctx context.Context, id uuid.UUID, amount int) (int, error) {
+	defer r.store.lock()()
+
+	i := r.find(id)
+	if i < 0 {
+		return 0, repository.ErrNotFound
+	}
+	customer := &r.store.data.customers[i]
+	if amount < 0 && customer.Points < -amount {
+		return 0, repository.ErrInsufficientPoints
+	}
+	customer.Points += amount
+	return customer.Points, nil
+}
+
+func (r *customerRepository) CreateLedgerEntry(ctx context.Context, entry *models.PointLedgerEntry) error {
+	defer r.store.lock()()
+
+	entry.ID = newID(entry.ID)
+	entry.CreatedAt = time.Now()
+	r.store.data.ledger = append(r.store.data.ledger, *entry)
+	return nil
+}
+
+func (r *customerRepository) LedgerEntries(ctx context.Context, customerID uuid.UUID, opts repository.ListOptions) ([]models.PointLedgerEntry, int64, error) {
+	defer r.store.lock()()
+
+	// Entries are appended in order, so walking backwards lists newest first
+	var entries []models.PointLedgerEntry
+	for i := len(r.store.data.ledger) - 1; i >= 0; i-- {
+		if entry := r.store.data.ledger[i]; entry.CustomerID == customerID {
+			entries = append(entries, entry)
+		}
+	}
+	return page(entries, opts), int64(len(entries)), nil
+}
+
+func (r *customerRepository) CreateLot(ctx context.Context, lot *models.PointLot) error {
+	defer r.store.lock()()
+
+	now := time.Now()
+	lot.ID = newID(lot.ID)
+	lot.CreatedAt, lot.UpdatedAt = now, now
+	r.store.data.lots = append(r.store.data.lots, *lot)
+	return nil
+}
+
+func (r *customerRepository) OpenLots(ctx context.Context, customerID uuid.UUID, after, until time.Time) ([]models.PointLot, error) {
+	defer r.store.lock()()
+
+	var lots []models.PointLot
+	for _, lot := range r.store.data.lots {
+		if lot.CustomerID != customerID || lot.RemainingPoints <= 0 {
+			continue
+		}
+		if !after.IsZero() && !lot.ExpiresAt.After(after) {
+			continue
+		}
+		if !until.IsZero() && lot.ExpiresAt.After(until) {
+			continue
+		}
+		lots = append(lots, lot)
+	}
+
+	// Soonest expiry first, like the lot order of the SQL repository
+	sort.SliceStable(lots, func(i, j int) bool {
+		if !lots[i].ExpiresAt.Equal(lots[j].ExpiresAt) {
+			return lots[i].ExpiresAt.Before(lots[j].ExpiresAt)
+		}
+		return lots[i].EarnedAt.Before(lots[j].EarnedAt)
+	})
+	return lots, nil
+}
+
+func (r *customerRepository) UpdateLot(ctx context.Context, lot *models.PointLot) error {
+	defer r.store.lock()()
+
+	for i := range r.store.data.lots {
+		if row := &r.store.data.lots[i]; row.ID == lot.ID {
+			row.RemainingPoints = lot.RemainingPoints
+			row.ExpiredAt = lot.ExpiredAt
+			row.UpdatedAt = time.Now()
+			return nil
+		}
+	}
+	return repository.ErrNotFound
+}
+
+func (r *customerRepository) CreateLotConsumption(ctx context.Context, consumption *models.PointLotConsumption) error {
+	defer r.store.lock()()
+
+	now := time.Now()
+	consumption.ID = newID(consumption.ID)
+	consumption.CreatedAt, consumption.UpdatedAt = now, now
+	row := *consumption
+	row.Lot = models.PointLot{}
+	r.store.data.consumptions = append(r.store.data.consumptions, row)
+	return nil
+}
+
+func (r *customerRepository) DebitConsumptions(ctx context.Context, customerID, referenceID uuid.UUID) ([]models.PointLotConsumption, error) {
+	defer r.store.lock()()
+
+	debits := make(map[uuid.UUID]bool)
+	for _, entry := range r.store.data.ledger {
+		if entry.CustomerID == customerID && entry.Amount < 0 && entry.ReferenceID != nil && *entry.ReferenceID == referenceID {
+			debits[entry.ID] = true
+		}
+	}
+
+	var consumptions []models.PointLotConsumption
+	for _, consumption := range r.store.data.consumptions {
+		if !debits[consumption.LedgerEntryID] {
+			continue
+		}
+		for _, lot := range r.store.data.lots {
+			if lot.ID == consumption.LotID {
+				consumption.Lot = lot
+			}
+		}
+		consumptions = append(consumptions, consumption)
+	}
+	return consumptions, nil
+}
+
+func (r *customerRepository) UpdateLotConsumption(ctx context.Context, consumption *models.PointLotConsumption) error {
+	defer r.store.lock()()
+
+	for i := range r.store.data.consumptions {
+		if row := &r.store.data.consumptions[i]; row.ID == consumption.ID {
+			row.RestoredPoints = consumption.RestoredPoints
+			row.UpdatedAt = time.Now()
+			return nil
+		}
+	}
+	return repository.ErrNotFound
+}
+
+func (r *customerRepository) CustomersWithExpiredLots(ctx context.Context, now time.Time) ([]uuid.UUID, error) {
+	defer r.store.lock()()
+
+	var customerIDs []uuid.UUID
+	seen := make(map[uuid.UUID]bool)
+	for _, lot := range r.store.data.lots {
+		if lot.RemainingPoints > 0 && !lot.ExpiresAt.After(now) && !seen[lot.CustomerID] {
+			seen[lot.CustomerID] = true
+			customerIDs = append(customerIDs, lot.CustomerID)
+		}
+	}
+	return customerIDs, nil
+}
+
+func (r *customerRepository) BalanceMismatches(ctx context.Context) ([]repository.BalanceMismatch, error) {
+	defer r.store.lock()()
+
+	balances := make(map[uuid.UUID]int)
+	for _, entry := range r.store.data.ledger {
+		balances[entry.CustomerID] += entry.Amount
+	}
+
+	var mismatches []repository.BalanceMismatch
+	for _, customer := range r.store.data.customers {
+		if customer.Points != balances[customer.ID] {
+			mismatches = append(mismatches, repository.BalanceMismatch{
+				CustomerID:    customer.ID,
+				CachedBalance: customer.Points,
+				LedgerBalance: balances[customer.ID],
+			})
+		}
+	}
+	return mismatches, nil
+}
@@ -0,0 +1,81 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"my-backend-app/models"
+	"my-backend-app/repository"
+)
+
+type idempotencyRepository struct {
+	store *Store
+}
+
+// find returns the index of a key of the caller, or -1
+func (r *idempotencyRepository) find(caller, key string) int {
+	for i, record := range r.store.data.idempotency {
+		if record.Caller == caller && record.Key == key {
+			return i
+		}
+	}
+	return -1
+}
+
+func (r *idempotencyRepository) Get(ctx context.Context, caller, key string) (models.IdempotencyKey, error) {
+	defer r.store.lock()()
+
+	if i := r.find(caller, key); i >= 0 {
+		return r.store.data.idempotency[i], nil
+	}
+	return models.IdempotencyKey{}, repository.ErrNotFound
+}
+
+func (r *idempotencyRepository) Claim(ctx context.Context, record *models.IdempotencyKey) error {
+	defer r.store.lock()()
+
+	if r.find(record.Caller, record.Key) >= 0 {
+		return ErrDuplicateIdempotencyKey
+	}
+	now := time.Now()
+	record.CreatedAt, record.UpdatedAt = now, now
+	r.store.data.idempotency = append(r.store.data.idempotency, *record)
+	return nil
+}
+
+func (r *idempotencyRepository) SaveResponse(ctx context.Context, caller, key string, status int, body string) error {
+	defer r.store.lock()()
+
+	i := r.find(caller, key)
+	if i < 0 {
+		return repository.ErrNotFound
+	}
+	record := &r.store.data.idempotency[i]
+	record.ResponseStatus, record.ResponseBody = status, body
+	record.UpdatedAt = time.Now()
+	return nil
+}
+
+func (r *idempotencyRepository) Delete(ctx context.Context, caller, key string) error {
+	defer r.store.lock()()
+
+	if i := r.find(caller, key); i >= 0 {
+		rows := r.store.data.idempotency
+		r.store.data.idempotency = append(rows[:i], rows[i+1:]...)
+	}
+	return nil
+}
+
+func (r *idempotencyRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	defer r.store.lock()()
+
+	var kept []models.IdempotencyKey
+	for _, record := range r.store.data.idempotency {
+		if !record.ExpiresAt.Before(now) {
+			kept = append(kept, record)
+		}
+	}
+	deleted := len(r.store.data.idempotency) - len(kept)
+	r.store.data.idempotency = kept
+	return int64(deleted), nil
+}
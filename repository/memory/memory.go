@@ -0,0 +1,166 @@
+// Package memory implements the repository interfaces in memory. It is meant
+// for tests: every Store is an isolated database, so tests using it can run
+// in parallel without a database server.
+package memory
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"my-backend-app/models"
+	"my-backend-app/repository"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrDuplicateEmail mirrors the unique index on customer emails
+	ErrDuplicateEmail = errors.New("duplicate customer email")
+	// ErrDuplicateCode mirrors the unique index on issued voucher codes
+	ErrDuplicateCode = errors.New("duplicate voucher code")
+	// ErrDuplicateIdempotencyKey mirrors the primary key of idempotency keys
+	ErrDuplicateIdempotencyKey = errors.New("duplicate idempotency key")
+)
+
+// tables holds the rows of every table. Rows are stored without their
+// associations, which are attached when they are read.
+type tables struct {
+	brands       []models.Brand
+	vouchers     []models.Voucher
+	customers    []models.Customer
+	transactions []models.Transaction
+	items        []models.TransactionItem
+	issued       []models.IssuedVoucher
+	refunds      []models.Refund
+	ledger       []models.PointLedgerEntry
+	lots         []models.PointLot
+	consumptions []models.PointLotConsumption
+	apiKeys      []models.APIKey
+	otps         []models.CustomerOTP
+	refresh      []models.RefreshToken
+	idempotency  []models.IdempotencyKey
+}
+
+// clone copies every table so a transaction can be discarded on rollback
+func (t *tables) clone() *tables {
+	return &tables{
+		brands:       append([]models.Brand(nil), t.brands...),
+		vouchers:     append([]models.Voucher(nil), t.vouchers...),
+		customers:    append([]models.Customer(nil), t.customers...),
+		transactions: append([]models.Transaction(nil), t.transactions...),
+		items:        append([]models.TransactionItem(nil), t.items...),
+		issued:       append([]models.IssuedVoucher(nil), t.issued...),
+		refunds:      append([]models.Refund(nil), t.refunds...),
+		ledger:       append([]models.PointLedgerEntry(nil), t.ledger...),
+		lots:         append([]models.PointLot(nil), t.lots...),
+		consumptions: append([]models.PointLotConsumption(nil), t.consumptions...),
+		apiKeys:      append([]models.APIKey(nil), t.apiKeys...),
+		otps:         append([]models.CustomerOTP(nil), t.otps...),
+		refresh:      append([]models.RefreshToken(nil), t.refresh...),
+		idempotency:  append([]models.IdempotencyKey(nil), t.idempotency...),
+	}
+}
+
+// Store is an in-memory repository.Store. Transactions run one at a time
+// against a copy of the data that replaces it on commit, so row locks are
+// implied and a failed transaction leaves nothing behind.
+type Store struct {
+	mu   *sync.Mutex
+	data *tables
+	inTx bool
+}
+
+// NewStore returns an empty in-memory store
+func NewStore() *Store {
+	return &Store{mu: &sync.Mutex{}, data: &tables{}}
+}
+
+func (s *Store) Brands() repository.BrandRepository {
+	return &brandRepository{store: s}
+}
+
+func (s *Store) Vouchers() repository.VoucherRepository {
+	return &voucherRepository{store: s}
+}
+
+func (s *Store) Customers() repository.CustomerRepository {
+	return &customerRepository{store: s}
+}
+
+func (s *Store) Transactions() repository.TransactionRepository {
+	return &transactionRepository{store: s}
+}
+
+func (s *Store) APIKeys() repository.APIKeyRepository {
+	return &apiKeyRepository{store: s}
+}
+
+func (s *Store) Tokens() repository.TokenRepository {
+	return &tokenRepository{store: s}
+}
+
+func (s *Store) Idempotency() repository.IdempotencyRepository {
+	return &idempotencyRepository{store: s}
+}
+
+func (s *Store) Atomic(ctx context.Context, fn func(tx repository.Store) error) error {
+	// Nested transactions join the outer one
+	if s.inTx {
+		return fn(s)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx := &Store{mu: s.mu, data: s.data.clone(), inTx: true}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	s.data = tx.data
+	return nil
+}
+
+// lock guards the data outside a transaction and returns the unlock function.
+// Inside one the transaction already holds the lock.
+func (s *Store) lock() func() {
+	if s.inTx {
+		return func() {}
+	}
+	s.mu.Lock()
+	return s.mu.Unlock
+}
+
+// page applies an offset and limit the way SQL does; a negative limit means no limit
+func page[T any](rows []T, opts repository.ListOptions) []T {
+	if opts.Offset > 0 {
+		if opts.Offset >= len(rows) {
+			return []T{}
+		}
+		rows = rows[opts.Offset:]
+	}
+	if opts.Limit >= 0 && opts.Limit < len(rows) {
+		rows = rows[:opts.Limit]
+	}
+	return rows
+}
+
+// visible reports whether a row with the given deletion time is listed
+func visible(deletedAt gorm.DeletedAt, includeDeleted bool) bool {
+	return includeDeleted || !deletedAt.Valid
+}
+
+// deleted returns a soft-delete timestamp for t
+func deleted(t time.Time) gorm.DeletedAt {
+	return gorm.DeletedAt{Time: t, Valid: true}
+}
+
+// newID returns id, or a new UUID when id is not set
+func newID(id uuid.UUID) uuid.UUID {
+	if id == uuid.Nil {
+		return uuid.New()
+	}
+	return id
+}
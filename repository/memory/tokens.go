@@ -0,0 +1,123 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"my-backend-app/models"
+	"my-backend-app/repository"
+
+	"github.com/google/uuid"
+)
+
+type tokenRepository struct {
+	store *Store
+}
+
+func (r *tokenRepository) CreateOTP(ctx context.Context, otp *models.CustomerOTP) error {
+	defer r.store.lock()()
+
+	otp.ID = newID(otp.ID)
+	otp.CreatedAt = time.Now()
+	r.store.data.otps = append(r.store.data.otps, *otp)
+	return nil
+}
+
+func (r *tokenRepository) ConsumeOTPs(ctx context.Context, customerID uuid.UUID, consumedAt time.Time) error {
+	defer r.store.lock()()
+
+	for i := range r.store.data.otps {
+		if otp := &r.store.data.otps[i]; otp.CustomerID == customerID && otp.ConsumedAt == nil {
+			otp.ConsumedAt = &consumedAt
+		}
+	}
+	return nil
+}
+
+func (r *tokenRepository) CurrentOTP(ctx context.Context, customerID uuid.UUID, now time.Time) (models.CustomerOTP, error) {
+	defer r.store.lock()()
+
+	// Codes are appended in order, so walking backwards finds the newest first
+	for i := len(r.store.data.otps) - 1; i >= 0; i-- {
+		otp := r.store.data.otps[i]
+		if otp.CustomerID == customerID && otp.ConsumedAt == nil && otp.ExpiresAt.After(now) {
+			return otp, nil
+		}
+	}
+	return models.CustomerOTP{}, repository.ErrNotFound
+}
+
+func (r *tokenRepository) UseOTPAttempt(ctx context.Context, id uuid.UUID, maxAttempts int) error {
+	defer r.store.lock()()
+
+	otp := r.otp(id)
+	if otp == nil || otp.ConsumedAt != nil || otp.Attempts >= maxAttempts {
+		return repository.ErrNotFound
+	}
+	otp.Attempts++
+	return nil
+}
+
+func (r *tokenRepository) ConsumeOTP(ctx context.Context, id uuid.UUID, consumedAt time.Time) error {
+	defer r.store.lock()()
+
+	otp := r.otp(id)
+	if otp == nil || otp.ConsumedAt != nil {
+		return repository.ErrNotFound
+	}
+	otp.ConsumedAt = &consumedAt
+	return nil
+}
+
+// otp returns the stored code, or nil
+func (r *tokenRepository) otp(id uuid.UUID) *models.CustomerOTP {
+	for i := range r.store.data.otps {
+		if r.store.data.otps[i].ID == id {
+			return &r.store.data.otps[i]
+		}
+	}
+	return nil
+}
+
+func (r *tokenRepository) CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error {
+	defer r.store.lock()()
+
+	token.ID = newID(token.ID)
+	token.CreatedAt = time.Now()
+	r.store.data.refresh = append(r.store.data.refresh, *token)
+	return nil
+}
+
+func (r *tokenRepository) GetRefreshToken(ctx context.Context, id, customerID uuid.UUID) (models.RefreshToken, error) {
+	defer r.store.lock()()
+
+	for _, token := range r.store.data.refresh {
+		if token.ID == id && token.CustomerID == customerID {
+			return token, nil
+		}
+	}
+	return models.RefreshToken{}, repository.ErrNotFound
+}
+
+func (r *tokenRepository) RevokeRefreshToken(ctx context.Context, id uuid.UUID, revokedAt time.Time) error {
+	defer r.store.lock()()
+
+	for i := range r.store.data.refresh {
+		if token := &r.store.data.refresh[i]; token.ID == id && token.RevokedAt == nil {
+			token.RevokedAt = &revokedAt
+			return nil
+		}
+	}
+	return repository.ErrNotFound
+}
+
+func (r *tokenRepository) RevokeRefreshTokens(ctx context.Context, customerID uuid.UUID, revokedAt time.Time) error {
+	defer r.store.lock()()
+
+	for i := range r.store.data.refresh {
+		if token := &r.store.data.refresh[i]; token.CustomerID == customerID && token.RevokedAt == nil {
+			token.RevokedAt = &revokedAt
+		}
+	}
+	return nil
+}
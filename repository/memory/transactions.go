@@ -0,0 +1,341 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"my-backend-app/models"
+	"my-backend-app/repository"
+
+	"github.com/google/uuid"
+)
+
+type transactionRepository struct {
+	store *Store
+}
+
+// find returns the index of the transaction, or -1
+func (r *transactionRepository) find(id uuid.UUID) int {
+	for i, transaction := range r.store.data.transactions {
+		if transaction.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// transactionItems returns the items of a transaction
+func (t *tables) transactionItems(transactionID uuid.UUID) []models.TransactionItem {
+	var items []models.TransactionItem
+	for _, item := range t.items {
+		if item.TransactionID == transactionID {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// issuedVoucher attaches the voucher to a code and reports codes past their
+// expiry as expired, as the model does when loaded from a database
+func (t *tables) issuedVoucher(issued models.IssuedVoucher, brand bool) models.IssuedVoucher {
+	if i := t.findVoucher(issued.VoucherID); i >= 0 {
+		voucher := t.vouchers[i]
+		if brand {
+			voucher = t.withBrand(voucher)
+		}
+		issued.Voucher = &voucher
+	}
+	issued.AfterFind(nil)
+	return issued
+}
+
+func (r *transactionRepository) Create(ctx context.Context, transaction *models.Transaction) error {
+	defer r.store.lock()()
+
+	now := time.Now()
+	transaction.ID = newID(transaction.ID)
+	transaction.CreatedAt, transaction.UpdatedAt = now, now
+
+	for i := range transaction.Items {
+		item := &transaction.Items[i]
+		item.ID = newID(item.ID)
+		item.TransactionID = transaction.ID
+		item.CreatedAt, item.UpdatedAt = now, now
+
+		row := *item
+		row.Transaction = models.Transaction{}
+		row.Voucher = models.Voucher{}
+		row.IssuedVouchers = nil
+		r.store.data.items = append(r.store.data.items, row)
+	}
+
+	row := *transaction
+	row.Customer = models.Customer{}
+	row.Items = nil
+	row.Refunds = nil
+	r.store.data.transactions = append(r.store.data.transactions, row)
+	return nil
+}
+
+func (r *transactionRepository) Get(ctx context.Context, id uuid.UUID) (models.Transaction, error) {
+	defer r.store.lock()()
+
+	i := r.find(id)
+	if i < 0 {
+		return models.Transaction{}, repository.ErrNotFound
+	}
+
+	data := r.store.data
+	transaction := data.transactions[i]
+	transaction.Customer, _ = data.customer(transaction.CustomerID)
+	transaction.Items = data.transactionItems(id)
+	for j := range transaction.Items {
+		item := &transaction.Items[j]
+		item.Voucher, _ = data.voucher(item.VoucherID)
+		for _, issued := range data.issued {
+			if issued.TransactionItemID == item.ID {
+				issued.AfterFind(nil)
+				item.IssuedVouchers = append(item.IssuedVouchers, issued)
+			}
+		}
+	}
+	for _, refund := range data.refunds {
+		if refund.TransactionID == id {
+			transaction.Refunds = append(transaction.Refunds, refund)
+		}
+	}
+	return transaction, nil
+}
+
+func (r *transactionRepository) GetForUpdate(ctx context.Context, id uuid.UUID) (models.Transaction, error) {
+	defer r.store.lock()()
+
+	i := r.find(id)
+	if i < 0 {
+		return models.Transaction{}, repository.ErrNotFound
+	}
+	transaction := r.store.data.transactions[i]
+	transaction.Items = r.store.data.transactionItems(id)
+	return transaction, nil
+}
+
+func (r *transactionRepository) ListByCustomer(ctx context.Context, customerID uuid.UUID) ([]models.Transaction, error) {
+	defer r.store.lock()()
+
+	var transactions []models.Transaction
+	for _, transaction := range r.store.data.transactions {
+		if transaction.CustomerID != customerID {
+			continue
+		}
+		transaction.Items = r.store.data.transactionItems(transaction.ID)
+		for j := range transaction.Items {
+			transaction.Items[j].Voucher, _ = r.store.data.voucher(transaction.Items[j].VoucherID)
+		}
+		transactions = append(transactions, transaction)
+	}
+	return transactions, nil
+}
+
+func (r *transactionRepository) UpdateStatus(ctx context.Context, id uuid.UUID, status string) error {
+	defer r.store.lock()()
+
+	i := r.find(id)
+	if i < 0 {
+		return repository.ErrNotFound
+	}
+	r.store.data.transactions[i].Status = status
+	r.store.data.transactions[i].UpdatedAt = time.Now()
+	return nil
+}
+
+func (r *transactionRepository) RecordRefund(ctx context.Context, transaction *models.Transaction, item *models.TransactionItem, refund *models.Refund) error {
+	defer r.store.lock()()
+
+	now := time.Now()
+	refund.ID = newID(refund.ID)
+	refund.CreatedAt, refund.UpdatedAt = now, now
+	r.store.data.refunds = append(r.store.data.refunds, *refund)
+
+	for i := range r.store.data.items {
+		if row := &r.store.data.items[i]; row.ID == item.ID {
+			row.RefundedQuantity = item.RefundedQuantity
+			row.UpdatedAt = now
+		}
+	}
+	if i := r.find(transaction.ID); i >= 0 {
+		r.store.data.transactions[i].RefundedPoints = transaction.RefundedPoints
+		r.store.data.transactions[i].UpdatedAt = now
+	}
+	return nil
+}
+
+func (r *transactionRepository) RedeemedUnits(ctx context.Context, voucherID uuid.UUID) (int, error) {
+	defer r.store.lock()()
+
+	redeemed := 0
+	for _, item := range r.store.data.items {
+		if item.VoucherID == voucherID {
+			redeemed += item.RemainingQuantity()
+		}
+	}
+	return redeemed, nil
+}
+
+func (r *transactionRepository) RedeemedByCustomer(ctx context.Context, customerID, voucherID uuid.UUID, since time.Time) (int, error) {
+	defer r.store.lock()()
+
+	customers := make(map[uuid.UUID]uuid.UUID, len(r.store.data.transactions))
+	for _, transaction := range r.store.data.transactions {
+		customers[transaction.ID] = transaction.CustomerID
+	}
+
+	redeemed := 0
+	for _, item := range r.store.data.items {
+		if item.VoucherID != voucherID || customers[item.TransactionID] != customerID {
+			continue
+		}
+		if !since.IsZero() && item.CreatedAt.Before(since) {
+			continue
+		}
+		redeemed += item.RemainingQuantity()
+	}
+	return redeemed, nil
+}
+
+func (r *transactionRepository) CreateIssuedVoucher(ctx context.Context, issued *models.IssuedVoucher) error {
+	defer r.store.lock()()
+
+	for _, existing := range r.store.data.issued {
+		if existing.Code == issued.Code {
+			return ErrDuplicateCode
+		}
+	}
+
+	now := time.Now()
+	issued.ID = newID(issued.ID)
+	issued.CreatedAt, issued.UpdatedAt = now, now
+
+	row := *issued
+	row.Voucher = nil
+	r.store.data.issued = append(r.store.data.issued, row)
+	return nil
+}
+
+func (r *transactionRepository) CountIssuedVouchers(ctx context.Context, itemID uuid.UUID) (int64, error) {
+	defer r.store.lock()()
+
+	var total int64
+	for _, issued := range r.store.data.issued {
+		if issued.TransactionItemID == itemID {
+			total++
+		}
+	}
+	return total, nil
+}
+
+func (r *transactionRepository) UnusedIssuedVouchers(ctx context.Context, itemID uuid.UUID, limit int) ([]uuid.UUID, error) {
+	defer r.store.lock()()
+
+	// Codes are appended in order, so walking forwards lists oldest first
+	var unused []uuid.UUID
+	for _, issued := range r.store.data.issued {
+		if len(unused) == limit {
+			break
+		}
+		if issued.TransactionItemID == itemID && issued.Status == models.IssuedVoucherStatusIssued {
+			unused = append(unused, issued.ID)
+		}
+	}
+	return unused, nil
+}
+
+func (r *transactionRepository) VoidIssuedVouchers(ctx context.Context, ids []uuid.UUID) error {
+	defer r.store.lock()()
+
+	void := make(map[uuid.UUID]bool, len(ids))
+	for _, id := range ids {
+		void[id] = true
+	}
+	for i := range r.store.data.issued {
+		if issued := &r.store.data.issued[i]; void[issued.ID] {
+			issued.Status = models.IssuedVoucherStatusVoid
+			issued.UpdatedAt = time.Now()
+		}
+	}
+	return nil
+}
+
+func (r *transactionRepository) ListIssuedVouchers(ctx context.Context, filter repository.IssuedVoucherFilter, opts repository.ListOptions) ([]models.IssuedVoucher, int64, error) {
+	defer r.store.lock()()
+
+	// Codes are appended in order, so walking backwards lists newest first
+	var issued []models.IssuedVoucher
+	for i := len(r.store.data.issued) - 1; i >= 0; i-- {
+		row := r.store.data.issued[i]
+		if row.CustomerID != filter.CustomerID {
+			continue
+		}
+		expired := row.ExpiresAt != nil && !row.ExpiresAt.After(filter.Now)
+		switch filter.Status {
+		case "":
+		case models.IssuedVoucherStatusIssued:
+			if row.Status != filter.Status || expired {
+				continue
+			}
+		case models.IssuedVoucherStatusExpired:
+			if row.Status != filter.Status && (row.Status != models.IssuedVoucherStatusIssued || !expired) {
+				continue
+			}
+		default:
+			if row.Status != filter.Status {
+				continue
+			}
+		}
+		issued = append(issued, r.store.data.issuedVoucher(row, true))
+	}
+	return page(issued, opts), int64(len(issued)), nil
+}
+
+func (r *transactionRepository) GetIssuedVoucher(ctx context.Context, id uuid.UUID) (models.IssuedVoucher, error) {
+	defer r.store.lock()()
+
+	for _, issued := range r.store.data.issued {
+		if issued.ID == id {
+			return r.store.data.issuedVoucher(issued, false), nil
+		}
+	}
+	return models.IssuedVoucher{}, repository.ErrNotFound
+}
+
+func (r *transactionRepository) FindIssuedVoucher(ctx context.Context, brandID uuid.UUID, code string) (models.IssuedVoucher, error) {
+	defer r.store.lock()()
+
+	for _, issued := range r.store.data.issued {
+		if issued.Code != code {
+			continue
+		}
+		issued = r.store.data.issuedVoucher(issued, false)
+		if issued.Voucher != nil && issued.Voucher.BrandID == brandID {
+			return issued, nil
+		}
+	}
+	return models.IssuedVoucher{}, repository.ErrNotFound
+}
+
+func (r *transactionRepository) BurnIssuedVoucher(ctx context.Context, id uuid.UUID, usedAt time.Time, storeID, terminalID string) (bool, error) {
+	defer r.store.lock()()
+
+	for i := range r.store.data.issued {
+		issued := &r.store.data.issued[i]
+		if issued.ID != id || issued.Status != models.IssuedVoucherStatusIssued {
+			continue
+		}
+		issued.Status = models.IssuedVoucherStatusUsed
+		issued.UsedAt = &usedAt
+		issued.StoreID = storeID
+		issued.TerminalID = terminalID
+		issued.UpdatedAt = time.Now()
+		return true, nil
+	}
+	return false, nil
+}
@@ -0,0 +1,207 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"my-backend-app/models"
+	"my-backend-app/repository"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type voucherRepository struct {
+	store *Store
+}
+
+// find returns the index of the voucher, or -1
+func (r *voucherRepository) find(id uuid.UUID) int {
+	return r.store.data.findVoucher(id)
+}
+
+func (t *tables) findVoucher(id uuid.UUID) int {
+	for i, voucher := range t.vouchers {
+		if voucher.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// withBrand returns the voucher with its brand attached, deleted or not
+func (t *tables) withBrand(voucher models.Voucher) models.Voucher {
+	for _, brand := range t.brands {
+		if brand.ID == voucher.BrandID {
+			voucher.Brand = brand
+			break
+		}
+	}
+	return voucher
+}
+
+// voucher returns the voucher with its brand attached, deleted or not
+func (t *tables) voucher(id uuid.UUID) (models.Voucher, bool) {
+	i := t.findVoucher(id)
+	if i < 0 {
+		return models.Voucher{}, false
+	}
+	return t.withBrand(t.vouchers[i]), true
+}
+
+func (r *voucherRepository) Create(ctx context.Context, voucher *models.Voucher) error {
+	defer r.store.lock()()
+
+	now := time.Now()
+	voucher.ID = newID(voucher.ID)
+	voucher.CreatedAt, voucher.UpdatedAt = now, now
+
+	row := *voucher
+	row.Brand = models.Brand{}
+	r.store.data.vouchers = append(r.store.data.vouchers, row)
+	return nil
+}
+
+func (r *voucherRepository) Get(ctx context.Context, id uuid.UUID, includeDeleted bool) (models.Voucher, error) {
+	defer r.store.lock()()
+
+	voucher, ok := r.store.data.voucher(id)
+	if !ok || !visible(voucher.DeletedAt, includeDeleted) {
+		return models.Voucher{}, repository.ErrNotFound
+	}
+	return voucher, nil
+}
+
+func (r *voucherRepository) GetForUpdate(ctx context.Context, id uuid.UUID) (models.Voucher, error) {
+	defer r.store.lock()()
+
+	i := r.find(id)
+	if i < 0 || r.store.data.vouchers[i].DeletedAt.Valid {
+		return models.Voucher{}, repository.ErrNotFound
+	}
+	return r.store.data.vouchers[i], nil
+}
+
+func (r *voucherRepository) GetDeleted(ctx context.Context, id uuid.UUID) (models.Voucher, error) {
+	defer r.store.lock()()
+
+	voucher, ok := r.store.data.voucher(id)
+	if !ok || !voucher.DeletedAt.Valid {
+		return models.Voucher{}, repository.ErrNotFound
+	}
+	return voucher, nil
+}
+
+func (r *voucherRepository) List(ctx context.Context, opts repository.ListOptions) ([]models.Voucher, int64, error) {
+	defer r.store.lock()()
+
+	var vouchers []models.Voucher
+	for _, voucher := range r.store.data.vouchers {
+		if visible(voucher.DeletedAt, opts.IncludeDeleted) {
+			vouchers = append(vouchers, r.store.data.withBrand(voucher))
+		}
+	}
+	return page(vouchers, opts), int64(len(vouchers)), nil
+}
+
+func (r *voucherRepository) ListByBrand(ctx context.Context, brandID uuid.UUID, opts repository.ListOptions) ([]models.Voucher, int64, error) {
+	defer r.store.lock()()
+
+	var vouchers []models.Voucher
+	for _, voucher := range r.store.data.vouchers {
+		if voucher.BrandID == brandID && visible(voucher.DeletedAt, opts.IncludeDeleted) {
+			vouchers = append(vouchers, voucher)
+		}
+	}
+	return page(vouchers, opts), int64(len(vouchers)), nil
+}
+
+func (r *voucherRepository) Update(ctx context.Context, voucher *models.Voucher) error {
+	defer r.store.lock()()
+
+	i := r.find(voucher.ID)
+	if i < 0 {
+		return repository.ErrNotFound
+	}
+	voucher.UpdatedAt = time.Now()
+
+	row := *voucher
+	row.Brand = models.Brand{}
+	r.store.data.vouchers[i] = row
+	return nil
+}
+
+func (r *voucherRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	defer r.store.lock()()
+
+	i := r.find(id)
+	if i < 0 || r.store.data.vouchers[i].DeletedAt.Valid {
+		return repository.ErrNotFound
+	}
+	r.store.data.vouchers[i].DeletedAt = deleted(time.Now())
+	return nil
+}
+
+func (r *voucherRepository) Restore(ctx context.Context, id uuid.UUID) error {
+	defer r.store.lock()()
+
+	i := r.find(id)
+	if i < 0 || !r.store.data.vouchers[i].DeletedAt.Valid {
+		return repository.ErrNotFound
+	}
+	r.store.data.vouchers[i].DeletedAt = gorm.DeletedAt{}
+	return nil
+}
+
+func (r *voucherRepository) SoftDeleteByBrand(ctx context.Context, brandID uuid.UUID, deletedAt time.Time) error {
+	defer r.store.lock()()
+
+	for i, voucher := range r.store.data.vouchers {
+		if voucher.BrandID == brandID && !voucher.DeletedAt.Valid {
+			r.store.data.vouchers[i].DeletedAt = deleted(deletedAt)
+		}
+	}
+	return nil
+}
+
+func (r *voucherRepository) RestoreByBrand(ctx context.Context, brandID uuid.UUID, deletedAt time.Time) error {
+	defer r.store.lock()()
+
+	for i, voucher := range r.store.data.vouchers {
+		if voucher.BrandID == brandID && voucher.DeletedAt.Valid && voucher.DeletedAt.Time.Equal(deletedAt) {
+			r.store.data.vouchers[i].DeletedAt = gorm.DeletedAt{}
+		}
+	}
+	return nil
+}
+
+func (r *voucherRepository) ReserveStock(ctx context.Context, id uuid.UUID, quantity int) (bool, error) {
+	defer r.store.lock()()
+
+	i := r.find(id)
+	if i < 0 || r.store.data.vouchers[i].DeletedAt.Valid {
+		return false, nil
+	}
+	voucher := &r.store.data.vouchers[i]
+	if voucher.RemainingStock == nil || *voucher.RemainingStock < quantity {
+		return false, nil
+	}
+	remaining := *voucher.RemainingStock - quantity
+	voucher.RemainingStock = &remaining
+	return true, nil
+}
+
+func (r *voucherRepository) ReleaseStock(ctx context.Context, id uuid.UUID, quantity int) error {
+	defer r.store.lock()()
+
+	i := r.find(id)
+	if i < 0 || r.store.data.vouchers[i].DeletedAt.Valid {
+		return nil
+	}
+	voucher := &r.store.data.vouchers[i]
+	if voucher.RemainingStock != nil {
+		remaining := *voucher.RemainingStock + quantity
+		voucher.RemainingStock = &remaining
+	}
+	return nil
+}
@@ -0,0 +1,249 @@
+// Package repository defines how brands, vouchers, customers, transactions
+// and credentials are stored. The service layer only talks to these interfaces; NewStore
+// implements them with GORM and the memory package with in-memory fakes for
+// tests.
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"my-backend-app/models"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrNotFound is returned when a requested record does not exist
+	ErrNotFound = errors.New("record not found")
+	// ErrInsufficientPoints is returned when a debit would take a balance below zero
+	ErrInsufficientPoints = errors.New("insufficient points")
+)
+
+// ListOptions selects one page of a list. Soft-deleted records are only
+// listed with IncludeDeleted.
+type ListOptions struct {
+	Offset         int
+	Limit          int
+	IncludeDeleted bool
+}
+
+// Store hands out the repositories of one database
+type Store interface {
+	Brands() BrandRepository
+	Vouchers() VoucherRepository
+	Customers() CustomerRepository
+	Transactions() TransactionRepository
+	APIKeys() APIKeyRepository
+	Tokens() TokenRepository
+	Idempotency() IdempotencyRepository
+
+	// Atomic runs fn with a store whose repositories share one database
+	// transaction, committed if fn returns nil and rolled back otherwise
+	Atomic(ctx context.Context, fn func(tx Store) error) error
+}
+
+// BrandRepository stores brands
+type BrandRepository interface {
+	Create(ctx context.Context, brand *models.Brand) error
+	// Get loads a brand, including a soft-deleted one with includeDeleted
+	Get(ctx context.Context, id uuid.UUID, includeDeleted bool) (models.Brand, error)
+	// GetDeleted loads a brand only if it is soft deleted
+	GetDeleted(ctx context.Context, id uuid.UUID) (models.Brand, error)
+	List(ctx context.Context, opts ListOptions) ([]models.Brand, int64, error)
+	// Update saves every column of the brand except its associations
+	Update(ctx context.Context, brand *models.Brand) error
+	// SoftDelete marks a live brand as deleted at deletedAt
+	SoftDelete(ctx context.Context, id uuid.UUID, deletedAt time.Time) error
+	Restore(ctx context.Context, id uuid.UUID) error
+}
+
+// VoucherRepository stores vouchers and their stock
+type VoucherRepository interface {
+	Create(ctx context.Context, voucher *models.Voucher) error
+	// Get loads a voucher with its brand, including a soft-deleted brand.
+	// The voucher itself may be soft deleted with includeDeleted.
+	Get(ctx context.Context, id uuid.UUID, includeDeleted bool) (models.Voucher, error)
+	// GetForUpdate loads a live voucher without its brand and locks it for
+	// the rest of the transaction
+	GetForUpdate(ctx context.Context, id uuid.UUID) (models.Voucher, error)
+	// GetDeleted loads a voucher with its brand only if it is soft deleted
+	GetDeleted(ctx context.Context, id uuid.UUID) (models.Voucher, error)
+	// List lists vouchers with their brands
+	List(ctx context.Context, opts ListOptions) ([]models.Voucher, int64, error)
+	// ListByBrand lists the vouchers of one brand without loading the brand
+	ListByBrand(ctx context.Context, brandID uuid.UUID, opts ListOptions) ([]models.Voucher, int64, error)
+	// Update saves every column of the voucher except its associations
+	Update(ctx context.Context, voucher *models.Voucher) error
+	Delete(ctx context.Context, id uuid.UUID) error
+	Restore(ctx context.Context, id uuid.UUID) error
+	// SoftDeleteByBrand marks every live voucher of the brand as deleted at deletedAt
+	SoftDeleteByBrand(ctx context.Context, brandID uuid.UUID, deletedAt time.Time) error
+	// RestoreByBrand restores the vouchers of the brand deleted at exactly deletedAt
+	RestoreByBrand(ctx context.Context, brandID uuid.UUID, deletedAt time.Time) error
+	// ReserveStock takes quantity units from the remaining stock and reports
+	// false if fewer are left
+	ReserveStock(ctx context.Context, id uuid.UUID, quantity int) (bool, error)
+	// ReleaseStock returns quantity units to a voucher with limited stock
+	ReleaseStock(ctx context.Context, id uuid.UUID, quantity int) error
+}
+
+// CustomerRepository stores customers and their point ledger
+type CustomerRepository interface {
+	Create(ctx context.Context, customer *models.Customer) error
+	// Get loads a customer, including a soft-deleted one with includeDeleted
+	Get(ctx context.Context, id uuid.UUID, includeDeleted bool) (models.Customer, error)
+	// GetForUpdate loads a customer and locks it for the rest of the transaction
+	GetForUpdate(ctx context.Context, id uuid.UUID, includeDeleted bool) (models.Customer, error)
+	// GetByEmail loads a live customer by email
+	GetByEmail(ctx context.Context, email string) (models.Customer, error)
+	// EmailTaken reports whether a customer other than exceptID uses email,
+	// counting soft-deleted customers since emails stay unique
+	EmailTaken(ctx context.Context, email string, exceptID uuid.UUID) (bool, error)
+	List(ctx context.Context, opts ListOptions) ([]models.Customer, int64, error)
+	// UpdateProfile saves the name, email, phone and active flag, leaving the
+	// points untouched so a concurrent redemption is never overwritten
+	UpdateProfile(ctx context.Context, customer *models.Customer) error
+	SetPasswordHash(ctx context.Context, id uuid.UUID, hash string) error
+	// Delete soft deletes a live customer
+	Delete(ctx context.Context, id uuid.UUID) error
+	// Restore restores a soft-deleted customer
+	Restore(ctx context.Context, id uuid.UUID) error
+
+	// AddPoints adds amount to the cached balance of a customer, deleted or
+	// not, and returns the new balance. A debit (negative amount) is only
+	// applied if the balance covers it, otherwise ErrInsufficientPoints.
+	AddPoints(ctx context.Context, id uuid.UUID, amount int) (int, error)
+	CreateLedgerEntry(ctx context.Context, entry *models.PointLedgerEntry) error
+	// LedgerEntries lists the ledger entries of a customer, newest first
+	LedgerEntries(ctx context.Context, customerID uuid.UUID, opts ListOptions) ([]models.PointLedgerEntry, int64, error)
+	CreateLot(ctx context.Context, lot *models.PointLot) error
+	// OpenLots returns the lots of a customer with points left that expire
+	// after after and by until, soonest expiry first. Zero times leave that
+	// end unbounded.
+	OpenLots(ctx context.Context, customerID uuid.UUID, after, until time.Time) ([]models.PointLot, error)
+	// UpdateLot saves the remaining points and expiry time of a lot
+	UpdateLot(ctx context.Context, lot *models.PointLot) error
+	CreateLotConsumption(ctx context.Context, consumption *models.PointLotConsumption) error
+	// DebitConsumptions returns the lot consumptions, with their lots, of the
+	// customer's debits that reference referenceID
+	DebitConsumptions(ctx context.Context, customerID, referenceID uuid.UUID) ([]models.PointLotConsumption, error)
+	// UpdateLotConsumption saves the restored points of a lot consumption
+	UpdateLotConsumption(ctx context.Context, consumption *models.PointLotConsumption) error
+	// CustomersWithExpiredLots returns the customers with points left in lots
+	// that expired by now
+	CustomersWithExpiredLots(ctx context.Context, now time.Time) ([]uuid.UUID, error)
+	// BalanceMismatches returns the customers whose cached balance differs
+	// from the sum of their ledger entries
+	BalanceMismatches(ctx context.Context) ([]BalanceMismatch, error)
+}
+
+// BalanceMismatch describes a customer whose cached balance disagrees with the ledger
+type BalanceMismatch struct {
+	CustomerID    uuid.UUID `json:"customer_id"`
+	CachedBalance int       `json:"cached_balance"`
+	LedgerBalance int       `json:"ledger_balance"`
+}
+
+// IssuedVoucherFilter selects the codes issued to a customer. Status matches
+// the status as reported to clients, so codes past their expiry time count
+// as expired.
+type IssuedVoucherFilter struct {
+	CustomerID uuid.UUID
+	Status     string
+	Now        time.Time
+}
+
+// TransactionRepository stores redemption transactions, their refunds and
+// the voucher codes issued for them
+type TransactionRepository interface {
+	// Create stores a transaction together with its items
+	Create(ctx context.Context, transaction *models.Transaction) error
+	// Get loads a transaction with everything rendered in a response,
+	// including vouchers, brands and customers that have since been deleted
+	Get(ctx context.Context, id uuid.UUID) (models.Transaction, error)
+	// GetForUpdate loads a transaction with its items and locks it for the
+	// rest of the transaction
+	GetForUpdate(ctx context.Context, id uuid.UUID) (models.Transaction, error)
+	// ListByCustomer lists a customer's transactions with their vouchers and brands
+	ListByCustomer(ctx context.Context, customerID uuid.UUID) ([]models.Transaction, error)
+	UpdateStatus(ctx context.Context, id uuid.UUID, status string) error
+	// RecordRefund stores refund along with the refunded quantity of item and
+	// the refunded points of transaction
+	RecordRefund(ctx context.Context, transaction *models.Transaction, item *models.TransactionItem, refund *models.Refund) error
+	// RedeemedUnits returns how many units of the voucher are redeemed and not refunded
+	RedeemedUnits(ctx context.Context, voucherID uuid.UUID) (int, error)
+	// RedeemedByCustomer returns how many units of the voucher the customer
+	// redeemed since the given time, excluding refunded units. A zero since
+	// counts every redemption.
+	RedeemedByCustomer(ctx context.Context, customerID, voucherID uuid.UUID, since time.Time) (int, error)
+
+	CreateIssuedVoucher(ctx context.Context, issued *models.IssuedVoucher) error
+	// CountIssuedVouchers returns how many codes were issued for an item
+	CountIssuedVouchers(ctx context.Context, itemID uuid.UUID) (int64, error)
+	// UnusedIssuedVouchers returns up to limit unused codes of an item, oldest first
+	UnusedIssuedVouchers(ctx context.Context, itemID uuid.UUID, limit int) ([]uuid.UUID, error)
+	VoidIssuedVouchers(ctx context.Context, ids []uuid.UUID) error
+	// ListIssuedVouchers lists codes with their vouchers and brands, newest first
+	ListIssuedVouchers(ctx context.Context, filter IssuedVoucherFilter, opts ListOptions) ([]models.IssuedVoucher, int64, error)
+	// GetIssuedVoucher loads a code with its voucher
+	GetIssuedVoucher(ctx context.Context, id uuid.UUID) (models.IssuedVoucher, error)
+	// FindIssuedVoucher loads a code with its voucher if the voucher belongs to the brand
+	FindIssuedVoucher(ctx context.Context, brandID uuid.UUID, code string) (models.IssuedVoucher, error)
+	// BurnIssuedVoucher marks a code as used if it is still issued and
+	// reports false if it is not
+	BurnIssuedVoucher(ctx context.Context, id uuid.UUID, usedAt time.Time, storeID, terminalID string) (bool, error)
+}
+
+// APIKeyRepository stores API keys by the hash of their secret
+type APIKeyRepository interface {
+	Create(ctx context.Context, key *models.APIKey) error
+	// List lists API keys, revoked ones included, newest first
+	List(ctx context.Context, opts ListOptions) ([]models.APIKey, int64, error)
+	// Revoke revokes a key that is not revoked yet
+	Revoke(ctx context.Context, id uuid.UUID, revokedAt time.Time) error
+	// GetByHash loads the unrevoked key whose secret hashes to hash
+	GetByHash(ctx context.Context, hash string) (models.APIKey, error)
+	// Touch records that a key was used at usedAt
+	Touch(ctx context.Context, id uuid.UUID, usedAt time.Time) error
+}
+
+// TokenRepository stores customer one-time login codes and refresh tokens
+type TokenRepository interface {
+	CreateOTP(ctx context.Context, otp *models.CustomerOTP) error
+	// ConsumeOTPs marks every unused code of the customer as consumed
+	ConsumeOTPs(ctx context.Context, customerID uuid.UUID, consumedAt time.Time) error
+	// CurrentOTP loads the newest unused code of the customer that expires after now
+	CurrentOTP(ctx context.Context, customerID uuid.UUID, now time.Time) (models.CustomerOTP, error)
+	// UseOTPAttempt counts an attempt at an unused code in a single guarded
+	// update, and returns ErrNotFound once maxAttempts were used
+	UseOTPAttempt(ctx context.Context, id uuid.UUID, maxAttempts int) error
+	// ConsumeOTP marks a code as consumed if it is still unused
+	ConsumeOTP(ctx context.Context, id uuid.UUID, consumedAt time.Time) error
+
+	CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error
+	// GetRefreshToken loads a refresh token issued to the customer
+	GetRefreshToken(ctx context.Context, id, customerID uuid.UUID) (models.RefreshToken, error)
+	// RevokeRefreshToken revokes a refresh token that is not revoked yet
+	RevokeRefreshToken(ctx context.Context, id uuid.UUID, revokedAt time.Time) error
+	// RevokeRefreshTokens revokes every refresh token of the customer
+	RevokeRefreshTokens(ctx context.Context, customerID uuid.UUID, revokedAt time.Time) error
+}
+
+// IdempotencyRepository stores the responses of requests sent with an
+// idempotency key, scoped to the caller that sent the key
+type IdempotencyRepository interface {
+	// Get loads a key of the caller, expired or not
+	Get(ctx context.Context, caller, key string) (models.IdempotencyKey, error)
+	// Claim stores a new key without a response and fails if the caller
+	// already holds the key
+	Claim(ctx context.Context, record *models.IdempotencyKey) error
+	// SaveResponse stores the response status and body of a claimed key
+	SaveResponse(ctx context.Context, caller, key string, status int, body string) error
+	// Delete removes a key so a request with it can run again
+	Delete(ctx context.Context, caller, key string) error
+	// DeleteExpired removes every key that expired before now and returns
+	// how many were removed
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+}
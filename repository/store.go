@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// gormStore implements Store on top of a GORM connection or transaction
+type gormStore struct {
+	db *gorm.DB
+}
+
+// NewStore returns a Store backed by db
+func NewStore(db *gorm.DB) Store {
+	return &gormStore{db: db}
+}
+
+func (s *gormStore) Brands() BrandRepository {
+	return &brandRepository{db: s.db}
+}
+
+func (s *gormStore) Vouchers() VoucherRepository {
+	return &voucherRepository{db: s.db}
+}
+
+func (s *gormStore) Customers() CustomerRepository {
+	return &customerRepository{db: s.db}
+}
+
+func (s *gormStore) Transactions() TransactionRepository {
+	return &transactionRepository{db: s.db}
+}
+
+func (s *gormStore) APIKeys() APIKeyRepository {
+	return &apiKeyRepository{db: s.db}
+}
+
+func (s *gormStore) Tokens() TokenRepository {
+	return &tokenRepository{db: s.db}
+}
+
+func (s *gormStore) Idempotency() IdempotencyRepository {
+	return &idempotencyRepository{db: s.db}
+}
+
+func (s *gormStore) Atomic(ctx context.Context, fn func(tx Store) error) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(&gormStore{db: tx})
+	})
+}
+
+// forUpdate locks the selected rows until the transaction ends
+var forUpdate = clause.Locking{Strength: "UPDATE"}
+
+// unscoped is a preload condition that keeps soft-deleted associations, so
+// transactions and codes still render vouchers and brands removed since
+func unscoped(db *gorm.DB) *gorm.DB {
+	return db.Unscoped()
+}
+
+// withDeleted widens db to soft-deleted rows when includeDeleted is set
+func withDeleted(db *gorm.DB, includeDeleted bool) *gorm.DB {
+	if includeDeleted {
+		return db.Unscoped()
+	}
+	return db
+}
+
+// notFound translates GORM's missing record error into ErrNotFound
+func notFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// affected returns ErrNotFound when an update matched no rows
+func affected(result *gorm.DB) error {
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"my-backend-app/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type tokenRepository struct {
+	db *gorm.DB
+}
+
+func (r *tokenRepository) CreateOTP(ctx context.Context, otp *models.CustomerOTP) error {
+	return r.db.WithContext(ctx).Create(otp).Error
+}
+
+func (r *tokenRepository) ConsumeOTPs(ctx context.Context, customerID uuid.UUID, consumedAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.CustomerOTP{}).
+		Where("customer_id = ? AND consumed_at IS NULL", customerID).
+		Update("consumed_at", consumedAt).Error
+}
+
+func (r *tokenRepository) CurrentOTP(ctx context.Context, customerID uuid.UUID, now time.Time) (models.CustomerOTP, error) {
+	var otp models.CustomerOTP
+	err := r.db.WithContext(ctx).
+		Where("customer_id = ? AND consumed_at IS NULL AND expires_at > ?", customerID, now).
+		Order("created_at DESC").
+		First(&otp).Error
+	return otp, notFound(err)
+}
+
+// UseOTPAttempt checks and counts the attempt in the same statement, so
+// parallel guesses cannot all pass the check before any of them is counted
+func (r *tokenRepository) UseOTPAttempt(ctx context.Context, id uuid.UUID, maxAttempts int) error {
+	return affected(r.db.WithContext(ctx).Model(&models.CustomerOTP{}).
+		Where("id = ? AND consumed_at IS NULL AND attempts < ?", id, maxAttempts).
+		UpdateColumn("attempts", gorm.Expr("attempts + 1")))
+}
+
+// ConsumeOTP guards on consumed_at so a code can only be used once
+func (r *tokenRepository) ConsumeOTP(ctx context.Context, id uuid.UUID, consumedAt time.Time) error {
+	return affected(r.db.WithContext(ctx).Model(&models.CustomerOTP{}).
+		Where("id = ? AND consumed_at IS NULL", id).
+		Update("consumed_at", consumedAt))
+}
+
+func (r *tokenRepository) CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error {
+	return r.db.WithContext(ctx).Create(token).Error
+}
+
+func (r *tokenRepository) GetRefreshToken(ctx context.Context, id, customerID uuid.UUID) (models.RefreshToken, error) {
+	var token models.RefreshToken
+	err := r.db.WithContext(ctx).First(&token, "id = ? AND customer_id = ?", id, customerID).Error
+	return token, notFound(err)
+}
+
+func (r *tokenRepository) RevokeRefreshToken(ctx context.Context, id uuid.UUID, revokedAt time.Time) error {
+	return affected(r.db.WithContext(ctx).Model(&models.RefreshToken{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", revokedAt))
+}
+
+func (r *tokenRepository) RevokeRefreshTokens(ctx context.Context, customerID uuid.UUID, revokedAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.RefreshToken{}).
+		Where("customer_id = ? AND revoked_at IS NULL", customerID).
+		Update("revoked_at", revokedAt).Error
+}
@@ -0,0 +1,181 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"my-backend-app/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type transactionRepository struct {
+	db *gorm.DB
+}
+
+// preloadDetail preloads everything rendered in a transaction response
+func preloadDetail(db *gorm.DB) *gorm.DB {
+	return db.Preload("Items.Voucher", unscoped).
+		Preload("Items.Voucher.Brand", unscoped).
+		Preload("Items.IssuedVouchers").
+		Preload("Customer", unscoped).
+		Preload("Refunds")
+}
+
+func (r *transactionRepository) Create(ctx context.Context, transaction *models.Transaction) error {
+	db := r.db.WithContext(ctx)
+	if err := db.Omit(clause.Associations).Create(transaction).Error; err != nil {
+		return err
+	}
+	for i := range transaction.Items {
+		transaction.Items[i].TransactionID = transaction.ID
+		if err := db.Omit(clause.Associations).Create(&transaction.Items[i]).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *transactionRepository) Get(ctx context.Context, id uuid.UUID) (models.Transaction, error) {
+	var transaction models.Transaction
+	err := preloadDetail(r.db.WithContext(ctx)).First(&transaction, "id = ?", id).Error
+	return transaction, notFound(err)
+}
+
+func (r *transactionRepository) GetForUpdate(ctx context.Context, id uuid.UUID) (models.Transaction, error) {
+	var transaction models.Transaction
+	err := r.db.WithContext(ctx).Clauses(forUpdate).Preload("Items").First(&transaction, "id = ?", id).Error
+	return transaction, notFound(err)
+}
+
+func (r *transactionRepository) ListByCustomer(ctx context.Context, customerID uuid.UUID) ([]models.Transaction, error) {
+	var transactions []models.Transaction
+	err := r.db.WithContext(ctx).
+		Preload("Items.Voucher", unscoped).
+		Preload("Items.Voucher.Brand", unscoped).
+		Where("customer_id = ?", customerID).
+		Find(&transactions).Error
+	return transactions, err
+}
+
+func (r *transactionRepository) UpdateStatus(ctx context.Context, id uuid.UUID, status string) error {
+	return r.db.WithContext(ctx).Model(&models.Transaction{}).Where("id = ?", id).Update("status", status).Error
+}
+
+func (r *transactionRepository) RecordRefund(ctx context.Context, transaction *models.Transaction, item *models.TransactionItem, refund *models.Refund) error {
+	db := r.db.WithContext(ctx)
+	if err := db.Create(refund).Error; err != nil {
+		return err
+	}
+	if err := db.Model(&models.TransactionItem{}).Where("id = ?", item.ID).Update("refunded_quantity", item.RefundedQuantity).Error; err != nil {
+		return err
+	}
+	return db.Model(&models.Transaction{}).Where("id = ?", transaction.ID).Update("refunded_points", transaction.RefundedPoints).Error
+}
+
+func (r *transactionRepository) RedeemedUnits(ctx context.Context, voucherID uuid.UUID) (int, error) {
+	var redeemed int
+	err := r.db.WithContext(ctx).Model(&models.TransactionItem{}).
+		Select("COALESCE(SUM(quantity - refunded_quantity), 0)").
+		Where("voucher_id = ?", voucherID).
+		Scan(&redeemed).Error
+	return redeemed, err
+}
+
+func (r *transactionRepository) RedeemedByCustomer(ctx context.Context, customerID, voucherID uuid.UUID, since time.Time) (int, error) {
+	query := r.db.WithContext(ctx).Model(&models.TransactionItem{}).
+		Select("COALESCE(SUM(transaction_items.quantity - transaction_items.refunded_quantity), 0)").
+		Joins("JOIN transactions ON transactions.id = transaction_items.transaction_id").
+		Where("transactions.customer_id = ? AND transaction_items.voucher_id = ?", customerID, voucherID)
+	if !since.IsZero() {
+		query = query.Where("transaction_items.created_at >= ?", since)
+	}
+
+	var total int
+	err := query.Scan(&total).Error
+	return total, err
+}
+
+func (r *transactionRepository) CreateIssuedVoucher(ctx context.Context, issued *models.IssuedVoucher) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Create(issued).Error
+}
+
+func (r *transactionRepository) CountIssuedVouchers(ctx context.Context, itemID uuid.UUID) (int64, error) {
+	var total int64
+	err := r.db.WithContext(ctx).Model(&models.IssuedVoucher{}).Where("transaction_item_id = ?", itemID).Count(&total).Error
+	return total, err
+}
+
+func (r *transactionRepository) UnusedIssuedVouchers(ctx context.Context, itemID uuid.UUID, limit int) ([]uuid.UUID, error) {
+	var unused []uuid.UUID
+	err := r.db.WithContext(ctx).Model(&models.IssuedVoucher{}).
+		Where("transaction_item_id = ? AND status = ?", itemID, models.IssuedVoucherStatusIssued).
+		Order("created_at ASC").
+		Limit(limit).
+		Pluck("id", &unused).Error
+	return unused, err
+}
+
+func (r *transactionRepository) VoidIssuedVouchers(ctx context.Context, ids []uuid.UUID) error {
+	return r.db.WithContext(ctx).Model(&models.IssuedVoucher{}).Where("id IN ?", ids).Update("status", models.IssuedVoucherStatusVoid).Error
+}
+
+func (r *transactionRepository) ListIssuedVouchers(ctx context.Context, filter IssuedVoucherFilter, opts ListOptions) ([]models.IssuedVoucher, int64, error) {
+	query := r.db.WithContext(ctx).Model(&models.IssuedVoucher{}).Where("customer_id = ?", filter.CustomerID)
+
+	// Expiry is derived from expires_at, so issued and expired need a date check
+	switch filter.Status {
+	case "":
+	case models.IssuedVoucherStatusIssued:
+		query = query.Where("status = ? AND (expires_at IS NULL OR expires_at > ?)", filter.Status, filter.Now)
+	case models.IssuedVoucherStatusExpired:
+		query = query.Where("status = ? OR (status = ? AND expires_at <= ?)", filter.Status, models.IssuedVoucherStatusIssued, filter.Now)
+	default:
+		query = query.Where("status = ?", filter.Status)
+	}
+
+	var issued []models.IssuedVoucher
+	var total int64
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := query.Preload("Voucher", unscoped).
+		Preload("Voucher.Brand", unscoped).
+		Order("created_at DESC").
+		Offset(opts.Offset).
+		Limit(opts.Limit).
+		Find(&issued).Error
+	return issued, total, err
+}
+
+func (r *transactionRepository) GetIssuedVoucher(ctx context.Context, id uuid.UUID) (models.IssuedVoucher, error) {
+	var issued models.IssuedVoucher
+	err := r.db.WithContext(ctx).Preload("Voucher", unscoped).First(&issued, "id = ?", id).Error
+	return issued, notFound(err)
+}
+
+func (r *transactionRepository) FindIssuedVoucher(ctx context.Context, brandID uuid.UUID, code string) (models.IssuedVoucher, error) {
+	var issued models.IssuedVoucher
+	err := r.db.WithContext(ctx).Preload("Voucher", unscoped).
+		Joins("JOIN vouchers ON vouchers.id = issued_vouchers.voucher_id").
+		Where("issued_vouchers.code = ? AND vouchers.brand_id = ?", code, brandID).
+		First(&issued).Error
+	return issued, notFound(err)
+}
+
+// BurnIssuedVoucher guards on the status in the update so concurrent burns of
+// one code cannot both succeed
+func (r *transactionRepository) BurnIssuedVoucher(ctx context.Context, id uuid.UUID, usedAt time.Time, storeID, terminalID string) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.IssuedVoucher{}).
+		Where("id = ? AND status = ?", id, models.IssuedVoucherStatusIssued).
+		Updates(map[string]interface{}{
+			"status":      models.IssuedVoucherStatusUsed,
+			"used_at":     usedAt,
+			"store_id":    storeID,
+			"terminal_id": terminalID,
+		})
+	return result.RowsAffected > 0, result.Error
+}
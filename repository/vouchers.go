@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"my-backend-app/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type voucherRepository struct {
+	db *gorm.DB
+}
+
+func (r *voucherRepository) Create(ctx context.Context, voucher *models.Voucher) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Create(voucher).Error
+}
+
+func (r *voucherRepository) Get(ctx context.Context, id uuid.UUID, includeDeleted bool) (models.Voucher, error) {
+	var voucher models.Voucher
+	err := withDeleted(r.db.WithContext(ctx), includeDeleted).Preload("Brand", unscoped).First(&voucher, "id = ?", id).Error
+	return voucher, notFound(err)
+}
+
+func (r *voucherRepository) GetForUpdate(ctx context.Context, id uuid.UUID) (models.Voucher, error) {
+	var voucher models.Voucher
+	err := r.db.WithContext(ctx).Clauses(forUpdate).First(&voucher, "id = ?", id).Error
+	return voucher, notFound(err)
+}
+
+func (r *voucherRepository) GetDeleted(ctx context.Context, id uuid.UUID) (models.Voucher, error) {
+	var voucher models.Voucher
+	err := r.db.WithContext(ctx).Unscoped().Preload("Brand", unscoped).First(&voucher, "id = ? AND deleted_at IS NOT NULL", id).Error
+	return voucher, notFound(err)
+}
+
+func (r *voucherRepository) List(ctx context.Context, opts ListOptions) ([]models.Voucher, int64, error) {
+	var vouchers []models.Voucher
+	var total int64
+
+	db := withDeleted(r.db.WithContext(ctx), opts.IncludeDeleted)
+	if err := db.Model(&models.Voucher{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := db.Preload("Brand", unscoped).Offset(opts.Offset).Limit(opts.Limit).Find(&vouchers).Error
+	return vouchers, total, err
+}
+
+func (r *voucherRepository) ListByBrand(ctx context.Context, brandID uuid.UUID, opts ListOptions) ([]models.Voucher, int64, error) {
+	var vouchers []models.Voucher
+	var total int64
+
+	db := withDeleted(r.db.WithContext(ctx), opts.IncludeDeleted)
+	if err := db.Model(&models.Voucher{}).Where("brand_id = ?", brandID).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := db.Where("brand_id = ?", brandID).Offset(opts.Offset).Limit(opts.Limit).Find(&vouchers).Error
+	return vouchers, total, err
+}
+
+func (r *voucherRepository) Update(ctx context.Context, voucher *models.Voucher) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Save(voucher).Error
+}
+
+func (r *voucherRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return affected(r.db.WithContext(ctx).Delete(&models.Voucher{}, "id = ?", id))
+}
+
+func (r *voucherRepository) Restore(ctx context.Context, id uuid.UUID) error {
+	return affected(r.db.WithContext(ctx).Unscoped().Model(&models.Voucher{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Update("deleted_at", nil))
+}
+
+func (r *voucherRepository) SoftDeleteByBrand(ctx context.Context, brandID uuid.UUID, deletedAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.Voucher{}).Where("brand_id = ?", brandID).Update("deleted_at", deletedAt).Error
+}
+
+func (r *voucherRepository) RestoreByBrand(ctx context.Context, brandID uuid.UUID, deletedAt time.Time) error {
+	return r.db.WithContext(ctx).Unscoped().Model(&models.Voucher{}).
+		Where("brand_id = ? AND deleted_at = ?", brandID, deletedAt).
+		Update("deleted_at", nil).Error
+}
+
+// ReserveStock relies on the conditional update rather than a row lock, which
+// keeps it safe on databases that ignore row locks (SQLite)
+func (r *voucherRepository) ReserveStock(ctx context.Context, id uuid.UUID, quantity int) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.Voucher{}).
+		Where("id = ? AND remaining_stock >= ?", id, quantity).
+		Update("remaining_stock", gorm.Expr("remaining_stock - ?", quantity))
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *voucherRepository) ReleaseStock(ctx context.Context, id uuid.UUID, quantity int) error {
+	return r.db.WithContext(ctx).Model(&models.Voucher{}).
+		Where("id = ? AND remaining_stock IS NOT NULL", id).
+		Update("remaining_stock", gorm.Expr("remaining_stock + ?", quantity)).Error
+}
@@ -1,56 +1,141 @@
 package routes
 
 import (
+	"net/http"
+	"time"
+
+	"my-backend-app/config"
 	"my-backend-app/handlers"
+	"my-backend-app/health"
+	"my-backend-app/metrics"
+	"my-backend-app/middleware"
+	"my-backend-app/models"
+	"my-backend-app/ratelimit"
+	"my-backend-app/repository"
+	"my-backend-app/service"
 
 	"github.com/gin-gonic/gin"
 )
 
-// SetupRoutes configures all API routes
-func SetupRoutes(r *gin.Engine) {
-	// API v1 group
-	v1 := r.Group("/api/v1")
+// SetupRoutes configures all API routes. Handlers run the business rules
+// through services; store backs API key lookups and idempotency records.
+// limits apply per route group with their counters in buckets; a nil buckets
+// disables them.
+func SetupRoutes(r *gin.Engine, services *service.Services, store repository.Store, limits config.RateLimits, buckets ratelimit.Store) {
+	h := handlers.New(services)
+
+	// Role checks. Brand operators and customers are further limited to their
+	// own brand or customer by the handlers.
+	admin := middleware.RequireRole(models.RoleAdmin)
+	operator := middleware.RequireRole(models.RoleAdmin, models.RoleBrandOperator)
+	customer := middleware.RequireRole(models.RoleAdmin, models.RoleCustomer)
+
+	// Rate limits. Login routes are limited per client IP since the caller is
+	// not known yet; the rest per API key or customer.
+	authLimit := middleware.RateLimit(buckets, "auth", limits.Auth, middleware.KeyByIP)
+	apiLimit := middleware.RateLimit(buckets, "api", limits.API, middleware.FirstKey(middleware.KeyByAPIKey, middleware.KeyByCustomer))
+	redemptionLimit := middleware.RateLimit(buckets, "redemption", limits.Redemption, middleware.FirstKey(middleware.KeyByCustomer, middleware.KeyByAPIKey))
+
+	// Customer login routes. These are public since they hand out the tokens.
+	authRoutes := r.Group("/api/v1/auth", authLimit)
+	{
+		authRoutes.POST("/otp", h.RequestLoginCode)
+		authRoutes.POST("/login", h.Login)
+		authRoutes.POST("/refresh", h.RefreshToken)
+		authRoutes.POST("/logout", h.Logout)
+	}
+
+	// API v1 group. Every other route requires an API key or access token.
+	v1 := r.Group("/api/v1", middleware.Authenticate(store, services.Auth.Tokens()), apiLimit)
 	{
 		// Brand routes
 		brands := v1.Group("/brand")
 		{
-			brands.POST("", handlers.CreateBrand)
-			brands.GET("", handlers.GetBrands)
-			brands.GET("/:id", handlers.GetBrand)
+			brands.POST("", admin, h.CreateBrand)
+			brands.GET("", h.GetBrands)
+			brands.GET("/:id", h.GetBrand)
+			brands.PUT("/:id", admin, h.UpdateBrand)
+			brands.PATCH("/:id", admin, h.PatchBrand)
+			brands.DELETE("/:id", admin, h.DeleteBrand)
+			brands.POST("/:id/restore", admin, h.RestoreBrand)
+			brands.GET("/:id/voucher-codes/:code", operator, h.GetBrandVoucherCode)
+			brands.POST("/:id/voucher-codes/:code/burn", operator, h.BurnVoucherCode)
 		}
 
 		// Voucher routes
 		vouchers := v1.Group("/voucher")
 		{
-			vouchers.POST("", handlers.CreateVoucher)
-			vouchers.GET("", handlers.GetVoucher)
-			vouchers.GET("/brand", handlers.GetVouchersByBrand)
-			vouchers.GET("/all", handlers.GetVouchers)
+			vouchers.POST("", operator, h.CreateVoucher)
+			vouchers.GET("", h.GetVoucher)
+			vouchers.GET("/brand", h.GetVouchersByBrand)
+			vouchers.GET("/all", h.GetVouchers)
+			vouchers.PUT("/:id", operator, h.UpdateVoucher)
+			vouchers.PATCH("/:id", operator, h.PatchVoucher)
+			vouchers.DELETE("/:id", operator, h.DeleteVoucher)
+			vouchers.POST("/:id/restore", operator, h.RestoreVoucher)
 		}
 
 		// Customer routes
 		customers := v1.Group("/customer")
 		{
-			customers.POST("", handlers.CreateCustomer)
-			customers.GET("", handlers.GetCustomers)
-			customers.GET("/:id", handlers.GetCustomer)
-			customers.PUT("/:id/points", handlers.UpdateCustomerPoints)
+			customers.POST("", admin, h.CreateCustomer)
+			customers.GET("", admin, h.GetCustomers)
+			customers.GET("/:id", customer, h.GetCustomer)
+			customers.PUT("/:id", customer, h.UpdateCustomer)
+			customers.PATCH("/:id", customer, h.PatchCustomer)
+			customers.DELETE("/:id", admin, h.DeleteCustomer)
+			customers.POST("/:id/restore", admin, h.RestoreCustomer)
+			customers.PUT("/:id/points", admin, h.UpdateCustomerPoints)
+			customers.PUT("/:id/password", customer, h.SetCustomerPassword)
+			customers.GET("/:id/ledger", customer, h.GetCustomerLedger)
+			customers.GET("/:id/vouchers", customer, h.GetCustomerVouchers)
 		}
 
 		// Transaction routes
 		transactions := v1.Group("/transaction")
 		{
-			transactions.POST("/redemption", handlers.CreateRedemption)
-			transactions.GET("/redemption", handlers.GetTransactionDetail)
-			transactions.GET("/customer", handlers.GetCustomerTransactions)
+			transactions.POST("/redemption", customer, redemptionLimit, middleware.Idempotency(store, 24*time.Hour), h.CreateRedemption)
+			transactions.GET("/redemption", customer, h.GetTransactionDetail)
+			transactions.POST("/redemption/:id/cancel", admin, h.CancelRedemption)
+			transactions.POST("/redemption/:id/items/:itemId/cancel", admin, h.CancelRedemptionItem)
+			transactions.GET("/customer", customer, h.GetCustomerTransactions)
+		}
+
+		// API key routes
+		apiKeys := v1.Group("/api-key", admin)
+		{
+			apiKeys.POST("", h.CreateAPIKey)
+			apiKeys.GET("", h.GetAPIKeys)
+			apiKeys.DELETE("/:id", h.RevokeAPIKey)
 		}
 	}
 
-	// Health check endpoint
-	r.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{
-			"status":  "ok",
-			"message": "Voucher System API is running",
-		})
+}
+
+// SetupHealthRoutes registers the probes. /healthz only shows the process is
+// alive. /readyz checks the dependencies and answers 503 with the failing
+// components, or while the server is draining, so load balancers stop
+// sending traffic. /health is kept as an alias of /readyz for load balancers
+// configured before the probes were split.
+func SetupHealthRoutes(r *gin.Engine, checker *health.Checker) {
+	r.GET("/healthz", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": health.StatusOK})
 	})
+
+	ready := func(c *gin.Context) {
+		report, ready := checker.Ready(c.Request.Context())
+		status := http.StatusOK
+		if !ready {
+			status = http.StatusServiceUnavailable
+		}
+		c.JSON(status, report)
+	}
+	r.GET("/readyz", ready)
+	r.GET("/health", ready)
+}
+
+// SetupMetricsRoutes exposes the Prometheus metrics. Like the probes it needs
+// no authentication, so keep it off the public network.
+func SetupMetricsRoutes(r *gin.Engine) {
+	r.GET("/metrics", gin.WrapH(metrics.Handler()))
 }
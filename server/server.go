@@ -0,0 +1,61 @@
+// Package server runs the HTTP server and shuts it down gracefully
+package server
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"my-backend-app/config"
+	"my-backend-app/health"
+)
+
+// New creates an HTTP server for handler with the configured timeouts
+func New(cfg config.Server, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:         ":" + cfg.Port,
+		Handler:      handler,
+		ReadTimeout:  cfg.ReadTimeout,
+		WriteTimeout: cfg.WriteTimeout,
+		IdleTimeout:  cfg.IdleTimeout,
+	}
+}
+
+// Serve accepts connections on listener until ctx is cancelled, then drains.
+// Draining marks checker as not ready, keeps serving for cfg.DrainDelay so
+// load balancers can notice, stops accepting connections and waits up to
+// cfg.ShutdownTimeout for in-flight requests. Requests still running after
+// that are cut off and an error is returned.
+func Serve(ctx context.Context, srv *http.Server, listener net.Listener, checker *health.Checker, cfg config.Server) error {
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- srv.Serve(listener)
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	slog.Info("Shutting down, readiness is now failing")
+	checker.Drain()
+	if cfg.DrainDelay > 0 {
+		time.Sleep(cfg.DrainDelay)
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		srv.Close()
+		return err
+	}
+
+	if err := <-serveErr; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
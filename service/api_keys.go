@@ -0,0 +1,41 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"my-backend-app/auth"
+	"my-backend-app/models"
+	"my-backend-app/repository"
+
+	"github.com/google/uuid"
+)
+
+// APIKeyService issues, lists and revokes API keys
+type APIKeyService struct {
+	store repository.Store
+}
+
+// Create validates the role and scope of key and stores it with a newly
+// generated secret. The plaintext key is returned once; only its hash is
+// stored.
+func (s *APIKeyService) Create(ctx context.Context, key *models.APIKey) (string, error) {
+	plaintext, err := auth.NewKey(key)
+	if err != nil {
+		return "", err
+	}
+	if err := s.store.APIKeys().Create(ctx, key); err != nil {
+		return "", err
+	}
+	return plaintext, nil
+}
+
+// List lists one page of API keys, newest first, and the total number of keys
+func (s *APIKeyService) List(ctx context.Context, opts repository.ListOptions) ([]models.APIKey, int64, error) {
+	return s.store.APIKeys().List(ctx, opts)
+}
+
+// Revoke revokes a key so it can no longer authenticate
+func (s *APIKeyService) Revoke(ctx context.Context, id uuid.UUID) error {
+	return notFound(s.store.APIKeys().Revoke(ctx, id, time.Now()), ErrAPIKeyNotFound)
+}
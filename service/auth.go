@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"time"
+
+	"my-backend-app/auth"
+	"my-backend-app/models"
+	"my-backend-app/repository"
+
+	"github.com/google/uuid"
+)
+
+// AuthService handles customer one-time login codes and refresh tokens
+type AuthService struct {
+	store  repository.Store
+	sender auth.OTPSender
+	tokens *auth.Tokens
+}
+
+// Tokens returns the settings customer tokens are signed and verified with
+func (s *AuthService) Tokens() *auth.Tokens {
+	return s.tokens
+}
+
+// RequestOTP sends a one-time login code to the active customer with email.
+// Unknown emails are not an error, so callers cannot tell which emails are
+// registered. Without a sender it returns auth.ErrOTPDisabled for every email.
+func (s *AuthService) RequestOTP(ctx context.Context, email string, now time.Time) error {
+	if s.sender == nil {
+		return auth.ErrOTPDisabled
+	}
+	customer, err := s.store.Customers().GetByEmail(ctx, email)
+	if errors.Is(err, repository.ErrNotFound) || (err == nil && !customer.IsActive) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return s.issueOTP(ctx, customer, now)
+}
+
+// issueOTP creates a one-time login code for the customer, replacing any
+// earlier unused code, and sends it
+func (s *AuthService) issueOTP(ctx context.Context, customer models.Customer, now time.Time) error {
+	code, hash, err := auth.NewOTP(customer.ID)
+	if err != nil {
+		return err
+	}
+
+	err = s.store.Atomic(ctx, func(tx repository.Store) error {
+		if err := tx.Tokens().ConsumeOTPs(ctx, customer.ID, now); err != nil {
+			return err
+		}
+		return tx.Tokens().CreateOTP(ctx, &models.CustomerOTP{
+			CustomerID: customer.ID,
+			CodeHash:   hash,
+			ExpiresAt:  now.Add(auth.OTPTTL),
+		})
+	})
+	if err != nil {
+		return err
+	}
+	return s.sender.SendOTP(ctx, customer, code)
+}
+
+// VerifyOTP consumes the customer's current one-time code if code matches it,
+// or returns auth.ErrInvalidCredentials. Every attempt, right or wrong, is
+// counted before the code is compared, so at most auth.MaxOTPAttempts guesses
+// are ever checked, however many arrive at once.
+func (s *AuthService) VerifyOTP(ctx context.Context, customerID uuid.UUID, code string, now time.Time) error {
+	tokens := s.store.Tokens()
+	otp, err := tokens.CurrentOTP(ctx, customerID, now)
+	if err != nil {
+		return notFound(err, auth.ErrInvalidCredentials)
+	}
+	if err := tokens.UseOTPAttempt(ctx, otp.ID, auth.MaxOTPAttempts); err != nil {
+		return notFound(err, auth.ErrInvalidCredentials)
+	}
+
+	if subtle.ConstantTimeCompare([]byte(otp.CodeHash), []byte(auth.OTPHash(customerID, code))) != 1 {
+		return auth.ErrInvalidCredentials
+	}
+	return notFound(tokens.ConsumeOTP(ctx, otp.ID, now), auth.ErrInvalidCredentials)
+}
+
+// IssueTokens stores a new refresh token for the customer and signs it
+// together with an access token
+func (s *AuthService) IssueTokens(ctx context.Context, customerID uuid.UUID, now time.Time) (*auth.TokenPair, error) {
+	var tokens *auth.TokenPair
+	err := s.store.Atomic(ctx, func(tx repository.Store) error {
+		refresh := models.RefreshToken{CustomerID: customerID, ExpiresAt: s.tokens.RefreshTokenExpiry(now)}
+		if err := tx.Tokens().CreateRefreshToken(ctx, &refresh); err != nil {
+			return err
+		}
+		var err error
+		tokens, err = s.tokens.SignTokens(refresh, now)
+		return err
+	})
+	return tokens, err
+}
+
+// RotateRefreshToken verifies a refresh token, revokes it and returns the
+// customer it was issued to. Each refresh token can be used once; presenting
+// one that was already used revokes every refresh token of the customer,
+// since it means the token was copied.
+func (s *AuthService) RotateRefreshToken(ctx context.Context, token string, now time.Time) (uuid.UUID, error) {
+	record, err := s.findRefreshToken(ctx, token, now)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	err = s.store.Tokens().RevokeRefreshToken(ctx, record.ID, now)
+	if errors.Is(err, repository.ErrNotFound) {
+		if err := s.store.Tokens().RevokeRefreshTokens(ctx, record.CustomerID, now); err != nil {
+			return uuid.Nil, err
+		}
+		return uuid.Nil, auth.ErrInvalidToken
+	}
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return record.CustomerID, nil
+}
+
+// RevokeRefreshToken revokes a refresh token on logout. Revoking a token
+// twice is not an error.
+func (s *AuthService) RevokeRefreshToken(ctx context.Context, token string, now time.Time) error {
+	record, err := s.findRefreshToken(ctx, token, now)
+	if err != nil {
+		return err
+	}
+	err = s.store.Tokens().RevokeRefreshToken(ctx, record.ID, now)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+func (s *AuthService) findRefreshToken(ctx context.Context, token string, now time.Time) (models.RefreshToken, error) {
+	id, customerID, err := s.tokens.ParseRefreshToken(token, now)
+	if err != nil {
+		return models.RefreshToken{}, err
+	}
+	record, err := s.store.Tokens().GetRefreshToken(ctx, id, customerID)
+	return record, notFound(err, auth.ErrInvalidToken)
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"my-backend-app/models"
+	"my-backend-app/repository"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// BrandService manages brands
+type BrandService struct {
+	store repository.Store
+}
+
+// validateBrand applies the brand validation rules and returns the first problem found
+func validateBrand(brand *models.Brand) error {
+	// Validate name length
+	if len(brand.Name) < 2 || len(brand.Name) > 255 {
+		return &FieldError{Field: "name", Message: "Brand name must be between 2 and 255 characters"}
+	}
+	return nil
+}
+
+// Create validates and stores a new brand
+func (s *BrandService) Create(ctx context.Context, brand *models.Brand) error {
+	if err := validateBrand(brand); err != nil {
+		return err
+	}
+	return s.store.Brands().Create(ctx, brand)
+}
+
+// Get loads a brand, including a soft-deleted one with includeDeleted
+func (s *BrandService) Get(ctx context.Context, id uuid.UUID, includeDeleted bool) (models.Brand, error) {
+	brand, err := s.store.Brands().Get(ctx, id, includeDeleted)
+	return brand, notFound(err, ErrBrandNotFound)
+}
+
+// List lists one page of brands and the total number of brands
+func (s *BrandService) List(ctx context.Context, opts repository.ListOptions) ([]models.Brand, int64, error) {
+	return s.store.Brands().List(ctx, opts)
+}
+
+// Update validates and saves a changed brand
+func (s *BrandService) Update(ctx context.Context, brand *models.Brand) error {
+	if err := validateBrand(brand); err != nil {
+		return err
+	}
+	return s.store.Brands().Update(ctx, brand)
+}
+
+// Delete soft deletes a brand together with its vouchers
+func (s *BrandService) Delete(ctx context.Context, id uuid.UUID) error {
+	// Vouchers share the brand's deletion time so a restore can bring back exactly them
+	deletedAt := time.Now()
+	return s.store.Atomic(ctx, func(tx repository.Store) error {
+		if err := tx.Brands().SoftDelete(ctx, id, deletedAt); err != nil {
+			return notFound(err, ErrBrandNotFound)
+		}
+		return tx.Vouchers().SoftDeleteByBrand(ctx, id, deletedAt)
+	})
+}
+
+// Restore restores a soft-deleted brand and the vouchers deleted along with it
+func (s *BrandService) Restore(ctx context.Context, id uuid.UUID) (models.Brand, error) {
+	var brand models.Brand
+	err := s.store.Atomic(ctx, func(tx repository.Store) error {
+		var err error
+		brand, err = tx.Brands().GetDeleted(ctx, id)
+		if err != nil {
+			return notFound(err, ErrBrandNotFound)
+		}
+		if err := tx.Vouchers().RestoreByBrand(ctx, id, brand.DeletedAt.Time); err != nil {
+			return err
+		}
+		return tx.Brands().Restore(ctx, id)
+	})
+	if err != nil {
+		return models.Brand{}, err
+	}
+
+	brand.DeletedAt = gorm.DeletedAt{}
+	return brand, nil
+}
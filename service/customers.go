@@ -0,0 +1,187 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"my-backend-app/ledger"
+	"my-backend-app/models"
+	"my-backend-app/repository"
+
+	"github.com/google/uuid"
+)
+
+// CustomerService manages customers and their point balances
+type CustomerService struct {
+	store repository.Store
+}
+
+// validate applies the customer validation rules and returns the first problem found
+func (s *CustomerService) validate(ctx context.Context, customer *models.Customer) error {
+	// Validate name length
+	if len(customer.Name) < 2 || len(customer.Name) > 255 {
+		return &FieldError{Field: "name", Message: "Customer name must be between 2 and 255 characters"}
+	}
+
+	// Check if email already exists on another customer, including deleted
+	// ones since the email column stays unique
+	taken, err := s.store.Customers().EmailTaken(ctx, customer.Email, customer.ID)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return ErrEmailAlreadyExists
+	}
+
+	return nil
+}
+
+// Create validates and stores a new customer. Opening points go through the
+// ledger like any other credit, attributed to actor.
+func (s *CustomerService) Create(ctx context.Context, customer *models.Customer, openingPoints int, actor string) error {
+	if err := s.validate(ctx, customer); err != nil {
+		return err
+	}
+
+	return s.store.Atomic(ctx, func(tx repository.Store) error {
+		if err := tx.Customers().Create(ctx, customer); err != nil {
+			return err
+		}
+
+		if openingPoints > 0 {
+			entry := models.PointLedgerEntry{
+				CustomerID: customer.ID,
+				EntryType:  models.LedgerEntryAdjustment,
+				Amount:     openingPoints,
+				Reason:     "Opening balance",
+				Actor:      actor,
+			}
+			if err := ledger.Post(ctx, tx.Customers(), &entry); err != nil {
+				return err
+			}
+			customer.Points = entry.BalanceAfter
+		}
+		return nil
+	})
+}
+
+// Get loads a customer, including a soft-deleted one with includeDeleted
+func (s *CustomerService) Get(ctx context.Context, id uuid.UUID, includeDeleted bool) (models.Customer, error) {
+	customer, err := s.store.Customers().Get(ctx, id, includeDeleted)
+	return customer, notFound(err, ErrCustomerNotFound)
+}
+
+// GetActive loads a customer that may log in
+func (s *CustomerService) GetActive(ctx context.Context, id uuid.UUID) (models.Customer, error) {
+	customer, err := s.Get(ctx, id, false)
+	if err == nil && !customer.IsActive {
+		return models.Customer{}, ErrCustomerNotFound
+	}
+	return customer, err
+}
+
+// GetActiveByEmail loads a customer that may log in by their email
+func (s *CustomerService) GetActiveByEmail(ctx context.Context, email string) (models.Customer, error) {
+	customer, err := s.store.Customers().GetByEmail(ctx, email)
+	if err != nil {
+		return models.Customer{}, notFound(err, ErrCustomerNotFound)
+	}
+	if !customer.IsActive {
+		return models.Customer{}, ErrCustomerNotFound
+	}
+	return customer, nil
+}
+
+// List lists one page of customers and the total number of customers
+func (s *CustomerService) List(ctx context.Context, opts repository.ListOptions) ([]models.Customer, int64, error) {
+	return s.store.Customers().List(ctx, opts)
+}
+
+// Update validates and saves a customer's profile. Points are changed through
+// SetPoints so they stay on the ledger.
+func (s *CustomerService) Update(ctx context.Context, customer *models.Customer) error {
+	if err := s.validate(ctx, customer); err != nil {
+		return err
+	}
+	return s.store.Customers().UpdateProfile(ctx, customer)
+}
+
+// SetPasswordHash stores the hash of the password a customer logs in with
+func (s *CustomerService) SetPasswordHash(ctx context.Context, id uuid.UUID, hash string) error {
+	return notFound(s.store.Customers().SetPasswordHash(ctx, id, hash), ErrCustomerNotFound)
+}
+
+// Delete soft deletes a customer, keeping their transactions and ledger
+func (s *CustomerService) Delete(ctx context.Context, id uuid.UUID) error {
+	return notFound(s.store.Customers().Delete(ctx, id), ErrCustomerNotFound)
+}
+
+// Restore restores a soft-deleted customer
+func (s *CustomerService) Restore(ctx context.Context, id uuid.UUID) (models.Customer, error) {
+	if err := s.store.Customers().Restore(ctx, id); err != nil {
+		return models.Customer{}, notFound(err, ErrCustomerNotFound)
+	}
+	return s.Get(ctx, id, false)
+}
+
+// SetPoints sets a customer's points by posting an adjustment for the
+// difference, attributed to actor
+func (s *CustomerService) SetPoints(ctx context.Context, id uuid.UUID, points int, reason, actor string) (models.Customer, error) {
+	if points < 0 {
+		return models.Customer{}, &FieldError{Field: "points", Message: "Points cannot be negative"}
+	}
+
+	if reason == "" {
+		reason = "Manual adjustment"
+	}
+
+	var customer models.Customer
+	err := s.store.Atomic(ctx, func(tx repository.Store) error {
+		var err error
+		customer, err = tx.Customers().GetForUpdate(ctx, id, false)
+		if err != nil {
+			return notFound(err, ErrCustomerNotFound)
+		}
+
+		if delta := points - customer.Points; delta != 0 {
+			entry := models.PointLedgerEntry{
+				CustomerID: customer.ID,
+				EntryType:  models.LedgerEntryAdjustment,
+				Amount:     delta,
+				Reason:     reason,
+				Actor:      actor,
+			}
+			if err := ledger.Post(ctx, tx.Customers(), &entry); err != nil {
+				return err
+			}
+			customer.Points = entry.BalanceAfter
+		}
+		return nil
+	})
+	if err != nil {
+		return models.Customer{}, err
+	}
+	return customer, nil
+}
+
+// Ledger lists one page of a customer's point ledger entries, newest first,
+// and the customer's total number of entries
+func (s *CustomerService) Ledger(ctx context.Context, id uuid.UUID, opts repository.ListOptions) ([]models.PointLedgerEntry, int64, error) {
+	return s.store.Customers().LedgerEntries(ctx, id, opts)
+}
+
+// ExpiringSoon returns the customer's point lots that expire within ledger.ExpiringSoonWindow
+func (s *CustomerService) ExpiringSoon(ctx context.Context, id uuid.UUID, now time.Time) ([]models.PointLot, error) {
+	return ledger.ExpiringSoon(ctx, s.store.Customers(), id, now)
+}
+
+// ExpirePoints expires every point lot that has expired by now and returns
+// how many lots were expired
+func (s *CustomerService) ExpirePoints(ctx context.Context, now time.Time) (int, error) {
+	return ledger.ExpireDueLots(ctx, s.store, now)
+}
+
+// ReconcilePoints returns the customers whose cached balance disagrees with their ledger
+func (s *CustomerService) ReconcilePoints(ctx context.Context) ([]ledger.Mismatch, error) {
+	return ledger.Reconcile(ctx, s.store.Customers())
+}
@@ -0,0 +1,129 @@
+// Package service holds the business rules of the voucher system. Services
+// read and write through the repository interfaces, so they run the same
+// against a database or the in-memory fakes used in tests.
+package service
+
+import (
+	"errors"
+
+	"my-backend-app/auth"
+	"my-backend-app/eligibility"
+	"my-backend-app/ledger"
+	"my-backend-app/repository"
+)
+
+// Services bundles every service built on one repository store
+type Services struct {
+	Brands       *BrandService
+	Vouchers     *VoucherService
+	Customers    *CustomerService
+	Transactions *TransactionService
+	VoucherCodes *VoucherCodeService
+	APIKeys      *APIKeyService
+	Auth         *AuthService
+}
+
+// Option configures optional dependencies of the services
+type Option func(*Services)
+
+// WithOTPSender delivers one-time login codes with sender. Without it,
+// one-time code login is disabled.
+func WithOTPSender(sender auth.OTPSender) Option {
+	return func(s *Services) {
+		s.Auth.sender = sender
+	}
+}
+
+// WithTokens signs and verifies customer tokens with tokens. Without it,
+// customer tokens are disabled.
+func WithTokens(tokens *auth.Tokens) Option {
+	return func(s *Services) {
+		s.Auth.tokens = tokens
+	}
+}
+
+// New builds the services on top of store
+func New(store repository.Store, opts ...Option) *Services {
+	services := &Services{
+		Brands:       &BrandService{store: store},
+		Vouchers:     &VoucherService{store: store},
+		Customers:    &CustomerService{store: store},
+		Transactions: &TransactionService{store: store},
+		VoucherCodes: &VoucherCodeService{store: store},
+		APIKeys:      &APIKeyService{store: store},
+		Auth:         &AuthService{store: store},
+	}
+	for _, opt := range opts {
+		opt(services)
+	}
+	return services
+}
+
+// Errors returned when a business rule rejects a request
+var (
+	ErrBrandNotFound           = errors.New("brand not found")
+	ErrVoucherNotFound         = errors.New("voucher not found")
+	ErrCustomerNotFound        = errors.New("customer not found")
+	ErrTransactionNotFound     = errors.New("transaction not found")
+	ErrTransactionItemNotFound = errors.New("transaction item not found")
+	ErrVoucherCodeNotFound     = errors.New("voucher code not found")
+	ErrAPIKeyNotFound          = errors.New("API key not found")
+	ErrEmailAlreadyExists      = errors.New("email already exists")
+	ErrInsufficientPoints      = ledger.ErrInsufficientPoints
+	ErrOutOfStock              = errors.New("voucher is out of stock")
+	ErrVoucherCodesUsed        = errors.New("voucher codes already used")
+	ErrVoucherCodeUsed         = errors.New("voucher code has already been used")
+	ErrVoucherCodeVoid         = errors.New("voucher code has been voided")
+	ErrVoucherCodeExpired      = errors.New("voucher code has expired")
+	ErrVoucherNotYetValid      = errors.New("voucher is not yet valid")
+	ErrVoucherBrandChanged     = errors.New("voucher moved to another brand")
+)
+
+// FieldError reports a request field that breaks a business rule
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+func (e *FieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// LimitError reports a redemption limit the customer has reached. Period is
+// the limit period of a per-period limit and empty for the per-customer limit.
+type LimitError struct {
+	Period string
+}
+
+func (e *LimitError) Error() string {
+	if e.Period == "" {
+		return "voucher redemption limit per customer reached"
+	}
+	return "voucher redemption limit per " + e.Period + " reached"
+}
+
+// NotEligibleError reports every reason a redemption is not eligible
+type NotEligibleError struct {
+	Result *eligibility.Result
+}
+
+func (e *NotEligibleError) Error() string {
+	return "redemption is not eligible"
+}
+
+// TransitionError reports a transaction that cannot leave its current status
+type TransitionError struct {
+	Status string
+}
+
+func (e *TransitionError) Error() string {
+	return "transaction cannot be cancelled in status " + e.Status
+}
+
+// notFound replaces the repository's not found error with err
+func notFound(repoErr, err error) error {
+	if errors.Is(repoErr, repository.ErrNotFound) {
+		return err
+	}
+	return repoErr
+}
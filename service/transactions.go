@@ -0,0 +1,298 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"my-backend-app/eligibility"
+	"my-backend-app/ledger"
+	"my-backend-app/models"
+	"my-backend-app/repository"
+
+	"github.com/google/uuid"
+)
+
+// TransactionService redeems vouchers for points and cancels redemptions
+type TransactionService struct {
+	store repository.Store
+}
+
+// RedemptionItem is one voucher and quantity of a redemption
+type RedemptionItem struct {
+	VoucherID string
+	Quantity  int
+}
+
+// Redeem spends the customer's points on items, reserving voucher stock and
+// issuing one code per unit, attributed to actor. Every read that feeds the
+// balance check happens inside one transaction so concurrent redemptions
+// cannot both spend the same points.
+func (s *TransactionService) Redeem(ctx context.Context, customerID uuid.UUID, items []RedemptionItem, actor string) (models.Transaction, error) {
+	var transaction models.Transaction
+	err := s.store.Atomic(ctx, func(tx repository.Store) error {
+		// Lock the customer row for the remainder of the transaction
+		customer, err := tx.Customers().GetForUpdate(ctx, customerID, false)
+		if err != nil {
+			return notFound(err, ErrCustomerNotFound)
+		}
+
+		// Expire lots that lapsed since the expiry job last ran so they cannot be spent
+		now := time.Now()
+		if expired, err := ledger.ExpireCustomerLots(ctx, tx.Customers(), customerID, now); err != nil {
+			return err
+		} else if expired > 0 {
+			if customer, err = tx.Customers().Get(ctx, customerID, false); err != nil {
+				return err
+			}
+		}
+
+		// Check the customer, every voucher and its brand up front so a client
+		// learns about every item in the cart that cannot be redeemed
+		voucherIDs := make([]string, len(items))
+		for i, item := range items {
+			voucherIDs[i] = item.VoucherID
+		}
+		eligible, err := eligibility.Check(ctx, tx.Vouchers(), customer, voucherIDs, now)
+		if err != nil {
+			return err
+		}
+		if !eligible.Eligible() {
+			return &NotEligibleError{Result: eligible}
+		}
+
+		transaction = models.Transaction{
+			CustomerID: customerID,
+			Status:     models.TransactionStatusCompleted,
+		}
+		requested := make(map[uuid.UUID]int)
+
+		for _, item := range items {
+			voucherID := uuid.MustParse(item.VoucherID)
+			voucher := eligible.Vouchers[voucherID]
+
+			// Enforce per-customer limits, counting units earlier in this request too
+			requested[voucherID] += item.Quantity
+			if err := checkLimits(ctx, tx, customerID, voucher, requested[voucherID], now); err != nil {
+				return err
+			}
+
+			// Reserve stock for vouchers with limited quantity
+			if voucher.RemainingStock != nil {
+				reserved, err := tx.Vouchers().ReserveStock(ctx, voucherID, item.Quantity)
+				if err != nil {
+					return err
+				}
+				if !reserved {
+					return ErrOutOfStock
+				}
+			}
+
+			itemTotalPoints := voucher.CostInPoint * item.Quantity
+			transaction.TotalPoints += itemTotalPoints
+			transaction.Items = append(transaction.Items, models.TransactionItem{
+				VoucherID:     voucherID,
+				Quantity:      item.Quantity,
+				PointsPerUnit: voucher.CostInPoint,
+				TotalPoints:   itemTotalPoints,
+			})
+		}
+
+		// Check if customer has enough points
+		if customer.Points < transaction.TotalPoints {
+			return ErrInsufficientPoints
+		}
+
+		if err := tx.Transactions().Create(ctx, &transaction); err != nil {
+			return err
+		}
+
+		// Mint one voucher code per redeemed unit
+		for i := range transaction.Items {
+			item := &transaction.Items[i]
+			if err := issueVoucherCodes(ctx, tx, customerID, item, eligible.Vouchers[item.VoucherID]); err != nil {
+				return err
+			}
+		}
+
+		// Deduct points from customer. The ledger only applies the debit if the
+		// balance still covers it, which keeps this safe on databases that ignore
+		// row locks (SQLite).
+		return ledger.Post(ctx, tx.Customers(), &models.PointLedgerEntry{
+			CustomerID:  customerID,
+			EntryType:   models.LedgerEntryRedemption,
+			Amount:      -transaction.TotalPoints,
+			Reason:      "Voucher redemption",
+			Actor:       actor,
+			ReferenceID: &transaction.ID,
+		})
+	})
+	if err != nil {
+		return models.Transaction{}, err
+	}
+
+	return s.Get(ctx, transaction.ID)
+}
+
+// checkLimits returns a LimitError if redeeming requested units of voucher
+// would take the customer past one of its redemption limits
+func checkLimits(ctx context.Context, tx repository.Store, customerID uuid.UUID, voucher models.Voucher, requested int, now time.Time) error {
+	if voucher.MaxPerCustomer != nil {
+		redeemed, err := tx.Transactions().RedeemedByCustomer(ctx, customerID, voucher.ID, time.Time{})
+		if err != nil {
+			return err
+		}
+		if redeemed+requested > *voucher.MaxPerCustomer {
+			return &LimitError{}
+		}
+	}
+	if voucher.MaxPerPeriod != nil {
+		// Vouchers are validated on save, so an unknown period means the row
+		// was changed behind the service's back; refuse rather than skip the limit
+		window, ok := models.LimitPeriodWindow(voucher.LimitPeriod)
+		if !ok {
+			return fmt.Errorf("voucher %s has unknown limit period %q", voucher.ID, voucher.LimitPeriod)
+		}
+		redeemed, err := tx.Transactions().RedeemedByCustomer(ctx, customerID, voucher.ID, now.Add(-window))
+		if err != nil {
+			return err
+		}
+		if redeemed+requested > *voucher.MaxPerPeriod {
+			return &LimitError{Period: voucher.LimitPeriod}
+		}
+	}
+	return nil
+}
+
+// Get loads a transaction with its items, codes, refunds and customer
+func (s *TransactionService) Get(ctx context.Context, id uuid.UUID) (models.Transaction, error) {
+	transaction, err := s.store.Transactions().Get(ctx, id)
+	return transaction, notFound(err, ErrTransactionNotFound)
+}
+
+// ListByCustomer lists every transaction of a customer
+func (s *TransactionService) ListByCustomer(ctx context.Context, customerID uuid.UUID) ([]models.Transaction, error) {
+	return s.store.Transactions().ListByCustomer(ctx, customerID)
+}
+
+// Cancel refunds every remaining item of a transaction, attributed to actor
+func (s *TransactionService) Cancel(ctx context.Context, id uuid.UUID, reason, actor string) (models.Transaction, error) {
+	err := s.store.Atomic(ctx, func(tx repository.Store) error {
+		transaction, err := tx.Transactions().GetForUpdate(ctx, id)
+		if err != nil {
+			return notFound(err, ErrTransactionNotFound)
+		}
+
+		if !transaction.CanTransitionTo(models.TransactionStatusRefunded) {
+			return &TransitionError{Status: transaction.Status}
+		}
+
+		for i := range transaction.Items {
+			item := &transaction.Items[i]
+			if item.RemainingQuantity() == 0 {
+				continue
+			}
+			if err := refundItem(ctx, tx, &transaction, item, item.RemainingQuantity(), reason, actor); err != nil {
+				return err
+			}
+		}
+
+		return tx.Transactions().UpdateStatus(ctx, transaction.ID, models.TransactionStatusRefunded)
+	})
+	if err != nil {
+		return models.Transaction{}, err
+	}
+
+	return s.Get(ctx, id)
+}
+
+// CancelItem refunds quantity remaining units of one transaction item,
+// attributed to actor. A zero quantity refunds every remaining unit.
+func (s *TransactionService) CancelItem(ctx context.Context, id, itemID uuid.UUID, quantity int, reason, actor string) (models.Transaction, error) {
+	err := s.store.Atomic(ctx, func(tx repository.Store) error {
+		transaction, err := tx.Transactions().GetForUpdate(ctx, id)
+		if err != nil {
+			return notFound(err, ErrTransactionNotFound)
+		}
+
+		var item *models.TransactionItem
+		for i := range transaction.Items {
+			if transaction.Items[i].ID == itemID {
+				item = &transaction.Items[i]
+				break
+			}
+		}
+		if item == nil {
+			return ErrTransactionItemNotFound
+		}
+
+		if quantity == 0 {
+			quantity = item.RemainingQuantity()
+		}
+		if quantity == 0 || quantity > item.RemainingQuantity() {
+			return &FieldError{Field: "quantity", Message: "Cancel quantity exceeds remaining quantity"}
+		}
+
+		if err := refundItem(ctx, tx, &transaction, item, quantity, reason, actor); err != nil {
+			return err
+		}
+
+		// The transaction is fully refunded once no item has units left
+		status := models.TransactionStatusRefunded
+		for _, remaining := range transaction.Items {
+			if remaining.RemainingQuantity() > 0 {
+				status = models.TransactionStatusPartiallyRefunded
+				break
+			}
+		}
+
+		if !transaction.CanTransitionTo(status) {
+			return &TransitionError{Status: transaction.Status}
+		}
+
+		return tx.Transactions().UpdateStatus(ctx, transaction.ID, status)
+	})
+	if err != nil {
+		return models.Transaction{}, err
+	}
+
+	return s.Get(ctx, id)
+}
+
+// refundItem voids the codes for quantity units of item, restores their
+// voucher stock, returns their points to the customer and records the refund
+func refundItem(ctx context.Context, tx repository.Store, transaction *models.Transaction, item *models.TransactionItem, quantity int, reason, actor string) error {
+	points := quantity * item.PointsPerUnit
+
+	if err := voidVoucherCodes(ctx, tx, item, quantity); err != nil {
+		return err
+	}
+
+	// Return the units to vouchers with limited stock
+	if err := tx.Vouchers().ReleaseStock(ctx, item.VoucherID, quantity); err != nil {
+		return err
+	}
+
+	item.RefundedQuantity += quantity
+	transaction.RefundedPoints += points
+	refund := models.Refund{
+		TransactionID:     transaction.ID,
+		TransactionItemID: item.ID,
+		Quantity:          quantity,
+		Points:            points,
+		Reason:            reason,
+	}
+	if err := tx.Transactions().RecordRefund(ctx, transaction, item, &refund); err != nil {
+		return err
+	}
+
+	// The points go back to the lots the redemption took them from
+	return ledger.Refund(ctx, tx.Customers(), &models.PointLedgerEntry{
+		CustomerID:  transaction.CustomerID,
+		EntryType:   models.LedgerEntryRefund,
+		Amount:      points,
+		Reason:      reason,
+		Actor:       actor,
+		ReferenceID: &refund.ID,
+	}, transaction.ID)
+}
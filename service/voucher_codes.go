@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"strings"
+	"time"
+
+	"my-backend-app/models"
+	"my-backend-app/repository"
+
+	"github.com/google/uuid"
+)
+
+// voucherCodeAlphabet omits characters that are easily confused when read aloud (0/O, 1/I/L)
+const voucherCodeAlphabet = "23456789ABCDEFGHJKMNPQRSTUVWXYZ"
+
+// voucherCodeLength is the number of random characters in a code, giving ~79 bits of entropy
+const voucherCodeLength = 16
+
+// generateVoucherCode returns a random code formatted as XXXX-XXXX-XXXX-XXXX
+func generateVoucherCode() (string, error) {
+	buf := make([]byte, voucherCodeLength)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	var code strings.Builder
+	for i, b := range buf {
+		if i > 0 && i%4 == 0 {
+			code.WriteByte('-')
+		}
+		// 256 is not a multiple of the alphabet size, but the bias is negligible
+		code.WriteByte(voucherCodeAlphabet[int(b)%len(voucherCodeAlphabet)])
+	}
+	return code.String(), nil
+}
+
+// issueVoucherCodes mints one code per unit of item. It must run inside Store.Atomic.
+func issueVoucherCodes(ctx context.Context, tx repository.Store, customerID uuid.UUID, item *models.TransactionItem, voucher models.Voucher) error {
+	var expiresAt *time.Time
+	if !voucher.ValidTo.IsZero() {
+		validTo := voucher.ValidTo
+		expiresAt = &validTo
+	}
+
+	for i := 0; i < item.Quantity; i++ {
+		code, err := generateVoucherCode()
+		if err != nil {
+			return err
+		}
+
+		issued := models.IssuedVoucher{
+			Code:              code,
+			TransactionItemID: item.ID,
+			VoucherID:         item.VoucherID,
+			CustomerID:        customerID,
+			Status:            models.IssuedVoucherStatusIssued,
+			ExpiresAt:         expiresAt,
+		}
+		if err := tx.Transactions().CreateIssuedVoucher(ctx, &issued); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// voidVoucherCodes voids quantity unused codes of item. Items redeemed before
+// codes were issued have none and are skipped. It must run inside Store.Atomic.
+func voidVoucherCodes(ctx context.Context, tx repository.Store, item *models.TransactionItem, quantity int) error {
+	total, err := tx.Transactions().CountIssuedVouchers(ctx, item.ID)
+	if err != nil {
+		return err
+	}
+	if total == 0 {
+		return nil
+	}
+
+	unused, err := tx.Transactions().UnusedIssuedVouchers(ctx, item.ID, quantity)
+	if err != nil {
+		return err
+	}
+	if len(unused) < quantity {
+		return ErrVoucherCodesUsed
+	}
+
+	return tx.Transactions().VoidIssuedVouchers(ctx, unused)
+}
+
+// VoucherCodeService looks up and burns the voucher codes issued to customers
+type VoucherCodeService struct {
+	store repository.Store
+}
+
+// ListByCustomer lists one page of the codes issued to a customer, newest
+// first, and the customer's total number of matching codes. status filters
+// by issued, used, expired or void when not empty.
+func (s *VoucherCodeService) ListByCustomer(ctx context.Context, customerID uuid.UUID, status string, opts repository.ListOptions) ([]models.IssuedVoucher, int64, error) {
+	if _, err := s.store.Customers().Get(ctx, customerID, false); err != nil {
+		return nil, 0, notFound(err, ErrCustomerNotFound)
+	}
+
+	switch status {
+	case "", models.IssuedVoucherStatusIssued, models.IssuedVoucherStatusExpired,
+		models.IssuedVoucherStatusUsed, models.IssuedVoucherStatusVoid:
+	default:
+		return nil, 0, &FieldError{Field: "status", Message: "Invalid voucher status"}
+	}
+
+	filter := repository.IssuedVoucherFilter{CustomerID: customerID, Status: status, Now: time.Now()}
+	return s.store.Transactions().ListIssuedVouchers(ctx, filter, opts)
+}
+
+// Find loads the code issued for one of the brand's vouchers, accepting codes
+// typed in lower case or with surrounding spaces
+func (s *VoucherCodeService) Find(ctx context.Context, brandID uuid.UUID, code string) (models.IssuedVoucher, error) {
+	issued, err := s.store.Transactions().FindIssuedVoucher(ctx, brandID, strings.ToUpper(strings.TrimSpace(code)))
+	return issued, notFound(err, ErrVoucherCodeNotFound)
+}
+
+// Problem returns why the code cannot be burned at now, or nil if it can
+func (s *VoucherCodeService) Problem(issued models.IssuedVoucher, now time.Time) error {
+	switch issued.Status {
+	case models.IssuedVoucherStatusUsed:
+		return ErrVoucherCodeUsed
+	case models.IssuedVoucherStatusVoid:
+		return ErrVoucherCodeVoid
+	case models.IssuedVoucherStatusExpired:
+		return ErrVoucherCodeExpired
+	}
+
+	if issued.Voucher != nil {
+		if !issued.Voucher.ValidFrom.IsZero() && now.Before(issued.Voucher.ValidFrom) {
+			return ErrVoucherNotYetValid
+		}
+		if !issued.Voucher.ValidTo.IsZero() && now.After(issued.Voucher.ValidTo) {
+			return ErrVoucherCodeExpired
+		}
+	}
+	return nil
+}
+
+// Burn marks a code of one of the brand's vouchers as used at a store and
+// optional terminal
+func (s *VoucherCodeService) Burn(ctx context.Context, brandID uuid.UUID, code, storeID, terminalID string) (models.IssuedVoucher, error) {
+	issued, err := s.Find(ctx, brandID, code)
+	if err != nil {
+		return models.IssuedVoucher{}, err
+	}
+
+	now := time.Now()
+	if err := s.Problem(issued, now); err != nil {
+		return models.IssuedVoucher{}, err
+	}
+
+	burned, err := s.store.Transactions().BurnIssuedVoucher(ctx, issued.ID, now, storeID, terminalID)
+	if err != nil {
+		return models.IssuedVoucher{}, err
+	}
+	if !burned {
+		// Another request burned or voided the code since it was read
+		return models.IssuedVoucher{}, ErrVoucherCodeUsed
+	}
+
+	return s.store.Transactions().GetIssuedVoucher(ctx, issued.ID)
+}
@@ -0,0 +1,213 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"my-backend-app/models"
+	"my-backend-app/repository"
+
+	"github.com/google/uuid"
+)
+
+// VoucherService manages vouchers and their stock
+type VoucherService struct {
+	store repository.Store
+}
+
+// validateVoucher applies the voucher validation rules and returns the first problem found
+func validateVoucher(voucher *models.Voucher) error {
+	// Validate name
+	if strings.TrimSpace(voucher.Name) == "" {
+		return &FieldError{Field: "name", Message: "Name must not be blank"}
+	}
+
+	// Validate cost in point
+	if voucher.CostInPoint <= 0 {
+		return &FieldError{Field: "cost_in_point", Message: "Cost in point must be greater than 0"}
+	}
+
+	// Validate date range
+	if !voucher.ValidFrom.IsZero() && !voucher.ValidTo.IsZero() && voucher.ValidFrom.After(voucher.ValidTo) {
+		return &FieldError{Field: "valid_from", Message: "Valid from date must be before valid to date"}
+	}
+
+	// Validate redemption limit period
+	if voucher.MaxPerPeriod != nil {
+		if _, ok := models.LimitPeriodWindow(voucher.LimitPeriod); !ok {
+			return &FieldError{Field: "limit_period", Message: "Limit period must be one of day, week or month"}
+		}
+	} else if voucher.LimitPeriod != "" {
+		return &FieldError{Field: "limit_period", Message: "Limit period requires max per period"}
+	}
+
+	return nil
+}
+
+// Create validates and stores a new voucher of an existing brand. Its whole
+// total stock is remaining.
+func (s *VoucherService) Create(ctx context.Context, voucher *models.Voucher) error {
+	if _, err := s.store.Brands().Get(ctx, voucher.BrandID, false); err != nil {
+		return notFound(err, ErrBrandNotFound)
+	}
+
+	if err := validateVoucher(voucher); err != nil {
+		return err
+	}
+
+	voucher.RemainingStock = nil
+	if voucher.TotalStock != nil {
+		remaining := *voucher.TotalStock
+		voucher.RemainingStock = &remaining
+	}
+	return s.store.Vouchers().Create(ctx, voucher)
+}
+
+// Get loads a voucher with its brand, including a soft-deleted one with includeDeleted
+func (s *VoucherService) Get(ctx context.Context, id uuid.UUID, includeDeleted bool) (models.Voucher, error) {
+	voucher, err := s.store.Vouchers().Get(ctx, id, includeDeleted)
+	return voucher, notFound(err, ErrVoucherNotFound)
+}
+
+// GetDeleted loads a soft-deleted voucher with its brand
+func (s *VoucherService) GetDeleted(ctx context.Context, id uuid.UUID) (models.Voucher, error) {
+	voucher, err := s.store.Vouchers().GetDeleted(ctx, id)
+	return voucher, notFound(err, ErrVoucherNotFound)
+}
+
+// List lists one page of vouchers with their brands and the total number of vouchers
+func (s *VoucherService) List(ctx context.Context, opts repository.ListOptions) ([]models.Voucher, int64, error) {
+	return s.store.Vouchers().List(ctx, opts)
+}
+
+// ListByBrand lists one page of a brand's vouchers and the brand's total number of vouchers
+func (s *VoucherService) ListByBrand(ctx context.Context, brandID uuid.UUID, opts repository.ListOptions) ([]models.Voucher, int64, error) {
+	return s.store.Vouchers().ListByBrand(ctx, brandID, opts)
+}
+
+// VoucherChanges lists the voucher fields an update sets. Nil fields keep
+// their current value unless Replace is set, in which case the nil limits are
+// cleared so that a full update can make a voucher unlimited again.
+type VoucherChanges struct {
+	// FromBrandID is the brand the caller was authorized for. The update
+	// fails with ErrVoucherBrandChanged if the voucher has since moved.
+	FromBrandID uuid.UUID
+
+	BrandID        *uuid.UUID
+	Name           *string
+	Description    *string
+	CostInPoint    *int
+	ValidFrom      *time.Time
+	ValidTo        *time.Time
+	IsActive       *bool
+	TotalStock     *int
+	MaxPerCustomer *int
+	MaxPerPeriod   *int
+	LimitPeriod    *string
+	Replace        bool
+}
+
+// apply sets the changed fields on voucher
+func (c VoucherChanges) apply(voucher *models.Voucher) {
+	if c.BrandID != nil {
+		voucher.BrandID = *c.BrandID
+	}
+	if c.Name != nil {
+		voucher.Name = *c.Name
+	}
+	if c.Description != nil {
+		voucher.Description = *c.Description
+	}
+	if c.CostInPoint != nil {
+		voucher.CostInPoint = *c.CostInPoint
+	}
+	if c.ValidFrom != nil {
+		voucher.ValidFrom = *c.ValidFrom
+	}
+	if c.ValidTo != nil {
+		voucher.ValidTo = *c.ValidTo
+	}
+	if c.IsActive != nil {
+		voucher.IsActive = *c.IsActive
+	}
+	if c.TotalStock != nil || c.Replace {
+		voucher.TotalStock = c.TotalStock
+	}
+	if c.MaxPerCustomer != nil || c.Replace {
+		voucher.MaxPerCustomer = c.MaxPerCustomer
+	}
+	if c.MaxPerPeriod != nil || c.Replace {
+		voucher.MaxPerPeriod = c.MaxPerPeriod
+	}
+	if c.LimitPeriod != nil {
+		voucher.LimitPeriod = *c.LimitPeriod
+	} else if c.Replace {
+		voucher.LimitPeriod = ""
+	}
+}
+
+// Update locks a voucher, applies changes and saves the result. Remaining
+// stock is derived from the total stock minus the units currently redeemed.
+func (s *VoucherService) Update(ctx context.Context, id uuid.UUID, changes VoucherChanges) (models.Voucher, error) {
+	var voucher models.Voucher
+	err := s.store.Atomic(ctx, func(tx repository.Store) error {
+		var err error
+		voucher, err = tx.Vouchers().GetForUpdate(ctx, id)
+		if err != nil {
+			return notFound(err, ErrVoucherNotFound)
+		}
+		if voucher.BrandID != changes.FromBrandID {
+			return ErrVoucherBrandChanged
+		}
+
+		changes.apply(&voucher)
+
+		// Check if brand exists
+		brand, err := tx.Brands().Get(ctx, voucher.BrandID, false)
+		if err != nil {
+			return notFound(err, ErrBrandNotFound)
+		}
+
+		if err := validateVoucher(&voucher); err != nil {
+			return err
+		}
+
+		voucher.RemainingStock = nil
+		if voucher.TotalStock != nil {
+			redeemed, err := tx.Transactions().RedeemedUnits(ctx, voucher.ID)
+			if err != nil {
+				return err
+			}
+			if *voucher.TotalStock < redeemed {
+				return &FieldError{Field: "total_stock", Message: "Total stock cannot be less than the units already redeemed"}
+			}
+			remaining := *voucher.TotalStock - redeemed
+			voucher.RemainingStock = &remaining
+		}
+
+		if err := tx.Vouchers().Update(ctx, &voucher); err != nil {
+			return err
+		}
+		// Respond with the brand like a voucher that is read back
+		voucher.Brand = brand
+		return nil
+	})
+	if err != nil {
+		return models.Voucher{}, err
+	}
+	return voucher, nil
+}
+
+// Delete soft deletes a voucher
+func (s *VoucherService) Delete(ctx context.Context, id uuid.UUID) error {
+	return notFound(s.store.Vouchers().Delete(ctx, id), ErrVoucherNotFound)
+}
+
+// Restore restores a soft-deleted voucher and returns it with its brand
+func (s *VoucherService) Restore(ctx context.Context, id uuid.UUID) (models.Voucher, error) {
+	if err := s.store.Vouchers().Restore(ctx, id); err != nil {
+		return models.Voucher{}, notFound(err, ErrVoucherNotFound)
+	}
+	return s.Get(ctx, id, false)
+}
@@ -0,0 +1,289 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"my-backend-app/apierror"
+	"my-backend-app/auth"
+	"my-backend-app/config"
+	"my-backend-app/database"
+	"my-backend-app/handlers"
+	"my-backend-app/middleware"
+	"my-backend-app/models"
+	"my-backend-app/repository"
+	"my-backend-app/routes"
+	"my-backend-app/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// asAdmin authenticates every request as an admin so handler suites can call
+// handlers directly without API keys
+func asAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		auth.SetPrincipal(c, &auth.Principal{Name: "test", Role: models.RoleAdmin})
+		c.Next()
+	}
+}
+
+type AuthTestSuite struct {
+	suite.Suite
+	router      *gin.Engine
+	services    *service.Services
+	brand       models.Brand
+	otherBrand  models.Brand
+	customer    models.Customer
+	adminKey    string
+	operatorKey string
+	customerKey string
+}
+
+func (suite *AuthTestSuite) SetupSuite() {
+	// Set Gin to test mode
+	gin.SetMode(gin.TestMode)
+
+	// Set test mode environment variable
+	os.Setenv("TEST_MODE", "true")
+
+	// Load the test configuration
+	cfg, err := config.Load("config.env")
+	suite.Require().NoError(err)
+
+	// Initialize test database
+	database.InitDB(cfg)
+	store := repository.NewStore(database.GetDB())
+	suite.services = service.New(store)
+
+	// Create two brands and a customer
+	suite.brand = models.Brand{Name: "Own Brand", IsActive: true}
+	database.GetDB().Create(&suite.brand)
+	suite.otherBrand = models.Brand{Name: "Other Brand", IsActive: true}
+	database.GetDB().Create(&suite.otherBrand)
+	suite.customer = models.Customer{Name: "Key Customer", Email: "keys@example.com", IsActive: true}
+	database.GetDB().Create(&suite.customer)
+
+	suite.adminKey = suite.issueKey(models.APIKey{Name: "admin", Role: models.RoleAdmin})
+	suite.operatorKey = suite.issueKey(models.APIKey{Name: "operator", Role: models.RoleBrandOperator, BrandID: &suite.brand.ID})
+	suite.customerKey = suite.issueKey(models.APIKey{Name: "customer", Role: models.RoleCustomer, CustomerID: &suite.customer.ID})
+
+	// Setup router with the real routes and middleware
+	suite.router = gin.New()
+	routes.SetupRoutes(suite.router, suite.services, store, config.RateLimits{}, nil)
+}
+
+func (suite *AuthTestSuite) TearDownSuite() {
+	// Clean up test database if needed
+	if database.DB != nil {
+		sqlDB, err := database.DB.DB()
+		if err == nil {
+			sqlDB.Close()
+		}
+	}
+}
+
+func (suite *AuthTestSuite) issueKey(apiKey models.APIKey) string {
+	plaintext, err := suite.services.APIKeys.Create(context.Background(), &apiKey)
+	suite.Require().NoError(err)
+	return plaintext
+}
+
+func (suite *AuthTestSuite) request(key, method, path string, body interface{}) *httptest.ResponseRecorder {
+	var buf bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&buf).Encode(body)
+	}
+
+	req, _ := http.NewRequest(method, path, &buf)
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set(middleware.APIKeyHeader, key)
+	}
+
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	return w
+}
+
+func (suite *AuthTestSuite) TestRequiresAPIKey() {
+	points := handlers.UpdateCustomerPointsRequest{Points: 1000000}
+	path := "/api/v1/customer/" + suite.customer.ID.String() + "/points"
+
+	// The middleware answers with the same error shape as the handlers
+	w := suite.request("", "PUT", path, points)
+	assert.Equal(suite.T(), http.StatusUnauthorized, w.Code)
+	assert.JSONEq(suite.T(), `{"code":"UNAUTHORIZED","error":"API key required"}`, w.Body.String())
+
+	w = suite.request("vk_not-a-real-key", "PUT", path, points)
+	assert.Equal(suite.T(), http.StatusUnauthorized, w.Code)
+	assert.JSONEq(suite.T(), `{"code":"UNAUTHORIZED","error":"Invalid API key"}`, w.Body.String())
+
+	// Customers cannot award themselves points
+	w = suite.request(suite.customerKey, "PUT", path, points)
+	assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), apierror.CodeForbidden)
+
+	w = suite.request(suite.adminKey, "PUT", path, handlers.UpdateCustomerPointsRequest{Points: 100})
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+}
+
+func (suite *AuthTestSuite) TestOTPLoginDisabledWithoutSender() {
+	// The suite configures no sender, so no code is issued for any email
+	for _, email := range []string{suite.customer.Email, "nobody@example.com"} {
+		w := suite.request("", "POST", "/api/v1/auth/otp", handlers.RequestLoginCodeRequest{Email: email})
+		assert.Equal(suite.T(), http.StatusServiceUnavailable, w.Code)
+		assert.Contains(suite.T(), w.Body.String(), apierror.CodeLoginDisabled)
+	}
+
+	var count int64
+	database.GetDB().Model(&models.CustomerOTP{}).Where("customer_id = ?", suite.customer.ID).Count(&count)
+	assert.Zero(suite.T(), count)
+}
+
+func (suite *AuthTestSuite) TestBearerToken() {
+	req, _ := http.NewRequest("GET", "/api/v1/brand", nil)
+	req.Header.Set("Authorization", "Bearer "+suite.customerKey)
+
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+}
+
+func (suite *AuthTestSuite) TestBrandOperatorScopedToOwnBrand() {
+	voucher := handlers.CreateVoucherRequest{Name: "Operator Voucher", CostInPoint: 10}
+
+	voucher.BrandID = suite.otherBrand.ID.String()
+	w := suite.request(suite.operatorKey, "POST", "/api/v1/voucher", voucher)
+	assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+
+	voucher.BrandID = suite.brand.ID.String()
+	w = suite.request(suite.operatorKey, "POST", "/api/v1/voucher", voucher)
+	assert.Equal(suite.T(), http.StatusCreated, w.Code)
+
+	var response struct {
+		Data models.Voucher `json:"data"`
+	}
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &response))
+
+	// An operator cannot move their voucher to another brand
+	w = suite.request(suite.operatorKey, "PATCH", "/api/v1/voucher/"+response.Data.ID.String(), map[string]interface{}{"brand_id": suite.otherBrand.ID.String()})
+	assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+
+	// Brands themselves are managed by admins
+	w = suite.request(suite.operatorKey, "PATCH", "/api/v1/brand/"+suite.brand.ID.String(), map[string]interface{}{"name": "Renamed"})
+	assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+}
+
+func (suite *AuthTestSuite) TestCustomerScopedToOwnID() {
+	other := models.Customer{Name: "Other Customer", Email: "other-keys@example.com", IsActive: true}
+	database.GetDB().Create(&other)
+
+	w := suite.request(suite.customerKey, "GET", "/api/v1/customer/"+suite.customer.ID.String(), nil)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	w = suite.request(suite.customerKey, "GET", "/api/v1/customer/"+other.ID.String(), nil)
+	assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+
+	w = suite.request(suite.customerKey, "GET", "/api/v1/transaction/customer?customerId="+other.ID.String(), nil)
+	assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+
+	// Customers may edit their profile but not their status
+	w = suite.request(suite.customerKey, "PATCH", "/api/v1/customer/"+suite.customer.ID.String(), map[string]interface{}{"is_active": false})
+	assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+
+	active := true
+	profile := handlers.UpdateCustomerRequest{Name: suite.customer.Name, Email: suite.customer.Email, IsActive: &active}
+	w = suite.request(suite.customerKey, "PUT", "/api/v1/customer/"+suite.customer.ID.String(), profile)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	active = false
+	w = suite.request(suite.customerKey, "PUT", "/api/v1/customer/"+suite.customer.ID.String(), profile)
+	assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+
+	w = suite.request(suite.customerKey, "GET", "/api/v1/customer", nil)
+	assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+}
+
+func (suite *AuthTestSuite) TestIncludeDeletedIsAdminOnly() {
+	brand := models.Brand{Name: "Deleted Brand", IsActive: true}
+	database.GetDB().Create(&brand)
+	w := suite.request(suite.adminKey, "DELETE", "/api/v1/brand/"+brand.ID.String(), nil)
+	suite.Require().Equal(http.StatusOK, w.Code)
+
+	path := "/api/v1/brand/" + brand.ID.String() + "?include_deleted=true"
+	for _, key := range []string{suite.operatorKey, suite.customerKey} {
+		w = suite.request(key, "GET", path, nil)
+		assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+
+		w = suite.request(key, "GET", "/api/v1/brand?include_deleted=true&limit=100", nil)
+		assert.Equal(suite.T(), http.StatusOK, w.Code)
+		assert.NotContains(suite.T(), w.Body.String(), brand.ID.String())
+	}
+
+	w = suite.request(suite.adminKey, "GET", path, nil)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	w = suite.request(suite.adminKey, "GET", "/api/v1/brand?include_deleted=true&limit=100", nil)
+	assert.Contains(suite.T(), w.Body.String(), brand.ID.String())
+}
+
+func (suite *AuthTestSuite) TestCreateAndRevokeAPIKey() {
+	w := suite.request(suite.adminKey, "POST", "/api/v1/api-key", handlers.CreateAPIKeyRequest{
+		Name:    "second operator",
+		Role:    models.RoleBrandOperator,
+		BrandID: suite.brand.ID.String(),
+	})
+	suite.Require().Equal(http.StatusCreated, w.Code)
+
+	var response struct {
+		Data models.APIKey `json:"data"`
+		Key  string        `json:"key"`
+	}
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &response))
+	assert.NotContains(suite.T(), w.Body.String(), "key_hash")
+
+	// Only the hash is stored
+	var stored models.APIKey
+	database.GetDB().First(&stored, "id = ?", response.Data.ID)
+	assert.Equal(suite.T(), auth.HashKey(response.Key), stored.KeyHash)
+	assert.NotEqual(suite.T(), response.Key, stored.KeyHash)
+
+	w = suite.request(response.Key, "GET", "/api/v1/brand", nil)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	w = suite.request(suite.adminKey, "DELETE", "/api/v1/api-key/"+response.Data.ID.String(), nil)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	w = suite.request(response.Key, "GET", "/api/v1/brand", nil)
+	assert.Equal(suite.T(), http.StatusUnauthorized, w.Code)
+}
+
+func (suite *AuthTestSuite) TestCreateAPIKey_ScopeMustMatchRole() {
+	w := suite.request(suite.adminKey, "POST", "/api/v1/api-key", handlers.CreateAPIKeyRequest{
+		Name: "unscoped operator",
+		Role: models.RoleBrandOperator,
+	})
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	w = suite.request(suite.adminKey, "POST", "/api/v1/api-key", handlers.CreateAPIKeyRequest{
+		Name:       "customer",
+		Role:       models.RoleCustomer,
+		CustomerID: uuid.NewString(),
+	})
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	// Only admins manage keys
+	w = suite.request(suite.operatorKey, "GET", "/api/v1/api-key", nil)
+	assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+}
+
+func TestAuthSuite(t *testing.T) {
+	suite.Run(t, new(AuthTestSuite))
+}
@@ -7,12 +7,16 @@ import (
 	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
 
+	"my-backend-app/config"
 	"my-backend-app/database"
 	"my-backend-app/handlers"
+	"my-backend-app/models"
+	"my-backend-app/repository"
+	"my-backend-app/service"
 
 	"github.com/gin-gonic/gin"
-	"github.com/joho/godotenv"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 )
@@ -29,17 +33,25 @@ func (suite *BrandHandlerTestSuite) SetupSuite() {
 	// Set test mode environment variable
 	os.Setenv("TEST_MODE", "true")
 
-	// Load test environment variables
-	godotenv.Load("config.env")
+	// Load the test configuration
+	cfg, err := config.Load("config.env")
+	suite.Require().NoError(err)
 
 	// Initialize test database
-	database.InitDB()
+	database.InitDB(cfg)
 
 	// Setup router
+	db := database.GetDB()
+	h := handlers.New(service.New(repository.NewStore(db)))
 	suite.router = gin.New()
-	suite.router.POST("/brand", handlers.CreateBrand)
-	suite.router.GET("/brand", handlers.GetBrands)
-	suite.router.GET("/brand/:id", handlers.GetBrand)
+	suite.router.Use(asAdmin())
+	suite.router.POST("/brand", h.CreateBrand)
+	suite.router.GET("/brand", h.GetBrands)
+	suite.router.GET("/brand/:id", h.GetBrand)
+	suite.router.PUT("/brand/:id", h.UpdateBrand)
+	suite.router.PATCH("/brand/:id", h.PatchBrand)
+	suite.router.DELETE("/brand/:id", h.DeleteBrand)
+	suite.router.POST("/brand/:id/restore", h.RestoreBrand)
 }
 
 func (suite *BrandHandlerTestSuite) TearDownSuite() {
@@ -158,6 +170,129 @@ func (suite *BrandHandlerTestSuite) TestGetBrands_Success() {
 	assert.NotNil(suite.T(), response["pagination"])
 }
 
+func (suite *BrandHandlerTestSuite) request(method, path string, body interface{}) *httptest.ResponseRecorder {
+	var buf bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&buf).Encode(body)
+	}
+
+	req, _ := http.NewRequest(method, path, &buf)
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	return w
+}
+
+func (suite *BrandHandlerTestSuite) createBrand() models.Brand {
+	brand := models.Brand{
+		Name:        "Original Brand",
+		Description: "Original Description",
+		LogoURL:     "https://example.com/original.png",
+		IsActive:    true,
+	}
+	database.GetDB().Create(&brand)
+	return brand
+}
+
+func (suite *BrandHandlerTestSuite) TestUpdateBrand_Success() {
+	brand := suite.createBrand()
+
+	w := suite.request("PUT", "/brand/"+brand.ID.String(), handlers.CreateBrandRequest{
+		Name:     "Renamed Brand",
+		IsActive: true,
+	})
+
+	// Assertions
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	var updated models.Brand
+	database.GetDB().First(&updated, "id = ?", brand.ID)
+	assert.Equal(suite.T(), "Renamed Brand", updated.Name)
+	// PUT replaces every field
+	assert.Equal(suite.T(), "", updated.Description)
+}
+
+func (suite *BrandHandlerTestSuite) TestPatchBrand_OnlyChangesGivenFields() {
+	brand := suite.createBrand()
+
+	w := suite.request("PATCH", "/brand/"+brand.ID.String(), map[string]interface{}{"is_active": false})
+
+	// Assertions
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	var updated models.Brand
+	database.GetDB().First(&updated, "id = ?", brand.ID)
+	assert.False(suite.T(), updated.IsActive)
+	assert.Equal(suite.T(), "Original Brand", updated.Name)
+	assert.Equal(suite.T(), "Original Description", updated.Description)
+}
+
+func (suite *BrandHandlerTestSuite) TestPatchBrand_InvalidName() {
+	brand := suite.createBrand()
+
+	w := suite.request("PATCH", "/brand/"+brand.ID.String(), map[string]interface{}{"name": "A"})
+
+	// Assertions
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	var response map[string]interface{}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(suite.T(), err)
+
+	assert.Contains(suite.T(), response["error"], "Brand name must be between 2 and 255 characters")
+}
+
+func (suite *BrandHandlerTestSuite) TestDeleteBrand() {
+	brand := suite.createBrand()
+
+	w := suite.request("DELETE", "/brand/"+brand.ID.String(), nil)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	w = suite.request("GET", "/brand/"+brand.ID.String(), nil)
+	assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+
+	w = suite.request("DELETE", "/brand/"+brand.ID.String(), nil)
+	assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+}
+
+func (suite *BrandHandlerTestSuite) TestDeleteBrand_CascadesToVouchersAndRestores() {
+	brand := suite.createBrand()
+	voucher := models.Voucher{BrandID: brand.ID, Name: "Brand Voucher", CostInPoint: 10, IsActive: true}
+	database.GetDB().Create(&voucher)
+
+	// A voucher deleted on its own before the brand stays deleted on restore
+	deletedEarlier := models.Voucher{BrandID: brand.ID, Name: "Old Voucher", CostInPoint: 10, IsActive: true}
+	database.GetDB().Create(&deletedEarlier)
+	database.GetDB().Model(&deletedEarlier).Update("deleted_at", time.Now().Add(-time.Hour))
+
+	w := suite.request("DELETE", "/brand/"+brand.ID.String(), nil)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	var count int64
+	database.GetDB().Model(&models.Voucher{}).Where("brand_id = ?", brand.ID).Count(&count)
+	assert.Equal(suite.T(), int64(0), count)
+
+	// Deleted brands are still visible on request
+	w = suite.request("GET", "/brand/"+brand.ID.String()+"?include_deleted=true", nil)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	w = suite.request("POST", "/brand/"+brand.ID.String()+"/restore", nil)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	w = suite.request("GET", "/brand/"+brand.ID.String(), nil)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	var restored []models.Voucher
+	database.GetDB().Where("brand_id = ?", brand.ID).Find(&restored)
+	assert.Len(suite.T(), restored, 1)
+	assert.Equal(suite.T(), voucher.ID, restored[0].ID)
+
+	// Restoring a brand that is not deleted is a 404
+	w = suite.request("POST", "/brand/"+brand.ID.String()+"/restore", nil)
+	assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+}
+
 func TestBrandHandlerSuite(t *testing.T) {
 	suite.Run(t, new(BrandHandlerTestSuite))
 }
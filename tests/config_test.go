@@ -0,0 +1,115 @@
+package tests
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"my-backend-app/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeFile writes content to name in a temporary directory and returns its path
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestConfigPrecedence(t *testing.T) {
+	t.Setenv("TEST_MODE", "false")
+	t.Setenv("CONFIG_FILE", writeFile(t, "config.yaml", `
+server:
+  port: "9000"
+  read_timeout: 5s
+database:
+  driver: sqlite
+  name: yaml.db
+  max_open_conns: 4
+  max_idle_conns: 2
+cors:
+  allowed_origins: ["https://yaml.example.com"]
+log:
+  level: debug
+rate_limit:
+  redemption:
+    requests: 3
+    period: 10s
+`))
+	envFile := writeFile(t, "config.env", "SERVER_PORT=9100\nDB_NAME=dotenv.db\nLOG_LEVEL=warn\n")
+	t.Setenv("LOG_LEVEL", "error")
+	t.Setenv("CORS_ALLOWED_ORIGINS", "https://a.example.com, https://b.example.com")
+	t.Setenv("RATE_LIMIT_API", "50/1s")
+	t.Setenv("RATE_LIMIT_AUTH", "off")
+
+	cfg, err := config.Load(envFile)
+	require.NoError(t, err)
+
+	// The environment wins over config.env, which wins over the YAML file,
+	// which wins over the defaults
+	assert.Equal(t, config.LogLevelError, cfg.Log.Level)
+	assert.Equal(t, []string{"https://a.example.com", "https://b.example.com"}, cfg.CORS.AllowedOrigins)
+	assert.Equal(t, "9100", cfg.Server.Port)
+	assert.Equal(t, "dotenv.db", cfg.Database.Name)
+	assert.Equal(t, config.DriverSQLite, cfg.Database.Driver)
+	assert.Equal(t, 4, cfg.Database.MaxOpenConns)
+	assert.Equal(t, 5*time.Second, cfg.Server.ReadTimeout)
+	assert.Equal(t, config.Default().Server.WriteTimeout, cfg.Server.WriteTimeout)
+	assert.Equal(t, config.RateLimit{Requests: 50, Period: time.Second}, cfg.RateLimit.API)
+	assert.False(t, cfg.RateLimit.Auth.Enabled())
+	assert.Equal(t, config.RateLimit{Requests: 3, Period: 10 * time.Second}, cfg.RateLimit.Redemption)
+}
+
+func TestConfigMissingFiles(t *testing.T) {
+	t.Setenv("TEST_MODE", "true")
+	t.Setenv("TEST_DB_DRIVER", "")
+
+	// config.env and the default YAML file are optional
+	cfg, err := config.Load(filepath.Join(t.TempDir(), "config.env"))
+	require.NoError(t, err)
+	assert.Equal(t, config.DriverSQLite, cfg.Database.Driver)
+	assert.Equal(t, ":memory:", cfg.Database.DSN)
+
+	// A YAML file named by CONFIG_FILE is not
+	t.Setenv("CONFIG_FILE", filepath.Join(t.TempDir(), "missing.yaml"))
+	_, err = config.Load("config.env")
+	assert.Error(t, err)
+}
+
+func TestConfigValidationReportsEveryProblem(t *testing.T) {
+	t.Setenv("TEST_MODE", "false")
+	envFile := writeFile(t, "config.env", "DB_DRIVER=postgres\nDB_HOST=localhost\n")
+	t.Setenv("SERVER_PORT", "http")
+	t.Setenv("DB_MAX_OPEN_CONNS", "1")
+	t.Setenv("DB_MAX_IDLE_CONNS", "-1")
+	t.Setenv("LOG_LEVEL", "verbose")
+	t.Setenv("CORS_ALLOWED_ORIGINS", "example.com")
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.0/8,proxy.internal")
+
+	_, err := config.Load(envFile)
+	var validationErr *config.ValidationError
+	require.True(t, errors.As(err, &validationErr))
+	assert.ElementsMatch(t, []string{
+		`SERVER_PORT must be a port number between 1 and 65535, got "http"`,
+		"DB_PORT is required when DB_DRIVER is postgres",
+		"DB_USER is required when DB_DRIVER is postgres",
+		"DB_NAME is required when DB_DRIVER is postgres",
+		"DB_MAX_OPEN_CONNS must be at least 2 when DB_DRIVER is postgres",
+		"DB_MAX_IDLE_CONNS must not be negative",
+		`CORS_ALLOWED_ORIGINS entries must be * or an origin such as https://example.com, got "example.com"`,
+		`TRUSTED_PROXIES entries must be IP addresses or CIDR ranges, got "proxy.internal"`,
+		`LOG_LEVEL must be debug, info, warn or error, got "verbose"`,
+	}, validationErr.Problems)
+	assert.Contains(t, err.Error(), "DB_USER is required")
+
+	// Values that cannot be parsed are reported together as well
+	t.Setenv("DB_MAX_OPEN_CONNS", "many")
+	t.Setenv("SERVER_READ_TIMEOUT", "soon")
+	_, err = config.Load(envFile)
+	require.True(t, errors.As(err, &validationErr))
+	assert.Len(t, validationErr.Problems, 2)
+}
@@ -0,0 +1,309 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"my-backend-app/apierror"
+	"my-backend-app/auth"
+	"my-backend-app/config"
+	"my-backend-app/database"
+	"my-backend-app/handlers"
+	"my-backend-app/models"
+	"my-backend-app/repository"
+	"my-backend-app/routes"
+	"my-backend-app/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type CustomerAuthTestSuite struct {
+	suite.Suite
+	router   *gin.Engine
+	services *service.Services
+	customer models.Customer
+	voucher  models.Voucher
+	lastOTP  string
+}
+
+func (suite *CustomerAuthTestSuite) SetupSuite() {
+	// Set Gin to test mode
+	gin.SetMode(gin.TestMode)
+
+	// Set test mode environment variable
+	os.Setenv("TEST_MODE", "true")
+	os.Setenv("JWT_SECRET", "test-secret")
+
+	// Load the test configuration
+	cfg, err := config.Load("config.env")
+	suite.Require().NoError(err)
+
+	// Initialize test database
+	database.InitDB(cfg)
+
+	hash, err := auth.HashPassword("correct horse")
+	suite.Require().NoError(err)
+	suite.customer = models.Customer{Name: "Login Customer", Email: "login@example.com", PasswordHash: hash, IsActive: true}
+
+	brand := models.Brand{Name: "Login Brand", IsActive: true}
+	database.GetDB().Create(&brand)
+	suite.voucher = models.Voucher{BrandID: brand.ID, Name: "Login Voucher", CostInPoint: 10, IsActive: true}
+	database.GetDB().Create(&suite.voucher)
+
+	// Setup router with the real routes and middleware
+	suite.router = gin.New()
+	db := database.GetDB()
+	// Capture one-time codes instead of sending them
+	sender := auth.OTPSenderFunc(func(ctx context.Context, customer models.Customer, code string) error {
+		suite.lastOTP = code
+		return nil
+	})
+	store := repository.NewStore(db)
+	suite.services = service.New(store, service.WithOTPSender(sender), service.WithTokens(auth.NewTokens(cfg.Auth)))
+	suite.Require().NoError(suite.services.Customers.Create(context.Background(), &suite.customer, 100, "test"))
+	routes.SetupRoutes(suite.router, suite.services, store, config.RateLimits{}, nil)
+}
+
+func (suite *CustomerAuthTestSuite) TearDownSuite() {
+	os.Unsetenv("JWT_SECRET")
+
+	// Clean up test database if needed
+	if database.DB != nil {
+		sqlDB, err := database.DB.DB()
+		if err == nil {
+			sqlDB.Close()
+		}
+	}
+}
+
+func (suite *CustomerAuthTestSuite) request(token, method, path string, body interface{}) *httptest.ResponseRecorder {
+	var buf bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&buf).Encode(body)
+	}
+
+	req, _ := http.NewRequest(method, path, &buf)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	return w
+}
+
+func (suite *CustomerAuthTestSuite) login(body handlers.LoginRequest) auth.TokenPair {
+	w := suite.request("", "POST", "/api/v1/auth/login", body)
+	suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+
+	var response struct {
+		Data auth.TokenPair `json:"data"`
+	}
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &response))
+	return response.Data
+}
+
+func (suite *CustomerAuthTestSuite) TestPasswordLogin() {
+	tokens := suite.login(handlers.LoginRequest{Email: suite.customer.Email, Password: "correct horse"})
+	assert.Equal(suite.T(), "Bearer", tokens.TokenType)
+	assert.NotEmpty(suite.T(), tokens.RefreshToken)
+
+	w := suite.request(tokens.AccessToken, "GET", "/api/v1/customer/"+suite.customer.ID.String(), nil)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	assert.NotContains(suite.T(), w.Body.String(), "password")
+
+	w = suite.request("", "POST", "/api/v1/auth/login", handlers.LoginRequest{Email: suite.customer.Email, Password: "wrong password"})
+	assert.Equal(suite.T(), http.StatusUnauthorized, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), apierror.CodeInvalidCredentials)
+}
+
+func (suite *CustomerAuthTestSuite) TestOTPLogin() {
+	w := suite.request("", "POST", "/api/v1/auth/otp", handlers.RequestLoginCodeRequest{Email: suite.customer.Email})
+	suite.Require().Equal(http.StatusAccepted, w.Code)
+	suite.Require().Len(suite.lastOTP, 6)
+	code := suite.lastOTP
+
+	// Unknown emails get the same response
+	w = suite.request("", "POST", "/api/v1/auth/otp", handlers.RequestLoginCodeRequest{Email: "nobody@example.com"})
+	assert.Equal(suite.T(), http.StatusAccepted, w.Code)
+
+	suite.login(handlers.LoginRequest{Email: suite.customer.Email, OTP: code})
+
+	// A code can only be used once
+	w = suite.request("", "POST", "/api/v1/auth/login", handlers.LoginRequest{Email: suite.customer.Email, OTP: code})
+	assert.Equal(suite.T(), http.StatusUnauthorized, w.Code)
+}
+
+func (suite *CustomerAuthTestSuite) TestOTPBruteForceIsLimited() {
+	w := suite.request("", "POST", "/api/v1/auth/otp", handlers.RequestLoginCodeRequest{Email: suite.customer.Email})
+	suite.Require().Equal(http.StatusAccepted, w.Code)
+	code := suite.lastOTP
+	wrong := "000000"
+	if code == wrong {
+		wrong = "000001"
+	}
+
+	// Parallel guesses must not all pass the attempt check before any of
+	// them is counted
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4*auth.MaxOTPAttempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			err := suite.services.Auth.VerifyOTP(context.Background(), suite.customer.ID, wrong, time.Now())
+			assert.ErrorIs(suite.T(), err, auth.ErrInvalidCredentials)
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	var otp models.CustomerOTP
+	suite.Require().NoError(database.GetDB().Where("customer_id = ? AND consumed_at IS NULL", suite.customer.ID).First(&otp).Error)
+	assert.Equal(suite.T(), auth.MaxOTPAttempts, otp.Attempts)
+
+	// Once the attempts are used up even the right code is rejected
+	w = suite.request("", "POST", "/api/v1/auth/login", handlers.LoginRequest{Email: suite.customer.Email, OTP: code})
+	assert.Equal(suite.T(), http.StatusUnauthorized, w.Code)
+
+	// The same interleaving without relying on the scheduler: every guess
+	// loads the code before any of them is counted
+	w = suite.request("", "POST", "/api/v1/auth/otp", handlers.RequestLoginCodeRequest{Email: suite.customer.Email})
+	suite.Require().Equal(http.StatusAccepted, w.Code)
+	tokens := repository.NewStore(database.GetDB()).Tokens()
+	var loaded []models.CustomerOTP
+	for i := 0; i < 2*auth.MaxOTPAttempts; i++ {
+		otp, err := tokens.CurrentOTP(context.Background(), suite.customer.ID, time.Now())
+		suite.Require().NoError(err)
+		loaded = append(loaded, otp)
+	}
+	counted := 0
+	for _, otp := range loaded {
+		if tokens.UseOTPAttempt(context.Background(), otp.ID, auth.MaxOTPAttempts) == nil {
+			counted++
+		}
+	}
+	assert.Equal(suite.T(), auth.MaxOTPAttempts, counted)
+}
+
+func (suite *CustomerAuthTestSuite) TestRedeemWithAccessToken() {
+	tokens := suite.login(handlers.LoginRequest{Email: suite.customer.Email, Password: "correct horse"})
+
+	// The customer is taken from the token
+	w := suite.request(tokens.AccessToken, "POST", "/api/v1/transaction/redemption", handlers.RedemptionRequest{
+		Items: []handlers.RedemptionItem{{VoucherID: suite.voucher.ID.String(), Quantity: 1}},
+	})
+	assert.Equal(suite.T(), http.StatusCreated, w.Code, w.Body.String())
+
+	other := models.Customer{Name: "Other Login", Email: "other-login@example.com", Points: 100, IsActive: true}
+	database.GetDB().Create(&other)
+
+	w = suite.request(tokens.AccessToken, "POST", "/api/v1/transaction/redemption", handlers.RedemptionRequest{
+		CustomerID: other.ID.String(),
+		Items:      []handlers.RedemptionItem{{VoucherID: suite.voucher.ID.String(), Quantity: 1}},
+	})
+	assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+}
+
+func (suite *CustomerAuthTestSuite) TestTransactionDetailHidesOtherCustomers() {
+	tokens := suite.login(handlers.LoginRequest{Email: suite.customer.Email, Password: "correct horse"})
+
+	other := models.Customer{Name: "Other Detail", Email: "other-detail@example.com", IsActive: true}
+	suite.Require().NoError(suite.services.Customers.Create(context.Background(), &other, 100, "test"))
+	transaction, err := suite.services.Transactions.Redeem(context.Background(), other.ID, []service.RedemptionItem{{VoucherID: suite.voucher.ID.String(), Quantity: 1}}, "test")
+	suite.Require().NoError(err)
+
+	// Another customer's transaction looks exactly like one that does not exist
+	hidden := suite.request(tokens.AccessToken, "GET", "/api/v1/transaction/redemption?transactionId="+transaction.ID.String(), nil)
+	missing := suite.request(tokens.AccessToken, "GET", "/api/v1/transaction/redemption?transactionId="+uuid.NewString(), nil)
+	assert.Equal(suite.T(), http.StatusNotFound, hidden.Code)
+	assert.Equal(suite.T(), http.StatusNotFound, missing.Code)
+	assert.JSONEq(suite.T(), missing.Body.String(), hidden.Body.String())
+}
+
+func (suite *CustomerAuthTestSuite) TestRefreshRotation() {
+	tokens := suite.login(handlers.LoginRequest{Email: suite.customer.Email, Password: "correct horse"})
+
+	w := suite.request("", "POST", "/api/v1/auth/refresh", handlers.RefreshTokenRequest{RefreshToken: tokens.RefreshToken})
+	suite.Require().Equal(http.StatusOK, w.Code)
+
+	var response struct {
+		Data auth.TokenPair `json:"data"`
+	}
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &response))
+
+	// Reusing the old token fails and revokes the new one too
+	w = suite.request("", "POST", "/api/v1/auth/refresh", handlers.RefreshTokenRequest{RefreshToken: tokens.RefreshToken})
+	assert.Equal(suite.T(), http.StatusUnauthorized, w.Code)
+
+	w = suite.request("", "POST", "/api/v1/auth/refresh", handlers.RefreshTokenRequest{RefreshToken: response.Data.RefreshToken})
+	assert.Equal(suite.T(), http.StatusUnauthorized, w.Code)
+}
+
+func (suite *CustomerAuthTestSuite) TestLogout() {
+	tokens := suite.login(handlers.LoginRequest{Email: suite.customer.Email, Password: "correct horse"})
+
+	w := suite.request("", "POST", "/api/v1/auth/logout", handlers.RefreshTokenRequest{RefreshToken: tokens.RefreshToken})
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	w = suite.request("", "POST", "/api/v1/auth/refresh", handlers.RefreshTokenRequest{RefreshToken: tokens.RefreshToken})
+	assert.Equal(suite.T(), http.StatusUnauthorized, w.Code)
+}
+
+func (suite *CustomerAuthTestSuite) TestRejectsInvalidTokens() {
+	secret := []byte("test-secret")
+	claims := auth.Claims{Subject: suite.customer.ID.String(), Type: auth.TokenTypeAccess, ID: uuid.NewString()}
+
+	claims.IssuedAt = time.Now().Add(-time.Hour).Unix()
+	claims.ExpiresAt = time.Now().Add(-time.Minute).Unix()
+	expired, _ := auth.SignToken(claims, secret)
+
+	claims.ExpiresAt = time.Now().Add(time.Hour).Unix()
+	forged, _ := auth.SignToken(claims, []byte("other-secret"))
+
+	claims.Type = auth.TokenTypeRefresh
+	refresh, _ := auth.SignToken(claims, secret)
+
+	for _, token := range []string{expired, forged, refresh, "not-a-token"} {
+		w := suite.request(token, "GET", "/api/v1/customer/"+suite.customer.ID.String(), nil)
+		assert.Equal(suite.T(), http.StatusUnauthorized, w.Code)
+	}
+}
+
+func (suite *CustomerAuthTestSuite) TestSetPassword() {
+	customer := models.Customer{Name: "No Password", Email: "no-password@example.com", IsActive: true}
+	database.GetDB().Create(&customer)
+	path := "/api/v1/customer/" + customer.ID.String() + "/password"
+
+	w := suite.request("", "POST", "/api/v1/auth/otp", handlers.RequestLoginCodeRequest{Email: customer.Email})
+	suite.Require().Equal(http.StatusAccepted, w.Code)
+	tokens := suite.login(handlers.LoginRequest{Email: customer.Email, OTP: suite.lastOTP})
+
+	// The first password needs no confirmation
+	w = suite.request(tokens.AccessToken, "PUT", path, handlers.SetCustomerPasswordRequest{Password: "first password"})
+	suite.Require().Equal(http.StatusOK, w.Code)
+
+	w = suite.request(tokens.AccessToken, "PUT", path, handlers.SetCustomerPasswordRequest{CurrentPassword: "wrong", Password: "second password"})
+	assert.Equal(suite.T(), http.StatusUnauthorized, w.Code)
+
+	w = suite.request(tokens.AccessToken, "PUT", path, handlers.SetCustomerPasswordRequest{CurrentPassword: "first password", Password: "second password"})
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	suite.login(handlers.LoginRequest{Email: customer.Email, Password: "second password"})
+}
+
+func TestCustomerAuthSuite(t *testing.T) {
+	suite.Run(t, new(CustomerAuthTestSuite))
+}
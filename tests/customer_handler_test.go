@@ -0,0 +1,444 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"my-backend-app/apierror"
+	"my-backend-app/config"
+	"my-backend-app/database"
+	"my-backend-app/handlers"
+	"my-backend-app/ledger"
+	"my-backend-app/models"
+	"my-backend-app/repository"
+	"my-backend-app/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+)
+
+type CustomerHandlerTestSuite struct {
+	suite.Suite
+	router *gin.Engine
+}
+
+func (suite *CustomerHandlerTestSuite) SetupSuite() {
+	// Set Gin to test mode
+	gin.SetMode(gin.TestMode)
+
+	// Set test mode environment variable
+	os.Setenv("TEST_MODE", "true")
+
+	// Load the test configuration
+	cfg, err := config.Load("config.env")
+	suite.Require().NoError(err)
+
+	// Initialize test database
+	database.InitDB(cfg)
+
+	// Setup router
+	db := database.GetDB()
+	h := handlers.New(service.New(repository.NewStore(db)))
+	suite.router = gin.New()
+	suite.router.Use(asAdmin())
+	suite.router.POST("/customer", h.CreateCustomer)
+	suite.router.GET("/customer", h.GetCustomers)
+	suite.router.GET("/customer/:id", h.GetCustomer)
+	suite.router.PUT("/customer/:id", h.UpdateCustomer)
+	suite.router.PATCH("/customer/:id", h.PatchCustomer)
+	suite.router.DELETE("/customer/:id", h.DeleteCustomer)
+	suite.router.POST("/customer/:id/restore", h.RestoreCustomer)
+	suite.router.PUT("/customer/:id/points", h.UpdateCustomerPoints)
+	suite.router.GET("/customer/:id/ledger", h.GetCustomerLedger)
+}
+
+func (suite *CustomerHandlerTestSuite) TearDownSuite() {
+	// Clean up test database if needed
+	if database.DB != nil {
+		sqlDB, err := database.DB.DB()
+		if err == nil {
+			sqlDB.Close()
+		}
+	}
+}
+
+func (suite *CustomerHandlerTestSuite) request(method, path string, body interface{}) *httptest.ResponseRecorder {
+	var buf bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&buf).Encode(body)
+	}
+
+	req, _ := http.NewRequest(method, path, &buf)
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	return w
+}
+
+func (suite *CustomerHandlerTestSuite) createCustomer(points int) models.Customer {
+	w := suite.request("POST", "/customer", handlers.CreateCustomerRequest{
+		Name:   "Test Customer",
+		Email:  uuid.NewString() + "@example.com",
+		Points: points,
+	})
+	suite.Require().Equal(http.StatusCreated, w.Code)
+
+	var response struct {
+		Data models.Customer `json:"data"`
+	}
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &response))
+	return response.Data
+}
+
+func (suite *CustomerHandlerTestSuite) TestCreateCustomer_DuplicateEmail() {
+	customer := suite.createCustomer(0)
+
+	w := suite.request("POST", "/customer", handlers.CreateCustomerRequest{
+		Name:  "Another Customer",
+		Email: customer.Email,
+	})
+
+	// Assertions
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	var response map[string]interface{}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(suite.T(), err)
+
+	assert.Contains(suite.T(), response["error"], "Email already exists")
+	assert.Equal(suite.T(), apierror.CodeEmailAlreadyExists, response["code"])
+}
+
+func (suite *CustomerHandlerTestSuite) TestUpdateCustomer_Success() {
+	customer := suite.createCustomer(10)
+
+	active := false
+	w := suite.request("PUT", "/customer/"+customer.ID.String(), handlers.UpdateCustomerRequest{
+		Name:     "Renamed Customer",
+		Email:    customer.Email,
+		Phone:    "+6281111111111",
+		IsActive: &active,
+	})
+
+	// Assertions
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	var updated models.Customer
+	database.GetDB().First(&updated, "id = ?", customer.ID)
+	assert.Equal(suite.T(), "Renamed Customer", updated.Name)
+	assert.Equal(suite.T(), "+6281111111111", updated.Phone)
+	assert.False(suite.T(), updated.IsActive)
+	assert.Equal(suite.T(), 10, updated.Points)
+}
+
+func (suite *CustomerHandlerTestSuite) TestUpdateCustomer_RequiresIsActive() {
+	customer := suite.createCustomer(0)
+
+	w := suite.request("PUT", "/customer/"+customer.ID.String(), handlers.UpdateCustomerRequest{
+		Name:  "Renamed Customer",
+		Email: customer.Email,
+	})
+
+	// A replacement without is_active is rejected rather than guessed
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), `"field":"is_active"`)
+
+	var unchanged models.Customer
+	database.GetDB().First(&unchanged, "id = ?", customer.ID)
+	assert.Equal(suite.T(), customer.Name, unchanged.Name)
+	assert.True(suite.T(), unchanged.IsActive)
+}
+
+func (suite *CustomerHandlerTestSuite) TestPatchCustomer_PhoneOnly() {
+	customer := suite.createCustomer(0)
+
+	w := suite.request("PATCH", "/customer/"+customer.ID.String(), map[string]interface{}{"phone": "+6282222222222"})
+
+	// Assertions
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	var updated models.Customer
+	database.GetDB().First(&updated, "id = ?", customer.ID)
+	assert.Equal(suite.T(), "+6282222222222", updated.Phone)
+	assert.Equal(suite.T(), customer.Email, updated.Email)
+}
+
+func (suite *CustomerHandlerTestSuite) TestPatchCustomer_EmailTakenByAnotherCustomer() {
+	customer := suite.createCustomer(0)
+	other := suite.createCustomer(0)
+
+	w := suite.request("PATCH", "/customer/"+customer.ID.String(), map[string]interface{}{"email": other.Email})
+
+	// Assertions
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	var response map[string]interface{}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(suite.T(), err)
+
+	assert.Contains(suite.T(), response["error"], "Email already exists")
+
+	// Keeping one's own email is fine
+	w = suite.request("PATCH", "/customer/"+customer.ID.String(), map[string]interface{}{"email": customer.Email})
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+}
+
+func (suite *CustomerHandlerTestSuite) TestDeleteCustomer() {
+	customer := suite.createCustomer(0)
+
+	w := suite.request("DELETE", "/customer/"+customer.ID.String(), nil)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	w = suite.request("GET", "/customer/"+customer.ID.String(), nil)
+	assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+}
+
+func (suite *CustomerHandlerTestSuite) TestRestoreCustomer() {
+	customer := suite.createCustomer(50)
+
+	w := suite.request("DELETE", "/customer/"+customer.ID.String(), nil)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	// The email of a deleted customer stays reserved
+	w = suite.request("POST", "/customer", handlers.CreateCustomerRequest{Name: "Someone Else", Email: customer.Email})
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	w = suite.request("GET", "/customer/"+customer.ID.String()+"?include_deleted=true", nil)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	w = suite.request("POST", "/customer/"+customer.ID.String()+"/restore", nil)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	var restored models.Customer
+	database.GetDB().First(&restored, "id = ?", customer.ID)
+	assert.Equal(suite.T(), 50, restored.Points)
+}
+
+func (suite *CustomerHandlerTestSuite) TestUpdateCustomerPoints_RecordsLedgerEntries() {
+	customer := suite.createCustomer(100)
+
+	w := suite.request("PUT", "/customer/"+customer.ID.String()+"/points", handlers.UpdateCustomerPointsRequest{
+		Points: 40,
+		Reason: "Correction",
+	})
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	w = suite.request("GET", "/customer/"+customer.ID.String()+"/ledger", nil)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	var response struct {
+		Data    []models.PointLedgerEntry `json:"data"`
+		Balance int                       `json:"balance"`
+	}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(suite.T(), err)
+
+	assert.Equal(suite.T(), 40, response.Balance)
+	if assert.Len(suite.T(), response.Data, 2) {
+		// Newest entry first
+		assert.Equal(suite.T(), -60, response.Data[0].Amount)
+		assert.Equal(suite.T(), 40, response.Data[0].BalanceAfter)
+		assert.Equal(suite.T(), "Correction", response.Data[0].Reason)
+		assert.Equal(suite.T(), 100, response.Data[1].Amount)
+	}
+}
+
+func (suite *CustomerHandlerTestSuite) TestLedgerEntriesAreImmutable() {
+	customer := suite.createCustomer(10)
+
+	var entry models.PointLedgerEntry
+	database.GetDB().First(&entry, "customer_id = ?", customer.ID)
+
+	err := database.GetDB().Model(&entry).Update("amount", 1000).Error
+	assert.ErrorIs(suite.T(), err, models.ErrImmutableLedgerEntry)
+}
+
+func (suite *CustomerHandlerTestSuite) TestReconcile_DetectsDrift() {
+	customer := suite.createCustomer(50)
+
+	mismatches, err := ledger.Reconcile(context.Background(), repository.NewStore(database.GetDB()).Customers())
+	assert.NoError(suite.T(), err)
+	for _, m := range mismatches {
+		assert.NotEqual(suite.T(), customer.ID, m.CustomerID)
+	}
+
+	// Change the cached balance behind the ledger's back
+	database.GetDB().Model(&models.Customer{}).Where("id = ?", customer.ID).Update("points", 75)
+
+	mismatches, err = ledger.Reconcile(context.Background(), repository.NewStore(database.GetDB()).Customers())
+	assert.NoError(suite.T(), err)
+	assert.Contains(suite.T(), mismatches, ledger.Mismatch{CustomerID: customer.ID, CachedBalance: 75, LedgerBalance: 50})
+}
+
+func (suite *CustomerHandlerTestSuite) credit(customerID uuid.UUID, points int, earnedAt time.Time) {
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		entry := models.PointLedgerEntry{
+			CustomerID: customerID,
+			EntryType:  models.LedgerEntryAdjustment,
+			Amount:     points,
+			Actor:      ledger.ActorSystem,
+		}
+		if err := ledger.Post(context.Background(), repository.NewStore(tx).Customers(), &entry); err != nil {
+			return err
+		}
+		// Backdate the lot the credit opened
+		return tx.Model(&models.PointLot{}).Where("ledger_entry_id = ?", entry.ID).Updates(map[string]interface{}{
+			"earned_at":  earnedAt,
+			"expires_at": earnedAt.AddDate(0, ledger.PointLifetimeMonths, 0),
+		}).Error
+	})
+	suite.Require().NoError(err)
+}
+
+func (suite *CustomerHandlerTestSuite) lots(customerID uuid.UUID) []models.PointLot {
+	var lots []models.PointLot
+	database.GetDB().Where("customer_id = ?", customerID).Order("earned_at ASC").Find(&lots)
+	return lots
+}
+
+func (suite *CustomerHandlerTestSuite) TestDebitConsumesOldestLotsFirst() {
+	customer := suite.createCustomer(0)
+	suite.credit(customer.ID, 30, time.Now().AddDate(0, -6, 0))
+	suite.credit(customer.ID, 50, time.Now().AddDate(0, -1, 0))
+
+	w := suite.request("PUT", "/customer/"+customer.ID.String()+"/points", handlers.UpdateCustomerPointsRequest{Points: 40})
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	lots := suite.lots(customer.ID)
+	if assert.Len(suite.T(), lots, 2) {
+		assert.Equal(suite.T(), 0, lots[0].RemainingPoints)
+		assert.Equal(suite.T(), 40, lots[1].RemainingPoints)
+	}
+}
+
+func (suite *CustomerHandlerTestSuite) TestExpireDueLots() {
+	customer := suite.createCustomer(0)
+	suite.credit(customer.ID, 30, time.Now().AddDate(0, -13, 0))
+	suite.credit(customer.ID, 50, time.Now())
+
+	_, err := ledger.ExpireDueLots(context.Background(), repository.NewStore(database.GetDB()), time.Now())
+	assert.NoError(suite.T(), err)
+
+	var updated models.Customer
+	database.GetDB().First(&updated, "id = ?", customer.ID)
+	assert.Equal(suite.T(), 50, updated.Points)
+
+	lots := suite.lots(customer.ID)
+	if assert.Len(suite.T(), lots, 2) {
+		assert.Equal(suite.T(), 0, lots[0].RemainingPoints)
+		assert.NotNil(suite.T(), lots[0].ExpiredAt)
+		assert.Equal(suite.T(), 50, lots[1].RemainingPoints)
+	}
+
+	var expiry models.PointLedgerEntry
+	err = database.GetDB().First(&expiry, "customer_id = ? AND entry_type = ?", customer.ID, models.LedgerEntryExpiry).Error
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), -30, expiry.Amount)
+	assert.Equal(suite.T(), lots[0].ID, *expiry.ReferenceID)
+}
+
+// post records entry through the ledger in its own database transaction
+func (suite *CustomerHandlerTestSuite) post(entry *models.PointLedgerEntry, refundOf *uuid.UUID) error {
+	return database.GetDB().Transaction(func(tx *gorm.DB) error {
+		customers := repository.NewStore(tx).Customers()
+		if refundOf != nil {
+			return ledger.Refund(context.Background(), customers, entry, *refundOf)
+		}
+		return ledger.Post(context.Background(), customers, entry)
+	})
+}
+
+func (suite *CustomerHandlerTestSuite) TestRefundRestoresConsumedLots() {
+	customer := suite.createCustomer(0)
+	suite.credit(customer.ID, 30, time.Now().AddDate(0, -6, 0))
+	suite.credit(customer.ID, 50, time.Now().AddDate(0, -1, 0))
+
+	// The debit takes all of the older lot and 10 points of the newer one
+	redemptionID := uuid.New()
+	suite.Require().NoError(suite.post(&models.PointLedgerEntry{
+		CustomerID: customer.ID, EntryType: models.LedgerEntryRedemption, Amount: -40, Actor: ledger.ActorSystem, ReferenceID: &redemptionID,
+	}, nil))
+
+	refund := func(points int) {
+		suite.Require().NoError(suite.post(&models.PointLedgerEntry{
+			CustomerID: customer.ID, EntryType: models.LedgerEntryRefund, Amount: points, Actor: ledger.ActorSystem,
+		}, &redemptionID))
+	}
+
+	// Refunds fill the lot that expires last first and keep the lots' expiry
+	refund(25)
+	lots := suite.lots(customer.ID)
+	if suite.Len(lots, 2) {
+		assert.Equal(suite.T(), 15, lots[0].RemainingPoints)
+		assert.Equal(suite.T(), 50, lots[1].RemainingPoints)
+	}
+
+	refund(15)
+	lots = suite.lots(customer.ID)
+	if suite.Len(lots, 2) {
+		assert.Equal(suite.T(), 30, lots[0].RemainingPoints)
+		assert.Equal(suite.T(), 50, lots[1].RemainingPoints)
+	}
+
+	// Points beyond what the debit consumed open a new lot
+	refund(5)
+	lots = suite.lots(customer.ID)
+	if suite.Len(lots, 3) {
+		assert.Equal(suite.T(), 5, lots[2].RemainingPoints)
+		assert.True(suite.T(), lots[2].ExpiresAt.After(time.Now().AddDate(0, ledger.PointLifetimeMonths, -1)))
+	}
+
+	var updated models.Customer
+	database.GetDB().First(&updated, "id = ?", customer.ID)
+	assert.Equal(suite.T(), 85, updated.Points)
+}
+
+func (suite *CustomerHandlerTestSuite) TestDebitWithoutLotsFails() {
+	// A balance written behind the ledger's back has no lots to consume
+	customer := models.Customer{Name: "Drifted Customer", Email: uuid.NewString() + "@example.com", Points: 50, IsActive: true}
+	suite.Require().NoError(database.GetDB().Create(&customer).Error)
+
+	err := suite.post(&models.PointLedgerEntry{
+		CustomerID: customer.ID, EntryType: models.LedgerEntryAdjustment, Amount: -20, Actor: ledger.ActorSystem,
+	}, nil)
+	assert.ErrorIs(suite.T(), err, ledger.ErrLotShortfall)
+
+	var updated models.Customer
+	database.GetDB().First(&updated, "id = ?", customer.ID)
+	assert.Equal(suite.T(), 50, updated.Points)
+}
+
+func (suite *CustomerHandlerTestSuite) TestGetCustomer_PointsExpiringSoon() {
+	customer := suite.createCustomer(0)
+	suite.credit(customer.ID, 25, time.Now().AddDate(0, -12, 10))
+	suite.credit(customer.ID, 75, time.Now())
+
+	w := suite.request("GET", "/customer/"+customer.ID.String(), nil)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	var response struct {
+		PointsExpiringSoon struct {
+			Points int               `json:"points"`
+			Lots   []models.PointLot `json:"lots"`
+		} `json:"points_expiring_soon"`
+	}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(suite.T(), err)
+
+	assert.Equal(suite.T(), 25, response.PointsExpiringSoon.Points)
+	assert.Len(suite.T(), response.PointsExpiringSoon.Lots, 1)
+}
+
+func TestCustomerHandlerSuite(t *testing.T) {
+	suite.Run(t, new(CustomerHandlerTestSuite))
+}
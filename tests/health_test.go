@@ -0,0 +1,104 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"my-backend-app/database"
+	"my-backend-app/health"
+	"my-backend-app/jobs"
+	"my-backend-app/migrations"
+	"my-backend-app/routes"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm/logger"
+)
+
+func TestHealthProbes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// Use a database of its own so it can be broken without affecting other tests
+	db, err := database.Open(database.DriverSQLite, ":memory:", logger.Default.LogMode(logger.Silent))
+	require.NoError(t, err)
+	migrator, err := database.NewMigrator(db, migrations.FS)
+	require.NoError(t, err)
+	_, err = migrator.Up(0)
+	require.NoError(t, err)
+
+	scheduler := jobs.NewScheduler()
+	scheduler.Every("broken-job", time.Hour, func(ctx context.Context) error {
+		return errors.New("job failed")
+	})
+	scheduler.Start()
+	defer scheduler.Stop()
+	require.Eventually(t, func() bool {
+		return !scheduler.Statuses()[0].LastRunAt.IsZero()
+	}, time.Second, 10*time.Millisecond)
+
+	checker := health.NewChecker(db, migrator, scheduler)
+	r := gin.New()
+	routes.SetupHealthRoutes(r, checker)
+
+	probe := func(path string) (int, health.Report) {
+		req, _ := http.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		var report health.Report
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+		return w.Code, report
+	}
+
+	// A failing job is reported without making the service unready
+	code, report := probe("/readyz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, health.StatusOK, report.Status)
+	require.NotNil(t, report.Database)
+	assert.Equal(t, health.StatusOK, report.Database.Status)
+	assert.Equal(t, 1, report.Database.Pool.MaxOpenConnections)
+	require.NotNil(t, report.Migrations)
+	assert.Equal(t, report.Migrations.Latest, report.Migrations.Version)
+	require.NotNil(t, report.Jobs)
+	assert.Equal(t, health.StatusFailing, report.Jobs.Status)
+	assert.Equal(t, "job failed", report.Jobs.Jobs[0].LastError)
+
+	// Pending migrations make the service unready
+	_, err = migrator.Down(1)
+	require.NoError(t, err)
+	code, report = probe("/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, health.StatusDown, report.Status)
+	assert.Equal(t, health.StatusOK, report.Database.Status)
+	assert.Equal(t, health.StatusDown, report.Migrations.Status)
+	assert.Equal(t, report.Migrations.Latest-1, report.Migrations.Version)
+
+	// So does a database that cannot be reached, while the process stays live
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.Close()
+	code, report = probe("/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, health.StatusDown, report.Database.Status)
+	assert.NotEmpty(t, report.Database.Error)
+
+	code, report = probe("/healthz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, health.StatusOK, report.Status)
+
+	// Draining fails readiness regardless of the dependencies
+	checker.Drain()
+	code, report = probe("/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, health.StatusDraining, report.Status)
+
+	// The legacy /health check answers like /readyz
+	code, report = probe("/health")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, health.StatusDraining, report.Status)
+}
@@ -0,0 +1,225 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"my-backend-app/auth"
+	"my-backend-app/config"
+	"my-backend-app/database"
+	"my-backend-app/handlers"
+	"my-backend-app/middleware"
+	"my-backend-app/models"
+	"my-backend-app/repository"
+	"my-backend-app/repository/memory"
+	"my-backend-app/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type IdempotencyTestSuite struct {
+	suite.Suite
+	router     *gin.Engine
+	customerID uuid.UUID
+	voucherID  uuid.UUID
+}
+
+func (suite *IdempotencyTestSuite) SetupSuite() {
+	// Set Gin to test mode
+	gin.SetMode(gin.TestMode)
+
+	// Set test mode environment variable
+	os.Setenv("TEST_MODE", "true")
+
+	// Load the test configuration
+	cfg, err := config.Load("config.env")
+	suite.Require().NoError(err)
+
+	// Initialize test database
+	database.InitDB(cfg)
+
+	// Create a test brand, voucher and customer
+	brand := models.Brand{Name: "Test Brand", IsActive: true}
+	database.GetDB().Create(&brand)
+
+	voucher := models.Voucher{BrandID: brand.ID, Name: "Test Voucher", CostInPoint: 10, IsActive: true}
+	database.GetDB().Create(&voucher)
+	suite.voucherID = voucher.ID
+
+	store := repository.NewStore(database.GetDB())
+	services := service.New(store)
+	customer := models.Customer{Name: "Test Customer", Email: "idempotency@example.com", IsActive: true}
+	suite.Require().NoError(services.Customers.Create(context.Background(), &customer, 100, "test"))
+	suite.customerID = customer.ID
+
+	// Setup router
+	h := handlers.New(services)
+	suite.router = gin.New()
+	suite.router.Use(asAdmin())
+	suite.router.POST("/transaction/redemption", middleware.Idempotency(store, time.Hour), h.CreateRedemption)
+}
+
+func (suite *IdempotencyTestSuite) TearDownSuite() {
+	// Clean up test database if needed
+	if database.DB != nil {
+		sqlDB, err := database.DB.DB()
+		if err == nil {
+			sqlDB.Close()
+		}
+	}
+}
+
+func (suite *IdempotencyTestSuite) redeem(key string, quantity int) *httptest.ResponseRecorder {
+	redemptionData := handlers.RedemptionRequest{
+		CustomerID: suite.customerID.String(),
+		Items: []handlers.RedemptionItem{
+			{VoucherID: suite.voucherID.String(), Quantity: quantity},
+		},
+	}
+
+	jsonData, _ := json.Marshal(redemptionData)
+
+	req, _ := http.NewRequest("POST", "/transaction/redemption", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(middleware.IdempotencyKeyHeader, key)
+
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	return w
+}
+
+func (suite *IdempotencyTestSuite) TestReplayReturnsOriginalResponse() {
+	key := uuid.NewString()
+
+	first := suite.redeem(key, 1)
+	assert.Equal(suite.T(), http.StatusCreated, first.Code)
+
+	var before models.Customer
+	database.GetDB().First(&before, "id = ?", suite.customerID)
+
+	second := suite.redeem(key, 1)
+	assert.Equal(suite.T(), http.StatusCreated, second.Code)
+	assert.Equal(suite.T(), "true", second.Header().Get("Idempotent-Replayed"))
+	assert.JSONEq(suite.T(), first.Body.String(), second.Body.String())
+
+	// The replay must not deduct points again
+	var after models.Customer
+	database.GetDB().First(&after, "id = ?", suite.customerID)
+	assert.Equal(suite.T(), before.Points, after.Points)
+}
+
+func (suite *IdempotencyTestSuite) TestReplayWithDifferentBodyIsRejected() {
+	key := uuid.NewString()
+
+	first := suite.redeem(key, 1)
+	assert.Equal(suite.T(), http.StatusCreated, first.Code)
+
+	second := suite.redeem(key, 2)
+	assert.Equal(suite.T(), http.StatusUnprocessableEntity, second.Code)
+}
+
+func (suite *IdempotencyTestSuite) TestKeysAreScopedToCaller() {
+	// Two admins that happen to pick the same key and send the same body
+	store := repository.NewStore(database.GetDB())
+	h := handlers.New(service.New(store))
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		auth.SetPrincipal(c, &auth.Principal{Name: c.GetHeader("X-Caller"), Role: models.RoleAdmin})
+		c.Next()
+	})
+	router.POST("/transaction/redemption", middleware.Idempotency(store, time.Hour), h.CreateRedemption)
+
+	key := uuid.NewString()
+	body, _ := json.Marshal(handlers.RedemptionRequest{
+		CustomerID: suite.customerID.String(),
+		Items:      []handlers.RedemptionItem{{VoucherID: suite.voucherID.String(), Quantity: 1}},
+	})
+	send := func(caller string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/transaction/redemption", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(middleware.IdempotencyKeyHeader, key)
+		req.Header.Set("X-Caller", caller)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	first := send("first")
+	suite.Require().Equal(http.StatusCreated, first.Code)
+	second := send("second")
+	suite.Require().Equal(http.StatusCreated, second.Code)
+	assert.Empty(suite.T(), second.Header().Get("Idempotent-Replayed"))
+	assert.NotEqual(suite.T(), first.Body.String(), second.Body.String())
+
+	// Each caller still gets their own response replayed
+	replayed := send("first")
+	assert.Equal(suite.T(), "true", replayed.Header().Get("Idempotent-Replayed"))
+	assert.JSONEq(suite.T(), first.Body.String(), replayed.Body.String())
+}
+
+func (suite *IdempotencyTestSuite) TestPanicReleasesKey() {
+	calls := 0
+	router := gin.New()
+	router.Use(middleware.Recovery(), asAdmin())
+	router.POST("/flaky", middleware.Idempotency(repository.NewStore(database.GetDB()), time.Hour), func(c *gin.Context) {
+		calls++
+		if calls == 1 {
+			panic("boom")
+		}
+		c.JSON(http.StatusCreated, gin.H{"calls": calls})
+	})
+
+	key := uuid.NewString()
+	send := func() *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/flaky", bytes.NewBufferString("{}"))
+		req.Header.Set(middleware.IdempotencyKeyHeader, key)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(suite.T(), http.StatusInternalServerError, send().Code)
+
+	// The retry runs the handler instead of getting 409 until the key expires
+	retry := send()
+	assert.Equal(suite.T(), http.StatusCreated, retry.Code)
+	assert.Equal(suite.T(), 2, calls)
+}
+
+func (suite *IdempotencyTestSuite) TestPurgeDeletesExpiredKeys() {
+	ctx := context.Background()
+	now := time.Now()
+	stores := map[string]repository.Store{
+		"gorm":   repository.NewStore(database.GetDB()),
+		"memory": memory.NewStore(),
+	}
+	for name, store := range stores {
+		keys := store.Idempotency()
+		expired := models.IdempotencyKey{Caller: "purge", Key: "expired-" + name, RequestHash: "hash", ExpiresAt: now.Add(-time.Minute)}
+		live := models.IdempotencyKey{Caller: "purge", Key: "live-" + name, RequestHash: "hash", ExpiresAt: now.Add(time.Hour)}
+		suite.Require().NoError(keys.Claim(ctx, &expired))
+		suite.Require().NoError(keys.Claim(ctx, &live))
+
+		purged, err := keys.DeleteExpired(ctx, now)
+		suite.Require().NoError(err, name)
+		assert.Equal(suite.T(), int64(1), purged, name)
+
+		_, err = keys.Get(ctx, expired.Caller, expired.Key)
+		assert.ErrorIs(suite.T(), err, repository.ErrNotFound, name)
+		_, err = keys.Get(ctx, live.Caller, live.Key)
+		assert.NoError(suite.T(), err, name)
+	}
+}
+
+func TestIdempotencySuite(t *testing.T) {
+	suite.Run(t, new(IdempotencyTestSuite))
+}
@@ -0,0 +1,178 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"my-backend-app/config"
+	"my-backend-app/database"
+	"my-backend-app/handlers"
+	"my-backend-app/models"
+	"my-backend-app/repository"
+	"my-backend-app/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type IssuedVoucherHandlerTestSuite struct {
+	suite.Suite
+	router     *gin.Engine
+	brandID    uuid.UUID
+	customerID uuid.UUID
+	voucherID  uuid.UUID
+}
+
+func (suite *IssuedVoucherHandlerTestSuite) SetupSuite() {
+	// Set Gin to test mode
+	gin.SetMode(gin.TestMode)
+
+	// Set test mode environment variable
+	os.Setenv("TEST_MODE", "true")
+
+	// Load the test configuration
+	cfg, err := config.Load("config.env")
+	suite.Require().NoError(err)
+
+	// Initialize test database
+	database.InitDB(cfg)
+
+	// Create a test brand, voucher and customer
+	brand := models.Brand{Name: "Test Brand", IsActive: true}
+	database.GetDB().Create(&brand)
+	suite.brandID = brand.ID
+
+	voucher := models.Voucher{BrandID: brand.ID, Name: "Test Voucher", CostInPoint: 10, IsActive: true}
+	database.GetDB().Create(&voucher)
+	suite.voucherID = voucher.ID
+
+	db := database.GetDB()
+	services := service.New(repository.NewStore(db))
+	customer := models.Customer{Name: "Test Customer", Email: "codes@example.com", IsActive: true}
+	suite.Require().NoError(services.Customers.Create(context.Background(), &customer, 1000, "test"))
+	suite.customerID = customer.ID
+
+	// Setup router
+	h := handlers.New(services)
+	suite.router = gin.New()
+	suite.router.Use(asAdmin())
+	suite.router.POST("/transaction/redemption", h.CreateRedemption)
+	suite.router.GET("/brand/:id/voucher-codes/:code", h.GetBrandVoucherCode)
+	suite.router.POST("/brand/:id/voucher-codes/:code/burn", h.BurnVoucherCode)
+}
+
+func (suite *IssuedVoucherHandlerTestSuite) TearDownSuite() {
+	// Clean up test database if needed
+	if database.DB != nil {
+		sqlDB, err := database.DB.DB()
+		if err == nil {
+			sqlDB.Close()
+		}
+	}
+}
+
+func (suite *IssuedVoucherHandlerTestSuite) issueCode() string {
+	jsonData, _ := json.Marshal(handlers.RedemptionRequest{
+		CustomerID: suite.customerID.String(),
+		Items:      []handlers.RedemptionItem{{VoucherID: suite.voucherID.String(), Quantity: 1}},
+	})
+
+	req, _ := http.NewRequest("POST", "/transaction/redemption", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	suite.Require().Equal(http.StatusCreated, w.Code)
+
+	var response struct {
+		Data models.Transaction `json:"data"`
+	}
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &response))
+	return response.Data.Items[0].IssuedVouchers[0].Code
+}
+
+func (suite *IssuedVoucherHandlerTestSuite) burn(brandID uuid.UUID, code string) *httptest.ResponseRecorder {
+	jsonData, _ := json.Marshal(handlers.BurnVoucherCodeRequest{StoreID: "store-1", TerminalID: "pos-7"})
+
+	req, _ := http.NewRequest("POST", "/brand/"+brandID.String()+"/voucher-codes/"+code+"/burn", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	return w
+}
+
+func (suite *IssuedVoucherHandlerTestSuite) TestLookupVoucherCode() {
+	code := suite.issueCode()
+
+	// Codes are accepted case-insensitively
+	req, _ := http.NewRequest("GET", "/brand/"+suite.brandID.String()+"/voucher-codes/"+strings.ToLower(code), nil)
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+
+	// Assertions
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	var response map[string]interface{}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(suite.T(), err)
+
+	assert.Equal(suite.T(), true, response["valid"])
+}
+
+func (suite *IssuedVoucherHandlerTestSuite) TestBurnVoucherCode_OtherBrand() {
+	code := suite.issueCode()
+
+	w := suite.burn(uuid.New(), code)
+	assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+}
+
+func (suite *IssuedVoucherHandlerTestSuite) TestBurnVoucherCode_OnlyOnceUnderConcurrency() {
+	code := suite.issueCode()
+
+	const attempts = 10
+	codes := make([]int, attempts)
+
+	// Release all requests at once to maximise interleaving
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			codes[i] = suite.burn(suite.brandID, code).Code
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	succeeded := 0
+	for _, status := range codes {
+		if status == http.StatusOK {
+			succeeded++
+		} else {
+			assert.Equal(suite.T(), http.StatusConflict, status)
+		}
+	}
+	assert.Equal(suite.T(), 1, succeeded)
+
+	var issued models.IssuedVoucher
+	database.GetDB().First(&issued, "code = ?", code)
+	assert.Equal(suite.T(), models.IssuedVoucherStatusUsed, issued.Status)
+	assert.Equal(suite.T(), "store-1", issued.StoreID)
+	assert.NotNil(suite.T(), issued.UsedAt)
+}
+
+func TestIssuedVoucherHandlerSuite(t *testing.T) {
+	suite.Run(t, new(IssuedVoucherHandlerTestSuite))
+}
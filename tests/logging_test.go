@@ -0,0 +1,171 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"my-backend-app/config"
+	"my-backend-app/database"
+	"my-backend-app/logging"
+	"my-backend-app/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// logRecords decodes the JSON lines written to buf
+func logRecords(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	var records []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var record map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &record))
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestRedact(t *testing.T) {
+	cases := map[string]string{
+		"login for jane.doe+shop@example.co.id failed": "login for [REDACTED_EMAIL] failed",
+		"call +62 812-3456-7890 now":                   "call [REDACTED_PHONE] now",
+		"office (021) 555-0100":                        "office [REDACTED_PHONE]",
+		"phone = '081234567890'":                       "phone = '[REDACTED_PHONE]'",
+		// IDs, timestamps and amounts are left alone
+		"customer 550e8400-e29b-41d4-a716-046655440000": "customer 550e8400-e29b-41d4-a716-046655440000",
+		"at 2024-01-01 10:00:00.123+07:00":              "at 2024-01-01 10:00:00.123+07:00",
+		"redeemed 1500 points":                          "redeemed 1500 points",
+	}
+	for input, expected := range cases {
+		assert.Equal(t, expected, logging.Redact(input), input)
+	}
+}
+
+func TestLoggerAddsRequestIDAndRedacts(t *testing.T) {
+	var buf bytes.Buffer
+	logger := logging.New(config.Log{Level: config.LogLevelInfo}, &buf)
+	ctx := logging.WithRequestID(context.Background(), "req-1")
+
+	logger.With("customer", "jane@example.com").InfoContext(ctx, "Sent code to +62 812 3456 7890",
+		"error", errors.New("duplicate email jane@example.com"),
+		slog.Group("contact", "phone", "081234567890"))
+	logger.DebugContext(ctx, "not written at info level")
+
+	records := logRecords(t, &buf)
+	require.Len(t, records, 1)
+	record := records[0]
+	assert.Equal(t, "req-1", record["request_id"])
+	assert.Equal(t, "Sent code to [REDACTED_PHONE]", record["msg"])
+	assert.Equal(t, "[REDACTED_EMAIL]", record["customer"])
+	assert.Equal(t, "duplicate email [REDACTED_EMAIL]", record["error"])
+	assert.Equal(t, map[string]interface{}{"phone": "[REDACTED_PHONE]"}, record["contact"])
+}
+
+func TestRequestIDMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(logging.New(config.Log{Level: config.LogLevelInfo}, &buf))
+	defer slog.SetDefault(previous)
+
+	router := gin.New()
+	router.Use(middleware.RequestID(), middleware.Logger(), middleware.Recovery())
+	router.GET("/echo", func(c *gin.Context) {
+		c.String(http.StatusOK, logging.RequestID(c.Request.Context()))
+	})
+	router.GET("/panic", func(c *gin.Context) {
+		panic("boom")
+	})
+
+	get := func(path, requestID string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", path, nil)
+		if requestID != "" {
+			req.Header.Set(middleware.RequestIDHeader, requestID)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// A client's ID is kept, a missing or malformed one is replaced
+	w := get("/echo", "client-id-1")
+	assert.Equal(t, "client-id-1", w.Header().Get(middleware.RequestIDHeader))
+	assert.Equal(t, "client-id-1", w.Body.String())
+
+	w = get("/echo", "")
+	generated := w.Header().Get(middleware.RequestIDHeader)
+	assert.Len(t, generated, 36)
+	assert.Equal(t, generated, w.Body.String())
+
+	w = get("/echo", "has spaces")
+	assert.NotEqual(t, "has spaces", w.Header().Get(middleware.RequestIDHeader))
+
+	w = get("/panic", "panic-id")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+	records := logRecords(t, &buf)
+	require.Len(t, records, 5)
+	assert.Equal(t, "request", records[0]["msg"])
+	assert.Equal(t, "client-id-1", records[0]["request_id"])
+	assert.Equal(t, "/echo", records[0]["route"])
+	assert.Equal(t, float64(200), records[0]["status"])
+
+	// The panic and its access log record share the request ID
+	assert.Equal(t, "panic recovered", records[3]["msg"])
+	assert.Equal(t, "panic-id", records[3]["request_id"])
+	assert.Equal(t, "ERROR", records[4]["level"])
+	assert.Equal(t, "panic-id", records[4]["request_id"])
+}
+
+func TestGormLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := logging.New(config.Log{Level: config.LogLevelDebug}, &buf)
+	gormLogger := logging.NewGormLogger(logger, gormlogger.Warn, time.Hour)
+
+	db, err := database.Open(database.DriverSQLite, ":memory:", gormLogger)
+	require.NoError(t, err)
+	ctx := logging.WithRequestID(context.Background(), "req-sql")
+
+	// Only failures and slow queries are logged at the Warn level
+	require.NoError(t, db.WithContext(ctx).Exec("SELECT 1").Error)
+	assert.Empty(t, buf.String())
+
+	require.Error(t, db.WithContext(ctx).Exec("SELECT * FROM missing_table WHERE email = ?", "jane@example.com").Error)
+	records := logRecords(t, &buf)
+	require.Len(t, records, 1)
+	assert.Equal(t, "query failed", records[0]["msg"])
+	assert.Equal(t, "req-sql", records[0]["request_id"])
+	assert.Equal(t, "SELECT * FROM missing_table WHERE email = \"[REDACTED_EMAIL]\"", records[0]["sql"])
+	assert.Equal(t, false, records[0]["slow"])
+
+	// Every statement is slow with a tiny threshold
+	buf.Reset()
+	db.Logger = logging.NewGormLogger(logger, gormlogger.Warn, time.Nanosecond)
+	require.NoError(t, db.WithContext(ctx).Exec("SELECT 1").Error)
+	records = logRecords(t, &buf)
+	require.Len(t, records, 1)
+	assert.Equal(t, "slow query", records[0]["msg"])
+	assert.Equal(t, "WARN", records[0]["level"])
+	assert.Equal(t, true, records[0]["slow"])
+
+	// The Info level logs every statement at debug
+	buf.Reset()
+	db.Logger = logging.NewGormLogger(logger, gormlogger.Info, time.Hour)
+	require.NoError(t, db.WithContext(ctx).Exec("SELECT 1").Error)
+	records = logRecords(t, &buf)
+	require.Len(t, records, 1)
+	assert.Equal(t, "query", records[0]["msg"])
+	assert.Equal(t, "DEBUG", records[0]["level"])
+}
@@ -0,0 +1,76 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"my-backend-app/apierror"
+	"my-backend-app/database"
+	"my-backend-app/handlers"
+	"my-backend-app/metrics"
+	"my-backend-app/middleware"
+	"my-backend-app/routes"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm/logger"
+)
+
+func TestMetrics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	f := newMemoryFixture(t, 1, nil, 100)
+	brandID := f.voucher.BrandID.String()
+
+	h := handlers.New(f.services)
+	router := gin.New()
+	router.Use(middleware.Metrics(), asAdmin())
+	routes.SetupMetricsRoutes(router)
+	router.POST("/transaction/redemption", h.CreateRedemption)
+
+	redeem := func(body interface{}) int {
+		data, _ := json.Marshal(body)
+		req, _ := http.NewRequest("POST", "/transaction/redemption", bytes.NewBuffer(data))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+	request := handlers.RedemptionRequest{
+		CustomerID: f.customer.ID.String(),
+		Items:      []handlers.RedemptionItem{{VoucherID: f.voucher.ID.String(), Quantity: 1}},
+	}
+
+	completed := testutil.ToFloat64(metrics.Redemptions.WithLabelValues("completed"))
+	outOfStock := testutil.ToFloat64(metrics.RedemptionFailures.WithLabelValues(apierror.CodeOutOfStock))
+	invalid := testutil.ToFloat64(metrics.RedemptionFailures.WithLabelValues(apierror.CodeValidationFailed))
+	created := testutil.ToFloat64(metrics.HTTPRequests.WithLabelValues("POST", "/transaction/redemption", "201"))
+
+	assert.Equal(t, http.StatusCreated, redeem(request))
+	assert.Equal(t, http.StatusBadRequest, redeem(request))
+	assert.Equal(t, http.StatusBadRequest, redeem(handlers.RedemptionRequest{CustomerID: f.customer.ID.String()}))
+
+	assert.Equal(t, completed+1, testutil.ToFloat64(metrics.Redemptions.WithLabelValues("completed")))
+	assert.Equal(t, float64(10), testutil.ToFloat64(metrics.PointsRedeemed.WithLabelValues(brandID)))
+	assert.Equal(t, outOfStock+1, testutil.ToFloat64(metrics.RedemptionFailures.WithLabelValues(apierror.CodeOutOfStock)))
+	assert.Equal(t, invalid+1, testutil.ToFloat64(metrics.RedemptionFailures.WithLabelValues(apierror.CodeValidationFailed)))
+	assert.Equal(t, created+1, testutil.ToFloat64(metrics.HTTPRequests.WithLabelValues("POST", "/transaction/redemption", "201")))
+
+	// Pool statistics are exported for a registered database
+	db, err := database.Open(database.DriverSQLite, ":memory:", logger.Default.LogMode(logger.Silent))
+	require.NoError(t, err)
+	require.NoError(t, metrics.RegisterDB(db, "metrics_test"))
+
+	req, _ := http.NewRequest("GET", "/metrics", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	body := w.Body.String()
+	assert.Contains(t, body, `http_request_duration_seconds_bucket{method="POST",route="/transaction/redemption"`)
+	assert.Contains(t, body, `voucher_points_redeemed_total{brand_id="`+brandID+`"} 10`)
+	assert.Contains(t, body, `go_sql_max_open_connections{db_name="metrics_test"} 1`)
+}
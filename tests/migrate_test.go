@@ -0,0 +1,227 @@
+package tests
+
+import (
+	"bytes"
+	"io/fs"
+	"os"
+	"testing"
+	"testing/fstest"
+
+	"my-backend-app/config"
+	"my-backend-app/database"
+	"my-backend-app/migrations"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type MigrateTestSuite struct {
+	suite.Suite
+	db *gorm.DB
+}
+
+func (suite *MigrateTestSuite) SetupSuite() {
+	// Set test mode environment variable
+	os.Setenv("TEST_MODE", "true")
+
+	// Connect to the test database without migrating it
+	cfg, err := config.Load("config.env")
+	suite.Require().NoError(err)
+	driver, dsn := database.ConnectionSettings(cfg.Database)
+	db, err := database.Open(driver, dsn, logger.Default.LogMode(logger.Silent))
+	suite.Require().NoError(err)
+	suite.db = db
+}
+
+func (suite *MigrateTestSuite) TearDownSuite() {
+	if sqlDB, err := suite.db.DB(); err == nil {
+		sqlDB.Close()
+	}
+}
+
+// SetupTest gives every test an empty database
+func (suite *MigrateTestSuite) SetupTest() {
+	suite.Require().NoError(database.DropAllTables(suite.db))
+}
+
+// file names a migration file for the dialect of the test database
+func (suite *MigrateTestSuite) file(name string) string {
+	return suite.db.Dialector.Name() + "/" + name
+}
+
+func (suite *MigrateTestSuite) migrator(source fs.FS) *database.Migrator {
+	migrator, err := database.NewMigrator(suite.db, source)
+	suite.Require().NoError(err)
+	return migrator
+}
+
+func (suite *MigrateTestSuite) TestUpAndDownRoundTrip() {
+	migrator := suite.migrator(migrations.FS)
+	total := len(migrator.Migrations)
+	suite.Require().NotZero(total)
+
+	applied, err := migrator.Up(0)
+	suite.Require().NoError(err)
+	assert.Len(suite.T(), applied, total)
+	assert.True(suite.T(), suite.db.Migrator().HasTable("vouchers"))
+
+	version, err := migrator.Version()
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), migrator.Migrations[total-1].Version, version)
+
+	// Running again is a no-op
+	applied, err = migrator.Up(0)
+	suite.Require().NoError(err)
+	assert.Empty(suite.T(), applied)
+
+	rolledBack, err := migrator.Down(total)
+	suite.Require().NoError(err)
+	assert.Len(suite.T(), rolledBack, total)
+	assert.False(suite.T(), suite.db.Migrator().HasTable("vouchers"))
+
+	applied, err = migrator.Up(0)
+	suite.Require().NoError(err)
+	assert.Len(suite.T(), applied, total)
+}
+
+func (suite *MigrateTestSuite) TestUpToTarget() {
+	migrator := suite.migrator(migrations.FS)
+
+	applied, err := migrator.Up(2)
+	suite.Require().NoError(err)
+	assert.Len(suite.T(), applied, 2)
+	assert.True(suite.T(), suite.db.Migrator().HasTable("idempotency_keys"))
+	assert.False(suite.T(), suite.db.Migrator().HasTable("refunds"))
+
+	statuses, err := migrator.Status()
+	suite.Require().NoError(err)
+	assert.NotNil(suite.T(), statuses[1].AppliedAt)
+	assert.Nil(suite.T(), statuses[2].AppliedAt)
+}
+
+func (suite *MigrateTestSuite) TestDryRunLeavesDatabaseUntouched() {
+	migrator := suite.migrator(migrations.FS)
+	var out bytes.Buffer
+	migrator.DryRun = true
+	migrator.Out = &out
+
+	applied, err := migrator.Up(0)
+	suite.Require().NoError(err)
+	assert.Len(suite.T(), applied, len(migrator.Migrations))
+	assert.Contains(suite.T(), out.String(), "CREATE TABLE IF NOT EXISTS brands")
+
+	tables, err := suite.db.Migrator().GetTables()
+	suite.Require().NoError(err)
+	assert.Empty(suite.T(), tables)
+}
+
+func (suite *MigrateTestSuite) TestChecksumMismatch() {
+	source := fstest.MapFS{
+		suite.file("001_widgets.sql"): {Data: []byte("-- +migrate Up\nCREATE TABLE widgets (id INT);\n-- +migrate Down\nDROP TABLE widgets;\n")},
+	}
+	_, err := suite.migrator(source).Up(0)
+	suite.Require().NoError(err)
+
+	// Editing an applied migration is detected
+	source[suite.file("001_widgets.sql")] = &fstest.MapFile{Data: []byte("-- +migrate Up\nCREATE TABLE widgets (id INT, name TEXT);\n")}
+	_, err = suite.migrator(source).Up(0)
+	assert.ErrorIs(suite.T(), err, database.ErrChecksumMismatch)
+
+	// So is removing it
+	delete(source, suite.file("001_widgets.sql"))
+	source[suite.file("002_gadgets.sql")] = &fstest.MapFile{Data: []byte("-- +migrate Up\nCREATE TABLE gadgets (id INT);\n")}
+	_, err = suite.migrator(source).Up(0)
+	assert.ErrorIs(suite.T(), err, database.ErrUnknownMigration)
+}
+
+func (suite *MigrateTestSuite) TestIrreversibleMigration() {
+	source := fstest.MapFS{
+		suite.file("001_widgets.sql"): {Data: []byte("-- +migrate Up\nCREATE TABLE widgets (id INT);\nINSERT INTO widgets VALUES (1);\n")},
+	}
+	migrator := suite.migrator(source)
+	_, err := migrator.Up(0)
+	suite.Require().NoError(err)
+
+	_, err = migrator.Down(1)
+	assert.ErrorIs(suite.T(), err, database.ErrIrreversible)
+}
+
+func (suite *MigrateTestSuite) TestExistingSchemaNeedsBaseline() {
+	migrator := suite.migrator(migrations.FS)
+
+	// A schema created before the runner existed
+	_, err := migrator.Up(3)
+	suite.Require().NoError(err)
+	suite.Require().NoError(suite.db.Exec("DROP TABLE " + database.MigrationsTable).Error)
+
+	_, err = migrator.Up(0)
+	assert.ErrorIs(suite.T(), err, database.ErrNotBaselined)
+
+	baselined, err := migrator.Baseline(3)
+	suite.Require().NoError(err)
+	assert.Len(suite.T(), baselined, 3)
+
+	applied, err := migrator.Up(0)
+	suite.Require().NoError(err)
+	assert.Len(suite.T(), applied, len(migrator.Migrations)-3)
+}
+
+func (suite *MigrateTestSuite) TestDialectsHaveSameMigrations() {
+	expected, err := database.LoadMigrations(migrations.FS, database.DriverMySQL)
+	suite.Require().NoError(err)
+
+	for _, dialect := range []string{database.DriverPostgres, database.DriverSQLite} {
+		loaded, err := database.LoadMigrations(migrations.FS, dialect)
+		suite.Require().NoError(err)
+		suite.Require().Len(loaded, len(expected), dialect)
+		for i, migration := range loaded {
+			assert.Equal(suite.T(), expected[i].Version, migration.Version, dialect)
+			assert.Equal(suite.T(), expected[i].Name, migration.Name, dialect)
+			assert.Len(suite.T(), migration.Down, len(expected[i].Down), dialect+" "+migration.Name)
+		}
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	load := func(dialect, up string) []string {
+		source := fstest.MapFS{dialect + "/001_split.sql": {Data: []byte("-- +migrate Up\n" + up)}}
+		migrations, err := database.LoadMigrations(source, dialect)
+		require.NoError(t, err)
+		return migrations[0].Up
+	}
+
+	// Semicolons in strings, quoted identifiers and comments do not split
+	script := "INSERT INTO notes (body) VALUES ('a;b', 'it''s;'); -- done; really\n" +
+		`CREATE TABLE "odd;name" (id INT); /* one; two */ SELECT 1;`
+	for _, dialect := range []string{database.DriverMySQL, database.DriverPostgres, database.DriverSQLite} {
+		assert.Equal(t, []string{
+			"INSERT INTO notes (body) VALUES ('a;b', 'it''s;')",
+			`CREATE TABLE "odd;name" (id INT)`,
+			"SELECT 1",
+		}, load(dialect, script), dialect)
+	}
+
+	// MySQL strings understand backslash escapes, other dialects take them literally
+	assert.Equal(t, []string{`INSERT INTO notes VALUES ('it\'s;')`, "SELECT 1"},
+		load(database.DriverMySQL, `INSERT INTO notes VALUES ('it\'s;'); SELECT 1;`))
+	assert.Equal(t, []string{`INSERT INTO notes VALUES ('C:\')`, "SELECT 1"},
+		load(database.DriverPostgres, `INSERT INTO notes VALUES ('C:\'); SELECT 1;`))
+
+	// PostgreSQL function bodies are dollar quoted
+	function := `CREATE FUNCTION touch() RETURNS trigger AS $$
+BEGIN
+    NEW.updated_at = NOW();
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql`
+	tagged := `DO $body$ BEGIN PERFORM 1; END $body$`
+	assert.Equal(t, []string{function, tagged, "SELECT $1"},
+		load(database.DriverPostgres, function+";\n"+tagged+";\nSELECT $1;"))
+}
+
+func TestMigrateSuite(t *testing.T) {
+	suite.Run(t, new(MigrateTestSuite))
+}
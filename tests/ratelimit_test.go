@@ -0,0 +1,162 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"my-backend-app/auth"
+	"my-backend-app/config"
+	"my-backend-app/middleware"
+	"my-backend-app/models"
+	"my-backend-app/ratelimit"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStoreRefills(t *testing.T) {
+	store := ratelimit.NewMemoryStore()
+	limit := config.RateLimit{Requests: 2, Period: 10 * time.Second}
+	now := time.Now()
+	take := func(key string, at time.Time) ratelimit.Result {
+		result, err := store.Take(context.Background(), key, limit, at)
+		require.NoError(t, err)
+		return result
+	}
+
+	// A bucket starts full and empties one token per request
+	first := take("a", now)
+	assert.True(t, first.Allowed)
+	assert.Equal(t, 1, first.Remaining)
+	assert.Equal(t, 5*time.Second, first.Reset)
+	assert.True(t, take("a", now).Allowed)
+
+	denied := take("a", now)
+	assert.False(t, denied.Allowed)
+	assert.Equal(t, 0, denied.Remaining)
+	assert.Equal(t, 5*time.Second, denied.RetryAfter)
+	assert.Equal(t, 10*time.Second, denied.Reset)
+
+	// Other keys have buckets of their own
+	assert.True(t, take("b", now).Allowed)
+
+	// A token comes back every Period/Requests
+	assert.False(t, take("a", now.Add(4*time.Second)).Allowed)
+	assert.True(t, take("a", now.Add(5*time.Second)).Allowed)
+
+	// Buckets never hold more than Requests tokens
+	later := now.Add(time.Hour)
+	assert.Equal(t, 1, take("a", later).Remaining)
+}
+
+type failingStore struct{}
+
+func (failingStore) Take(context.Context, string, config.RateLimit, time.Time) (ratelimit.Result, error) {
+	return ratelimit.Result{}, errors.New("store unavailable")
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	limit := config.RateLimit{Requests: 2, Period: time.Minute}
+	customerA, customerB := uuid.New(), uuid.New()
+
+	newRouter := func(store ratelimit.Store, limit config.RateLimit) *gin.Engine {
+		router := gin.New()
+		router.Use(func(c *gin.Context) {
+			if id, err := uuid.Parse(c.GetHeader("X-Customer")); err == nil {
+				auth.SetPrincipal(c, &auth.Principal{Role: models.RoleCustomer, CustomerID: &id})
+			}
+			c.Next()
+		})
+		router.POST("/redemption", middleware.RateLimit(store, "redemption", limit, middleware.KeyByCustomer), func(c *gin.Context) {
+			c.Status(http.StatusCreated)
+		})
+		return router
+	}
+	send := func(router *gin.Engine, customer string, ip string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/redemption", nil)
+		req.Header.Set("X-Customer", customer)
+		req.RemoteAddr = ip + ":1234"
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	router := newRouter(ratelimit.NewMemoryStore(), limit)
+	w := send(router, customerA.String(), "10.0.0.1")
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "2", w.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", w.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "30", w.Header().Get("RateLimit-Reset"))
+	assert.Equal(t, "2;w=60", w.Header().Get("RateLimit-Policy"))
+
+	// The customer is limited wherever they call from
+	assert.Equal(t, http.StatusCreated, send(router, customerA.String(), "10.0.0.2").Code)
+	w = send(router, customerA.String(), "10.0.0.3")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "30", w.Header().Get("Retry-After"))
+	assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+	assert.JSONEq(t, `{"error":"Too many requests, retry later","code":"RATE_LIMITED"}`, w.Body.String())
+
+	// Other customers are not
+	assert.Equal(t, http.StatusCreated, send(router, customerB.String(), "10.0.0.1").Code)
+
+	// Anonymous requests fall back to the client IP
+	assert.Equal(t, http.StatusCreated, send(router, "", "10.0.0.9").Code)
+	assert.Equal(t, http.StatusCreated, send(router, "", "10.0.0.9").Code)
+	assert.Equal(t, http.StatusTooManyRequests, send(router, "", "10.0.0.9").Code)
+	assert.Equal(t, http.StatusCreated, send(router, "", "10.0.0.10").Code)
+
+	// Disabled limits, missing stores and failing stores let requests through
+	for _, router := range []*gin.Engine{
+		newRouter(ratelimit.NewMemoryStore(), config.RateLimit{}),
+		newRouter(nil, limit),
+		newRouter(failingStore{}, limit),
+	} {
+		for i := 0; i < 3; i++ {
+			w := send(router, customerA.String(), "10.0.0.1")
+			assert.Equal(t, http.StatusCreated, w.Code)
+			assert.Empty(t, w.Header().Get("RateLimit-Limit"))
+		}
+	}
+}
+
+func TestRateLimitIgnoresSpoofedForwardedFor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	limit := config.RateLimit{Requests: 1, Period: time.Minute}
+
+	newRouter := func(trustedProxies []string) *gin.Engine {
+		router := gin.New()
+		require.NoError(t, router.SetTrustedProxies(trustedProxies))
+		router.POST("/login", middleware.RateLimit(ratelimit.NewMemoryStore(), "auth", limit, middleware.KeyByIP), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+		return router
+	}
+	login := func(router *gin.Engine, forwardedFor string) int {
+		req := httptest.NewRequest(http.MethodPost, "/login", nil)
+		req.RemoteAddr = "192.0.2.10:40000"
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// By default no proxy is trusted, so a client making up a new
+	// X-Forwarded-For for every attempt still lands in the bucket of its address
+	router := newRouter(config.Default().Server.TrustedProxies)
+	assert.Equal(t, http.StatusOK, login(router, "203.0.113.1"))
+	assert.Equal(t, http.StatusTooManyRequests, login(router, "203.0.113.2"))
+
+	// Behind a trusted proxy the forwarded client IP picks the bucket
+	router = newRouter([]string{"192.0.2.0/24"})
+	assert.Equal(t, http.StatusOK, login(router, "203.0.113.1"))
+	assert.Equal(t, http.StatusOK, login(router, "203.0.113.2"))
+	assert.Equal(t, http.StatusTooManyRequests, login(router, "203.0.113.1"))
+}
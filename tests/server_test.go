@@ -0,0 +1,111 @@
+package tests
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"my-backend-app/config"
+	"my-backend-app/health"
+	"my-backend-app/routes"
+	"my-backend-app/server"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startServer serves a router with the health check and a /slow route that
+// blocks until release is closed. It returns the base URL and the result of
+// server.Serve.
+func startServer(t *testing.T, ctx context.Context, cfg config.Server, release <-chan struct{}) (string, <-chan error) {
+	gin.SetMode(gin.TestMode)
+	checker := health.NewChecker(nil, nil, nil)
+	r := gin.New()
+	routes.SetupHealthRoutes(r, checker)
+	r.GET("/slow", func(c *gin.Context) {
+		<-release
+		c.String(http.StatusOK, "done")
+	})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- server.Serve(ctx, server.New(cfg, r), listener, checker, cfg)
+	}()
+	return "http://" + listener.Addr().String(), done
+}
+
+func TestServerDrainsInFlightRequests(t *testing.T) {
+	cfg := config.Default().Server
+	cfg.DrainDelay = 200 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	release := make(chan struct{})
+	url, done := startServer(t, ctx, cfg, release)
+
+	resp, err := http.Get(url + "/readyz")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// Start a request that is still running when shutdown begins
+	slow := make(chan string, 1)
+	go func() {
+		resp, err := http.Get(url + "/slow")
+		if err != nil {
+			slow <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		slow <- string(body)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	// Readiness fails during the drain delay while requests are still served
+	require.Eventually(t, func() bool {
+		resp, err := http.Get(url + "/readyz")
+		if err != nil {
+			return false
+		}
+		resp.Body.Close()
+		return resp.StatusCode == http.StatusServiceUnavailable
+	}, time.Second, 10*time.Millisecond)
+
+	close(release)
+	assert.Equal(t, "done", <-slow)
+	assert.NoError(t, <-done)
+
+	_, err = http.Get(url + "/readyz")
+	assert.Error(t, err)
+}
+
+func TestServerShutdownDeadline(t *testing.T) {
+	cfg := config.Default().Server
+	cfg.ShutdownTimeout = 100 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	release := make(chan struct{})
+	defer close(release)
+	url, done := startServer(t, ctx, cfg, release)
+
+	go http.Get(url + "/slow")
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	// The request never finishes, so shutdown gives up at the deadline
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	case <-time.After(2 * time.Second):
+		t.Fatal("server did not stop at the shutdown deadline")
+	}
+}
@@ -0,0 +1,273 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"my-backend-app/apierror"
+	"my-backend-app/auth"
+	"my-backend-app/config"
+	"my-backend-app/handlers"
+	"my-backend-app/middleware"
+	"my-backend-app/models"
+	"my-backend-app/repository"
+	"my-backend-app/repository/memory"
+	"my-backend-app/routes"
+	"my-backend-app/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// These tests run the service layer on the in-memory repositories. Every test
+// gets its own store, so they run in parallel without a database.
+
+type memoryFixture struct {
+	store    *memory.Store
+	services *service.Services
+	voucher  models.Voucher
+	customer models.Customer
+}
+
+// newMemoryFixture creates a brand, a voucher costing 10 points with the
+// given stock and limit, and a customer with points
+func newMemoryFixture(t *testing.T, stock int, maxPerCustomer *int, points int) memoryFixture {
+	ctx := context.Background()
+	store := memory.NewStore()
+	services := service.New(store)
+
+	brand := models.Brand{Name: "Memory Brand", IsActive: true}
+	require.NoError(t, services.Brands.Create(ctx, &brand))
+
+	voucher := models.Voucher{
+		BrandID:        brand.ID,
+		Name:           "Memory Voucher",
+		CostInPoint:    10,
+		IsActive:       true,
+		TotalStock:     &stock,
+		MaxPerCustomer: maxPerCustomer,
+	}
+	require.NoError(t, services.Vouchers.Create(ctx, &voucher))
+
+	customer := models.Customer{Name: "Memory Customer", Email: "memory@example.com", IsActive: true}
+	require.NoError(t, services.Customers.Create(ctx, &customer, points, "test"))
+
+	return memoryFixture{store: store, services: services, voucher: voucher, customer: customer}
+}
+
+func (f memoryFixture) redeem(quantity int) (models.Transaction, error) {
+	items := []service.RedemptionItem{{VoucherID: f.voucher.ID.String(), Quantity: quantity}}
+	return f.services.Transactions.Redeem(context.Background(), f.customer.ID, items, "test")
+}
+
+func (f memoryFixture) state(t *testing.T) (points, stock int) {
+	ctx := context.Background()
+	customer, err := f.services.Customers.Get(ctx, f.customer.ID, false)
+	require.NoError(t, err)
+	voucher, err := f.services.Vouchers.Get(ctx, f.voucher.ID, false)
+	require.NoError(t, err)
+	return customer.Points, *voucher.RemainingStock
+}
+
+func TestMemoryRedeemAndCancel(t *testing.T) {
+	t.Parallel()
+	f := newMemoryFixture(t, 5, nil, 100)
+	ctx := context.Background()
+
+	transaction, err := f.redeem(3)
+	require.NoError(t, err)
+	assert.Equal(t, 30, transaction.TotalPoints)
+	require.Len(t, transaction.Items, 1)
+	assert.Len(t, transaction.Items[0].IssuedVouchers, 3)
+
+	points, stock := f.state(t)
+	assert.Equal(t, 70, points)
+	assert.Equal(t, 2, stock)
+
+	// Cancel one unit, then the rest
+	item := transaction.Items[0]
+	transaction, err = f.services.Transactions.CancelItem(ctx, transaction.ID, item.ID, 1, "Changed mind", "test")
+	require.NoError(t, err)
+	assert.Equal(t, models.TransactionStatusPartiallyRefunded, transaction.Status)
+	assert.Equal(t, 10, transaction.RefundedPoints)
+
+	transaction, err = f.services.Transactions.Cancel(ctx, transaction.ID, "Changed mind", "test")
+	require.NoError(t, err)
+	assert.Equal(t, models.TransactionStatusRefunded, transaction.Status)
+
+	points, stock = f.state(t)
+	assert.Equal(t, 100, points)
+	assert.Equal(t, 5, stock)
+
+	_, err = f.services.Transactions.Cancel(ctx, transaction.ID, "Again", "test")
+	var transitionErr *service.TransitionError
+	assert.True(t, errors.As(err, &transitionErr))
+}
+
+func TestMemoryFailedRedemptionRollsBack(t *testing.T) {
+	t.Parallel()
+	maxPerCustomer := 2
+	f := newMemoryFixture(t, 10, &maxPerCustomer, 100)
+
+	_, err := f.redeem(2)
+	require.NoError(t, err)
+
+	// The limit check fails after nothing is written, the points check after
+	// stock was reserved; neither may leave changes behind
+	_, err = f.redeem(1)
+	var limitErr *service.LimitError
+	assert.True(t, errors.As(err, &limitErr))
+
+	points, stock := f.state(t)
+	assert.Equal(t, 80, points)
+	assert.Equal(t, 8, stock)
+
+	g := newMemoryFixture(t, 10, nil, 15)
+	_, err = g.redeem(2)
+	assert.ErrorIs(t, err, service.ErrInsufficientPoints)
+
+	points, stock = g.state(t)
+	assert.Equal(t, 15, points)
+	assert.Equal(t, 10, stock)
+}
+
+func TestMemoryUnknownLimitPeriodFails(t *testing.T) {
+	t.Parallel()
+	f := newMemoryFixture(t, 10, nil, 100)
+	ctx := context.Background()
+
+	// Write a period the service would never accept straight to the store
+	voucher, err := f.store.Vouchers().Get(ctx, f.voucher.ID, false)
+	require.NoError(t, err)
+	maxPerPeriod := 1
+	voucher.MaxPerPeriod, voucher.LimitPeriod = &maxPerPeriod, "fortnight"
+	require.NoError(t, f.store.Vouchers().Update(ctx, &voucher))
+
+	// The redemption fails instead of skipping the per-period limit
+	_, err = f.redeem(2)
+	require.Error(t, err)
+	var limitErr *service.LimitError
+	assert.False(t, errors.As(err, &limitErr))
+
+	points, stock := f.state(t)
+	assert.Equal(t, 100, points)
+	assert.Equal(t, 10, stock)
+}
+
+func TestMemoryBurnVoucherCode(t *testing.T) {
+	t.Parallel()
+	f := newMemoryFixture(t, 5, nil, 100)
+	ctx := context.Background()
+
+	transaction, err := f.redeem(1)
+	require.NoError(t, err)
+	code := transaction.Items[0].IssuedVouchers[0].Code
+
+	issued, err := f.services.VoucherCodes.Burn(ctx, f.voucher.BrandID, " "+code+" ", "store-1", "")
+	require.NoError(t, err)
+	assert.Equal(t, models.IssuedVoucherStatusUsed, issued.Status)
+
+	_, err = f.services.VoucherCodes.Burn(ctx, f.voucher.BrandID, code, "store-1", "")
+	assert.ErrorIs(t, err, service.ErrVoucherCodeUsed)
+
+	_, err = f.services.VoucherCodes.Burn(ctx, uuid.New(), code, "store-1", "")
+	assert.ErrorIs(t, err, service.ErrVoucherCodeNotFound)
+}
+
+func TestMemoryAPIKeys(t *testing.T) {
+	t.Parallel()
+	f := newMemoryFixture(t, 1, nil, 0)
+	ctx := context.Background()
+
+	key := models.APIKey{Name: "customer", Role: models.RoleCustomer, CustomerID: &f.customer.ID}
+	plaintext, err := f.services.APIKeys.Create(ctx, &key)
+	require.NoError(t, err)
+	assert.Equal(t, auth.HashKey(plaintext), key.KeyHash)
+	assert.Equal(t, plaintext[:len(key.Prefix)], key.Prefix)
+
+	_, err = f.services.APIKeys.Create(ctx, &models.APIKey{Name: "unscoped", Role: models.RoleCustomer})
+	assert.ErrorIs(t, err, auth.ErrInvalidScope)
+
+	keys, total, err := f.services.APIKeys.List(ctx, repository.ListOptions{Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	require.Len(t, keys, 1)
+	assert.Equal(t, key.ID, keys[0].ID)
+
+	require.NoError(t, f.services.APIKeys.Revoke(ctx, key.ID))
+	assert.ErrorIs(t, f.services.APIKeys.Revoke(ctx, key.ID), service.ErrAPIKeyNotFound)
+}
+
+func TestMemoryVoucherUpdate(t *testing.T) {
+	t.Parallel()
+	f := newMemoryFixture(t, 5, nil, 0)
+	ctx := context.Background()
+
+	other := models.Brand{Name: "Other Brand", IsActive: true}
+	require.NoError(t, f.services.Brands.Create(ctx, &other))
+
+	// Only the given fields change
+	name := "Renamed"
+	voucher, err := f.services.Vouchers.Update(ctx, f.voucher.ID, service.VoucherChanges{FromBrandID: f.voucher.BrandID, Name: &name})
+	require.NoError(t, err)
+	assert.Equal(t, "Renamed", voucher.Name)
+	assert.Equal(t, 10, voucher.CostInPoint)
+	require.NotNil(t, voucher.TotalStock)
+
+	// A replacement clears the limits it leaves out
+	cost := 20
+	voucher, err = f.services.Vouchers.Update(ctx, f.voucher.ID, service.VoucherChanges{FromBrandID: f.voucher.BrandID, BrandID: &other.ID, CostInPoint: &cost, Replace: true})
+	require.NoError(t, err)
+	assert.Equal(t, other.ID, voucher.BrandID)
+	assert.Nil(t, voucher.TotalStock)
+	assert.Nil(t, voucher.RemainingStock)
+
+	// A caller authorized for the old brand cannot update the moved voucher
+	_, err = f.services.Vouchers.Update(ctx, f.voucher.ID, service.VoucherChanges{FromBrandID: f.voucher.BrandID, Name: &name})
+	assert.ErrorIs(t, err, service.ErrVoucherBrandChanged)
+}
+
+func TestMemoryRedemptionHandler(t *testing.T) {
+	t.Parallel()
+	f := newMemoryFixture(t, 1, nil, 100)
+
+	// The full API, including API keys and idempotency records, runs on the store
+	router := gin.New()
+	routes.SetupRoutes(router, f.services, f.store, config.RateLimits{}, nil)
+	apiKey, err := f.services.APIKeys.Create(context.Background(), &models.APIKey{Name: "admin", Role: models.RoleAdmin})
+	require.NoError(t, err)
+
+	redeem := func(quantity int, idempotencyKey string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(handlers.RedemptionRequest{
+			CustomerID: f.customer.ID.String(),
+			Items:      []handlers.RedemptionItem{{VoucherID: f.voucher.ID.String(), Quantity: quantity}},
+		})
+		req, _ := http.NewRequest("POST", "/api/v1/transaction/redemption", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(middleware.APIKeyHeader, apiKey)
+		req.Header.Set(middleware.IdempotencyKeyHeader, idempotencyKey)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusCreated, redeem(1, "first").Code)
+
+	// A retry is replayed instead of running out of stock
+	w := redeem(1, "first")
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "true", w.Header().Get("Idempotent-Replayed"))
+
+	w = redeem(1, "second")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var response apierror.Error
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, apierror.CodeOutOfStock, response.Code)
+}
@@ -0,0 +1,99 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"my-backend-app/config"
+	"my-backend-app/database"
+	"my-backend-app/logging"
+	"my-backend-app/middleware"
+	"my-backend-app/tracing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+func TestTracing(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var logs bytes.Buffer
+	logger := logging.New(config.Log{Level: config.LogLevelInfo}, &logs)
+	db, err := database.Open(database.DriverSQLite, ":memory:", gormlogger.Default.LogMode(gormlogger.Silent))
+	require.NoError(t, err)
+	require.NoError(t, db.Use(tracing.GormPlugin{}))
+	require.NoError(t, db.Exec("CREATE TABLE widgets (id INTEGER PRIMARY KEY, name TEXT)").Error)
+
+	// Install the propagators and record spans in memory
+	shutdown, err := tracing.Setup(context.Background(), config.Tracing{Exporter: config.TracingExporterNone})
+	require.NoError(t, err)
+	defer shutdown(context.Background())
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(tracing.NewProvider(config.Default().Tracing, sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(noop.NewTracerProvider())
+
+	router := gin.New()
+	router.Use(middleware.Tracing())
+	router.GET("/widgets/:id", func(c *gin.Context) {
+		var names []string
+		db.WithContext(c.Request.Context()).Table("widgets").Where("id = ?", c.Param("id")).Pluck("name", &names)
+		logger.InfoContext(c.Request.Context(), "listed widgets")
+		c.JSON(http.StatusOK, names)
+	})
+	router.GET("/fail", func(c *gin.Context) {
+		db.WithContext(c.Request.Context()).Exec("SELECT * FROM missing_table")
+		c.Status(http.StatusInternalServerError)
+	})
+
+	// Continue the caller's trace from its traceparent header
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req, _ := http.NewRequest("GET", "/widgets/7", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("traceparent"), traceID)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	query, server := spans[0], spans[1]
+
+	assert.Equal(t, "GET /widgets/:id", server.Name())
+	assert.Equal(t, trace.SpanKindServer, server.SpanKind())
+	assert.Equal(t, traceID, server.SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", server.Parent().SpanID().String())
+
+	assert.Equal(t, "gorm.query widgets", query.Name())
+	assert.Equal(t, trace.SpanKindClient, query.SpanKind())
+	assert.Equal(t, server.SpanContext().SpanID(), query.Parent().SpanID())
+	attrs := map[string]string{}
+	for _, attr := range query.Attributes() {
+		attrs[string(attr.Key)] = attr.Value.Emit()
+	}
+	assert.Equal(t, "sqlite", attrs["db.system"])
+	assert.Equal(t, "SELECT `name` FROM `widgets` WHERE id = ?", attrs["db.query.text"])
+
+	// Logs written while handling the request carry the trace
+	assert.Contains(t, logs.String(), `"trace_id":"`+traceID+`"`)
+
+	// Failed queries and server errors mark their spans as errors
+	req, _ = http.NewRequest("GET", "/fail", nil)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	spans = recorder.Ended()[2:]
+	require.Len(t, spans, 2)
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+	assert.Equal(t, "gorm.raw", spans[0].Name())
+	assert.Equal(t, codes.Error, spans[1].Status().Code)
+	assert.NotEqual(t, traceID, spans[1].SpanContext().TraceID().String())
+}
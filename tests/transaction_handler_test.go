@@ -0,0 +1,561 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"my-backend-app/apierror"
+	"my-backend-app/config"
+	"my-backend-app/database"
+	"my-backend-app/eligibility"
+	"my-backend-app/handlers"
+	"my-backend-app/models"
+	"my-backend-app/repository"
+	"my-backend-app/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type TransactionHandlerTestSuite struct {
+	suite.Suite
+	router    *gin.Engine
+	services  *service.Services
+	brandID   uuid.UUID
+	voucherID uuid.UUID
+}
+
+func (suite *TransactionHandlerTestSuite) SetupSuite() {
+	// Set Gin to test mode
+	gin.SetMode(gin.TestMode)
+
+	// Set test mode environment variable
+	os.Setenv("TEST_MODE", "true")
+
+	// Load the test configuration
+	cfg, err := config.Load("config.env")
+	suite.Require().NoError(err)
+
+	// Initialize test database
+	database.InitDB(cfg)
+
+	// Create a test brand and voucher
+	brand := models.Brand{
+		Name:     "Test Brand",
+		IsActive: true,
+	}
+	database.GetDB().Create(&brand)
+	suite.brandID = brand.ID
+
+	voucher := models.Voucher{
+		BrandID:     brand.ID,
+		Name:        "Test Voucher",
+		CostInPoint: 30,
+		IsActive:    true,
+	}
+	database.GetDB().Create(&voucher)
+	suite.voucherID = voucher.ID
+
+	// Setup router
+	db := database.GetDB()
+	suite.services = service.New(repository.NewStore(db))
+	h := handlers.New(suite.services)
+	suite.router = gin.New()
+	suite.router.Use(asAdmin())
+	suite.router.POST("/transaction/redemption", h.CreateRedemption)
+	suite.router.GET("/transaction/redemption", h.GetTransactionDetail)
+	suite.router.GET("/transaction/customer", h.GetCustomerTransactions)
+	suite.router.POST("/transaction/redemption/:id/cancel", h.CancelRedemption)
+	suite.router.POST("/transaction/redemption/:id/items/:itemId/cancel", h.CancelRedemptionItem)
+	suite.router.GET("/customer/:id/vouchers", h.GetCustomerVouchers)
+}
+
+func (suite *TransactionHandlerTestSuite) TearDownSuite() {
+	// Clean up test database if needed
+	if database.DB != nil {
+		sqlDB, err := database.DB.DB()
+		if err == nil {
+			sqlDB.Close()
+		}
+	}
+}
+
+func (suite *TransactionHandlerTestSuite) createCustomer(points int) models.Customer {
+	// Points go through the ledger so that redemptions find lots to consume
+	customer := models.Customer{
+		Name:     "Test Customer",
+		Email:    uuid.NewString() + "@example.com",
+		IsActive: true,
+	}
+	suite.Require().NoError(suite.services.Customers.Create(context.Background(), &customer, points, "test"))
+	return customer
+}
+
+func (suite *TransactionHandlerTestSuite) redeem(customerID uuid.UUID, quantity int) *httptest.ResponseRecorder {
+	return suite.redeemVoucher(customerID, suite.voucherID, quantity)
+}
+
+func (suite *TransactionHandlerTestSuite) redeemVoucher(customerID, voucherID uuid.UUID, quantity int) *httptest.ResponseRecorder {
+	redemptionData := handlers.RedemptionRequest{
+		CustomerID: customerID.String(),
+		Items: []handlers.RedemptionItem{
+			{VoucherID: voucherID.String(), Quantity: quantity},
+		},
+	}
+
+	jsonData, _ := json.Marshal(redemptionData)
+
+	req, _ := http.NewRequest("POST", "/transaction/redemption", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	return w
+}
+
+func (suite *TransactionHandlerTestSuite) TestCreateRedemption_Success() {
+	customer := suite.createCustomer(100)
+
+	w := suite.redeem(customer.ID, 2)
+
+	// Assertions
+	assert.Equal(suite.T(), http.StatusCreated, w.Code)
+
+	var response map[string]interface{}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(suite.T(), err)
+
+	assert.Equal(suite.T(), "Redemption successful", response["message"])
+
+	var updated models.Customer
+	database.GetDB().First(&updated, "id = ?", customer.ID)
+	assert.Equal(suite.T(), 40, updated.Points)
+}
+
+func (suite *TransactionHandlerTestSuite) TestCreateRedemption_ValidationErrorsListFields() {
+	w := suite.redeemItems(uuid.Nil, []handlers.RedemptionItem{{VoucherID: suite.voucherID.String(), Quantity: 0}})
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	var response struct {
+		Code    string                `json:"code"`
+		Details []apierror.FieldError `json:"details"`
+	}
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(suite.T(), apierror.CodeValidationFailed, response.Code)
+	assert.Equal(suite.T(), []apierror.FieldError{{Field: "items[0].quantity", Message: "is required"}}, response.Details)
+}
+
+func (suite *TransactionHandlerTestSuite) TestCreateRedemption_InsufficientPoints() {
+	customer := suite.createCustomer(50)
+
+	w := suite.redeem(customer.ID, 2)
+
+	// Assertions
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	var response map[string]interface{}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(suite.T(), err)
+
+	assert.Equal(suite.T(), apierror.CodeInsufficientPoints, response["code"])
+}
+
+func (suite *TransactionHandlerTestSuite) TestCreateRedemption_ConcurrentRequestsNeverOverspend() {
+	// 100 points cover exactly three 30-point vouchers
+	customer := suite.createCustomer(100)
+
+	const attempts = 20
+	codes := make([]int, attempts)
+
+	// Release all requests at once to maximise interleaving
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			codes[i] = suite.redeem(customer.ID, 1).Code
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	succeeded := 0
+	for _, code := range codes {
+		if code == http.StatusCreated {
+			succeeded++
+		} else {
+			assert.Equal(suite.T(), http.StatusBadRequest, code)
+		}
+	}
+	assert.Equal(suite.T(), 3, succeeded)
+
+	var updated models.Customer
+	database.GetDB().First(&updated, "id = ?", customer.ID)
+	assert.Equal(suite.T(), 10, updated.Points)
+	assert.GreaterOrEqual(suite.T(), updated.Points, 0)
+
+	var spent int64
+	database.GetDB().Model(&models.Transaction{}).
+		Where("customer_id = ?", customer.ID).
+		Select("COALESCE(SUM(total_points), 0)").Scan(&spent)
+	assert.Equal(suite.T(), int64(90), spent)
+}
+
+func (suite *TransactionHandlerTestSuite) redeemTransaction(customerID uuid.UUID, quantity int) models.Transaction {
+	w := suite.redeem(customerID, quantity)
+	suite.Require().Equal(http.StatusCreated, w.Code)
+
+	var response struct {
+		Data models.Transaction `json:"data"`
+	}
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &response))
+	return response.Data
+}
+
+func (suite *TransactionHandlerTestSuite) cancel(path string, body interface{}) *httptest.ResponseRecorder {
+	jsonData, _ := json.Marshal(body)
+
+	req, _ := http.NewRequest("POST", path, bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	return w
+}
+
+func (suite *TransactionHandlerTestSuite) TestCancelRedemption_RefundsAllPoints() {
+	customer := suite.createCustomer(100)
+	transaction := suite.redeemTransaction(customer.ID, 3)
+
+	w := suite.cancel("/transaction/redemption/"+transaction.ID.String()+"/cancel",
+		handlers.CancelRedemptionRequest{Reason: "Customer request"})
+
+	// Assertions
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	var response struct {
+		Data models.Transaction `json:"data"`
+	}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(suite.T(), err)
+
+	assert.Equal(suite.T(), models.TransactionStatusRefunded, response.Data.Status)
+	assert.Equal(suite.T(), 90, response.Data.RefundedPoints)
+	assert.Len(suite.T(), response.Data.Refunds, 1)
+	assert.Equal(suite.T(), "Customer request", response.Data.Refunds[0].Reason)
+
+	var updated models.Customer
+	database.GetDB().First(&updated, "id = ?", customer.ID)
+	assert.Equal(suite.T(), 100, updated.Points)
+}
+
+func (suite *TransactionHandlerTestSuite) TestCancelRedemption_AlreadyRefunded() {
+	customer := suite.createCustomer(100)
+	transaction := suite.redeemTransaction(customer.ID, 1)
+	path := "/transaction/redemption/" + transaction.ID.String() + "/cancel"
+
+	first := suite.cancel(path, handlers.CancelRedemptionRequest{Reason: "Customer request"})
+	assert.Equal(suite.T(), http.StatusOK, first.Code)
+
+	second := suite.cancel(path, handlers.CancelRedemptionRequest{Reason: "Customer request"})
+	assert.Equal(suite.T(), http.StatusConflict, second.Code)
+
+	// Points must only be refunded once
+	var updated models.Customer
+	database.GetDB().First(&updated, "id = ?", customer.ID)
+	assert.Equal(suite.T(), 100, updated.Points)
+}
+
+func (suite *TransactionHandlerTestSuite) TestCancelRedemptionItem_PartialThenFull() {
+	customer := suite.createCustomer(100)
+	transaction := suite.redeemTransaction(customer.ID, 3)
+	path := "/transaction/redemption/" + transaction.ID.String() + "/items/" + transaction.Items[0].ID.String() + "/cancel"
+
+	w := suite.cancel(path, handlers.CancelRedemptionItemRequest{Quantity: 1, Reason: "Damaged"})
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	var response struct {
+		Data models.Transaction `json:"data"`
+	}
+	assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(suite.T(), models.TransactionStatusPartiallyRefunded, response.Data.Status)
+	assert.Equal(suite.T(), 30, response.Data.RefundedPoints)
+
+	// Cancelling more than what is left is rejected
+	w = suite.cancel(path, handlers.CancelRedemptionItemRequest{Quantity: 5, Reason: "Damaged"})
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	// Omitting the quantity cancels the rest of the item
+	w = suite.cancel(path, handlers.CancelRedemptionItemRequest{Reason: "Damaged"})
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(suite.T(), models.TransactionStatusRefunded, response.Data.Status)
+
+	var updated models.Customer
+	database.GetDB().First(&updated, "id = ?", customer.ID)
+	assert.Equal(suite.T(), 100, updated.Points)
+}
+
+func (suite *TransactionHandlerTestSuite) TestCreateRedemption_IssuesUniqueCodes() {
+	customer := suite.createCustomer(100)
+	transaction := suite.redeemTransaction(customer.ID, 3)
+
+	suite.Require().Len(transaction.Items, 1)
+	issued := transaction.Items[0].IssuedVouchers
+	assert.Len(suite.T(), issued, 3)
+
+	codes := make(map[string]bool)
+	for _, voucher := range issued {
+		assert.Equal(suite.T(), models.IssuedVoucherStatusIssued, voucher.Status)
+		assert.Regexp(suite.T(), `^[2-9A-Z]{4}-[2-9A-Z]{4}-[2-9A-Z]{4}-[2-9A-Z]{4}$`, voucher.Code)
+		codes[voucher.Code] = true
+	}
+	assert.Len(suite.T(), codes, 3)
+
+	req, _ := http.NewRequest("GET", "/customer/"+customer.ID.String()+"/vouchers?status=issued", nil)
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	var response struct {
+		Data []models.IssuedVoucher `json:"data"`
+	}
+	assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Len(suite.T(), response.Data, 3)
+}
+
+func (suite *TransactionHandlerTestSuite) TestCancelRedemptionItem_VoidsCodes() {
+	customer := suite.createCustomer(100)
+	transaction := suite.redeemTransaction(customer.ID, 2)
+	item := transaction.Items[0]
+
+	// Burn one of the two codes
+	database.GetDB().Model(&models.IssuedVoucher{}).Where("id = ?", item.IssuedVouchers[0].ID).Update("status", models.IssuedVoucherStatusUsed)
+
+	path := "/transaction/redemption/" + transaction.ID.String() + "/items/" + item.ID.String() + "/cancel"
+
+	// Only the unused unit can be cancelled
+	w := suite.cancel(path, handlers.CancelRedemptionItemRequest{Quantity: 2, Reason: "Changed mind"})
+	assert.Equal(suite.T(), http.StatusConflict, w.Code)
+
+	w = suite.cancel(path, handlers.CancelRedemptionItemRequest{Quantity: 1, Reason: "Changed mind"})
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	var void int64
+	database.GetDB().Model(&models.IssuedVoucher{}).Where("transaction_item_id = ? AND status = ?", item.ID, models.IssuedVoucherStatusVoid).Count(&void)
+	assert.Equal(suite.T(), int64(1), void)
+}
+
+func (suite *TransactionHandlerTestSuite) TestCreateRedemption_VoucherStock() {
+	stock := 2
+	voucher := models.Voucher{
+		BrandID:        suite.brandID,
+		Name:           "Limited Voucher",
+		CostInPoint:    10,
+		IsActive:       true,
+		TotalStock:     &stock,
+		RemainingStock: &stock,
+	}
+	database.GetDB().Create(&voucher)
+
+	customer := suite.createCustomer(100)
+
+	// More units than remain are rejected without touching stock
+	w := suite.redeemVoucher(customer.ID, voucher.ID, 3)
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), "Voucher is out of stock")
+
+	w = suite.redeemVoucher(customer.ID, voucher.ID, 2)
+	suite.Require().Equal(http.StatusCreated, w.Code)
+
+	var updated models.Voucher
+	database.GetDB().First(&updated, "id = ?", voucher.ID)
+	assert.Equal(suite.T(), 0, *updated.RemainingStock)
+	assert.Equal(suite.T(), 2, *updated.TotalStock)
+
+	// Cancelling returns the units to stock
+	var response struct {
+		Data models.Transaction `json:"data"`
+	}
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &response))
+	w = suite.cancel("/transaction/redemption/"+response.Data.ID.String()+"/cancel",
+		handlers.CancelRedemptionRequest{Reason: "Customer request"})
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	database.GetDB().First(&updated, "id = ?", voucher.ID)
+	assert.Equal(suite.T(), 2, *updated.RemainingStock)
+}
+
+func (suite *TransactionHandlerTestSuite) createLimitedVoucher(maxPerCustomer, maxPerPeriod *int, period string) models.Voucher {
+	voucher := models.Voucher{
+		BrandID:        suite.brandID,
+		Name:           "Limited Voucher",
+		CostInPoint:    10,
+		IsActive:       true,
+		MaxPerCustomer: maxPerCustomer,
+		MaxPerPeriod:   maxPerPeriod,
+		LimitPeriod:    period,
+	}
+	database.GetDB().Create(&voucher)
+	return voucher
+}
+
+func (suite *TransactionHandlerTestSuite) TestCreateRedemption_PerCustomerLimit() {
+	limit := 2
+	voucher := suite.createLimitedVoucher(&limit, nil, "")
+	customer := suite.createCustomer(100)
+
+	w := suite.redeemVoucher(customer.ID, voucher.ID, 2)
+	assert.Equal(suite.T(), http.StatusCreated, w.Code)
+
+	w = suite.redeemVoucher(customer.ID, voucher.ID, 1)
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	var response map[string]interface{}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), apierror.CodeCustomerLimitExceeded, response["code"])
+
+	// The limit is per customer
+	other := suite.createCustomer(100)
+	w = suite.redeemVoucher(other.ID, voucher.ID, 1)
+	assert.Equal(suite.T(), http.StatusCreated, w.Code)
+}
+
+func (suite *TransactionHandlerTestSuite) TestCreateRedemption_PerPeriodLimit() {
+	limit := 1
+	voucher := suite.createLimitedVoucher(nil, &limit, models.LimitPeriodDay)
+	customer := suite.createCustomer(100)
+
+	w := suite.redeemVoucher(customer.ID, voucher.ID, 1)
+	assert.Equal(suite.T(), http.StatusCreated, w.Code)
+
+	w = suite.redeemVoucher(customer.ID, voucher.ID, 1)
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	var response map[string]interface{}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), apierror.CodePeriodLimitExceeded, response["code"])
+
+	// Redemptions outside the window no longer count
+	database.GetDB().Model(&models.TransactionItem{}).
+		Where("voucher_id = ?", voucher.ID).
+		Update("created_at", time.Now().Add(-48*time.Hour))
+
+	w = suite.redeemVoucher(customer.ID, voucher.ID, 1)
+	assert.Equal(suite.T(), http.StatusCreated, w.Code)
+}
+
+func (suite *TransactionHandlerTestSuite) TestGetTransactionDetail_AfterVoucherDeleted() {
+	voucher := suite.createLimitedVoucher(nil, nil, "")
+	customer := suite.createCustomer(100)
+
+	w := suite.redeemVoucher(customer.ID, voucher.ID, 1)
+	suite.Require().Equal(http.StatusCreated, w.Code)
+
+	var created struct {
+		Data models.Transaction `json:"data"`
+	}
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &created))
+
+	database.GetDB().Delete(&models.Voucher{}, "id = ?", voucher.ID)
+	database.GetDB().Delete(&models.Customer{}, "id = ?", customer.ID)
+
+	req, _ := http.NewRequest("GET", "/transaction/redemption?transactionId="+created.Data.ID.String(), nil)
+	w = httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	var response struct {
+		Data models.Transaction `json:"data"`
+	}
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &response))
+
+	// Historical transactions still show what was redeemed and by whom
+	assert.Equal(suite.T(), voucher.Name, response.Data.Items[0].Voucher.Name)
+	assert.Equal(suite.T(), "Test Brand", response.Data.Items[0].Voucher.Brand.Name)
+	assert.Equal(suite.T(), customer.Email, response.Data.Customer.Email)
+}
+
+func (suite *TransactionHandlerTestSuite) redeemItems(customerID uuid.UUID, items []handlers.RedemptionItem) *httptest.ResponseRecorder {
+	jsonData, _ := json.Marshal(handlers.RedemptionRequest{CustomerID: customerID.String(), Items: items})
+
+	req, _ := http.NewRequest("POST", "/transaction/redemption", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	return w
+}
+
+func (suite *TransactionHandlerTestSuite) TestCreateRedemption_InactiveCustomer() {
+	customer := suite.createCustomer(100)
+	database.GetDB().Model(&customer).Update("is_active", false)
+
+	w := suite.redeem(customer.ID, 1)
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	var response struct {
+		Code    string             `json:"code"`
+		Details eligibility.Result `json:"details"`
+	}
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(suite.T(), apierror.CodeNotEligible, response.Code)
+	suite.Require().Len(response.Details.Customer, 1)
+	assert.Equal(suite.T(), eligibility.CodeCustomerInactive, response.Details.Customer[0].Code)
+}
+
+func (suite *TransactionHandlerTestSuite) TestCreateRedemption_ReportsEachIneligibleItem() {
+	inactiveBrand := models.Brand{Name: "Inactive Brand", IsActive: false}
+	database.GetDB().Create(&inactiveBrand)
+	database.GetDB().Model(&inactiveBrand).Update("is_active", false)
+
+	brandVoucher := models.Voucher{BrandID: inactiveBrand.ID, Name: "Brand Voucher", CostInPoint: 10, IsActive: true}
+	database.GetDB().Create(&brandVoucher)
+
+	expired := suite.createLimitedVoucher(nil, nil, "")
+	database.GetDB().Model(&expired).Update("valid_to", time.Now().Add(-time.Hour))
+
+	customer := suite.createCustomer(100)
+	w := suite.redeemItems(customer.ID, []handlers.RedemptionItem{
+		{VoucherID: suite.voucherID.String(), Quantity: 1},
+		{VoucherID: brandVoucher.ID.String(), Quantity: 1},
+		{VoucherID: expired.ID.String(), Quantity: 1},
+		{VoucherID: uuid.NewString(), Quantity: 1},
+	})
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	var response struct {
+		Details eligibility.Result `json:"details"`
+	}
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &response))
+	suite.Require().Len(response.Details.Items, 3)
+
+	assert.Equal(suite.T(), 1, response.Details.Items[0].Index)
+	assert.Equal(suite.T(), eligibility.CodeBrandInactive, response.Details.Items[0].Reasons[0].Code)
+	assert.Equal(suite.T(), 2, response.Details.Items[1].Index)
+	assert.Equal(suite.T(), eligibility.CodeVoucherExpired, response.Details.Items[1].Reasons[0].Code)
+	assert.Equal(suite.T(), 3, response.Details.Items[2].Index)
+	assert.Equal(suite.T(), eligibility.CodeVoucherNotFound, response.Details.Items[2].Reasons[0].Code)
+
+	// Nothing was charged for the eligible item either
+	var updated models.Customer
+	database.GetDB().First(&updated, "id = ?", customer.ID)
+	assert.Equal(suite.T(), 100, updated.Points)
+}
+
+func TestTransactionHandlerSuite(t *testing.T) {
+	suite.Run(t, new(TransactionHandlerTestSuite))
+}
@@ -9,13 +9,16 @@ import (
 	"testing"
 	"time"
 
+	"my-backend-app/apierror"
+	"my-backend-app/config"
 	"my-backend-app/database"
 	"my-backend-app/handlers"
 	"my-backend-app/models"
+	"my-backend-app/repository"
+	"my-backend-app/service"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"github.com/joho/godotenv"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 )
@@ -33,11 +36,12 @@ func (suite *VoucherHandlerTestSuite) SetupSuite() {
 	// Set test mode environment variable
 	os.Setenv("TEST_MODE", "true")
 
-	// Load test environment variables
-	godotenv.Load("config.env")
+	// Load the test configuration
+	cfg, err := config.Load("config.env")
+	suite.Require().NoError(err)
 
 	// Initialize test database
-	database.InitDB()
+	database.InitDB(cfg)
 
 	// Create a test brand
 	brand := models.Brand{
@@ -51,11 +55,18 @@ func (suite *VoucherHandlerTestSuite) SetupSuite() {
 	suite.brandID = brand.ID
 
 	// Setup router
+	db := database.GetDB()
+	h := handlers.New(service.New(repository.NewStore(db)))
 	suite.router = gin.New()
-	suite.router.POST("/voucher", handlers.CreateVoucher)
-	suite.router.GET("/voucher", handlers.GetVoucher)
-	suite.router.GET("/voucher/brand", handlers.GetVouchersByBrand)
-	suite.router.GET("/voucher/all", handlers.GetVouchers)
+	suite.router.Use(asAdmin())
+	suite.router.POST("/voucher", h.CreateVoucher)
+	suite.router.GET("/voucher", h.GetVoucher)
+	suite.router.GET("/voucher/brand", h.GetVouchersByBrand)
+	suite.router.GET("/voucher/all", h.GetVouchers)
+	suite.router.PUT("/voucher/:id", h.UpdateVoucher)
+	suite.router.PATCH("/voucher/:id", h.PatchVoucher)
+	suite.router.DELETE("/voucher/:id", h.DeleteVoucher)
+	suite.router.POST("/voucher/:id/restore", h.RestoreVoucher)
 }
 
 func (suite *VoucherHandlerTestSuite) TearDownSuite() {
@@ -160,10 +171,11 @@ func (suite *VoucherHandlerTestSuite) TestCreateVoucher_InvalidCostInPoint() {
 	assert.NoError(suite.T(), err)
 
 	// Check for validation error (either from binding or custom validation)
-	assert.True(suite.T(),
-		response["error"] == "Key: 'CreateVoucherRequest.CostInPoint' Error:Field validation for 'CostInPoint' failed on the 'required' tag" ||
-			response["error"] == "Cost in point must be greater than 0",
-		"Expected validation error for cost in point")
+	assert.Equal(suite.T(), apierror.CodeValidationFailed, response["code"])
+
+	details, _ := response["details"].([]interface{})
+	suite.Require().Len(details, 1)
+	assert.Equal(suite.T(), "cost_in_point", details[0].(map[string]interface{})["field"])
 }
 
 func (suite *VoucherHandlerTestSuite) TestCreateVoucher_InvalidDateRange() {
@@ -238,6 +250,161 @@ func (suite *VoucherHandlerTestSuite) TestGetVouchersByBrand_MissingBrandID() {
 	assert.Contains(suite.T(), response["error"], "Brand ID is required")
 }
 
+func (suite *VoucherHandlerTestSuite) request(method, path string, body interface{}) *httptest.ResponseRecorder {
+	var buf bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&buf).Encode(body)
+	}
+
+	req, _ := http.NewRequest(method, path, &buf)
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	return w
+}
+
+func (suite *VoucherHandlerTestSuite) createVoucher() models.Voucher {
+	voucher := models.Voucher{
+		BrandID:     suite.brandID,
+		Name:        "Original Voucher",
+		CostInPoint: 1000,
+		ValidFrom:   time.Now(),
+		ValidTo:     time.Now().AddDate(0, 1, 0),
+		IsActive:    true,
+	}
+	database.GetDB().Create(&voucher)
+	return voucher
+}
+
+func (suite *VoucherHandlerTestSuite) TestUpdateVoucher_Success() {
+	voucher := suite.createVoucher()
+	stock := 50
+	active := false
+
+	w := suite.request("PUT", "/voucher/"+voucher.ID.String(), handlers.CreateVoucherRequest{
+		BrandID:     suite.brandID.String(),
+		Name:        "Updated Voucher",
+		CostInPoint: 500,
+		ValidTo:     time.Now().AddDate(0, 2, 0),
+		TotalStock:  &stock,
+		IsActive:    &active,
+	})
+
+	// Assertions
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	var updated models.Voucher
+	database.GetDB().First(&updated, "id = ?", voucher.ID)
+	assert.Equal(suite.T(), "Updated Voucher", updated.Name)
+	assert.Equal(suite.T(), 500, updated.CostInPoint)
+	assert.False(suite.T(), updated.IsActive)
+	if assert.NotNil(suite.T(), updated.RemainingStock) {
+		assert.Equal(suite.T(), 50, *updated.RemainingStock)
+	}
+}
+
+func (suite *VoucherHandlerTestSuite) TestUpdateVoucher_RequiresIsActive() {
+	voucher := suite.createVoucher()
+
+	w := suite.request("PUT", "/voucher/"+voucher.ID.String(), handlers.CreateVoucherRequest{
+		BrandID:     suite.brandID.String(),
+		Name:        "Updated Voucher",
+		CostInPoint: 500,
+	})
+
+	// A replacement without is_active is rejected rather than guessed
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), `"field":"is_active"`)
+
+	var unchanged models.Voucher
+	database.GetDB().First(&unchanged, "id = ?", voucher.ID)
+	assert.Equal(suite.T(), "Original Voucher", unchanged.Name)
+	assert.True(suite.T(), unchanged.IsActive)
+}
+
+func (suite *VoucherHandlerTestSuite) TestPatchVoucher_OnlyChangesGivenFields() {
+	voucher := suite.createVoucher()
+
+	w := suite.request("PATCH", "/voucher/"+voucher.ID.String(), map[string]interface{}{"cost_in_point": 250})
+
+	// Assertions
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	// The response carries the brand, like GET does
+	var response struct {
+		Data models.Voucher `json:"data"`
+	}
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(suite.T(), suite.brandID, response.Data.Brand.ID)
+
+	var updated models.Voucher
+	database.GetDB().First(&updated, "id = ?", voucher.ID)
+	assert.Equal(suite.T(), 250, updated.CostInPoint)
+	assert.Equal(suite.T(), "Original Voucher", updated.Name)
+}
+
+func (suite *VoucherHandlerTestSuite) TestPatchVoucher_BlankName() {
+	voucher := suite.createVoucher()
+
+	for _, name := range []string{"", "   "} {
+		w := suite.request("PATCH", "/voucher/"+voucher.ID.String(), map[string]interface{}{"name": name})
+
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+		assert.Contains(suite.T(), w.Body.String(), `"field":"name"`)
+	}
+
+	var unchanged models.Voucher
+	database.GetDB().First(&unchanged, "id = ?", voucher.ID)
+	assert.Equal(suite.T(), "Original Voucher", unchanged.Name)
+}
+
+func (suite *VoucherHandlerTestSuite) TestPatchVoucher_InvalidDateRange() {
+	voucher := suite.createVoucher()
+
+	// Moving valid_to before the existing valid_from is rejected
+	w := suite.request("PATCH", "/voucher/"+voucher.ID.String(), map[string]interface{}{
+		"valid_to": time.Now().AddDate(0, 0, -1),
+	})
+
+	// Assertions
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	var response map[string]interface{}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(suite.T(), err)
+
+	assert.Contains(suite.T(), response["error"], "Valid from date must be before valid to date")
+}
+
+func (suite *VoucherHandlerTestSuite) TestPatchVoucher_UnknownBrand() {
+	voucher := suite.createVoucher()
+
+	w := suite.request("PATCH", "/voucher/"+voucher.ID.String(), map[string]interface{}{"brand_id": uuid.NewString()})
+
+	// Assertions
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+}
+
+func (suite *VoucherHandlerTestSuite) TestDeleteVoucher() {
+	voucher := suite.createVoucher()
+
+	w := suite.request("DELETE", "/voucher/"+voucher.ID.String(), nil)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	w = suite.request("GET", "/voucher?id="+voucher.ID.String(), nil)
+	assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+
+	w = suite.request("GET", "/voucher?id="+voucher.ID.String()+"&include_deleted=true", nil)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	w = suite.request("POST", "/voucher/"+voucher.ID.String()+"/restore", nil)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	w = suite.request("GET", "/voucher?id="+voucher.ID.String(), nil)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+}
+
 func TestVoucherHandlerSuite(t *testing.T) {
 	suite.Run(t, new(VoucherHandlerTestSuite))
 }